@@ -0,0 +1,115 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// AccessibilityConfig repairs common template omissions in generated pages
+type AccessibilityConfig struct {
+	SkipLink  bool `yaml:"skipLink"`  // add a "Skip to content" link to the main landmark
+	Landmarks bool `yaml:"landmarks"` // ensure main, navigation and footer landmarks
+}
+
+// builtA11y fixes the pages of the running build, nil when disabled
+var builtA11y *a11yFixer
+
+var (
+	bodyOpenPattern  = regexp.MustCompile(`(?i)<body[^>]*>`)
+	mainOpenPattern  = regexp.MustCompile(`(?i)<main\b[^>]*>|<[a-z]+\b[^>]*\brole="main"[^>]*>`)
+	navPattern       = regexp.MustCompile(`(?i)<nav\b|\brole="navigation"`)
+	footerPattern    = regexp.MustCompile(`(?i)<footer\b|\brole="contentinfo"`)
+	skipLinkPattern  = regexp.MustCompile(`(?i)<a\b[^>]*\bclass="skip-link"`)
+	idAttrPattern    = regexp.MustCompile(`\bid="([^"]*)"`)
+	navClassPattern  = regexp.MustCompile(`(?i)<(div|ul)\b([^>]*\bclass="[^"]*\b(nav|menu)\b[^"]*"[^>]*)>`)
+	footClassPattern = regexp.MustCompile(`(?i)<div\b([^>]*\b(?:class|id)="[^"]*\bfooter\b[^"]*"[^>]*)>`)
+	headerEndPattern = regexp.MustCompile(`(?i)</header>`)
+	footerOpenIndex  = regexp.MustCompile(`(?i)<footer\b|<div\b[^>]*\brole="contentinfo"`)
+)
+
+// a11yFixer adds skip links and missing landmarks to generated pages, and
+// warns once per template that was missing landmarks
+type a11yFixer struct {
+	cfg AccessibilityConfig
+
+	mu     sync.Mutex
+	warned map[string]bool
+}
+
+func newA11yFixer(cfg AccessibilityConfig) *a11yFixer {
+	if !cfg.SkipLink && !cfg.Landmarks {
+		return nil
+	}
+	return &a11yFixer{cfg: cfg, warned: map[string]bool{}}
+}
+
+// fix returns html with the configured repairs applied
+// template names the template that produced it, for warnings
+func (f *a11yFixer) fix(template, html string) string {
+	if f == nil || !bodyOpenPattern.MatchString(html) {
+		return html
+	}
+
+	var fixed []string
+	if f.cfg.Landmarks {
+		if !navPattern.MatchString(html) {
+			if loc := navClassPattern.FindStringSubmatchIndex(html); loc != nil {
+				html = html[:loc[5]] + ` role="navigation"` + html[loc[5]:]
+				fixed = append(fixed, "navigation without <nav>")
+			}
+		}
+		if !footerPattern.MatchString(html) {
+			if loc := footClassPattern.FindStringSubmatchIndex(html); loc != nil {
+				html = html[:loc[3]] + ` role="contentinfo"` + html[loc[3]:]
+				fixed = append(fixed, "footer without <footer>")
+			}
+		}
+		// After the footer, so a fixed footer stays outside <main>
+		if !mainOpenPattern.MatchString(html) {
+			html = wrapMain(html)
+			fixed = append(fixed, "no <main> landmark")
+		}
+	}
+
+	if f.cfg.SkipLink && !skipLinkPattern.MatchString(html) {
+		if loc := mainOpenPattern.FindStringIndex(html); loc != nil {
+			id := "main"
+			tag := html[loc[0]:loc[1]]
+			if m := idAttrPattern.FindStringSubmatch(tag); m != nil {
+				id = m[1]
+			} else {
+				html = html[:loc[1]-1] + ` id="main"` + html[loc[1]-1:]
+			}
+			body := bodyOpenPattern.FindStringIndex(html)
+			html = html[:body[1]] + `<a class="skip-link" href="#` + id + `">Skip to content</a>` + html[body[1]:]
+		}
+	}
+
+	if len(fixed) > 0 {
+		f.mu.Lock()
+		if !f.warned[template] {
+			f.warned[template] = true
+			log.Warnf("%s: %s (fixed in generated pages)", template, strings.Join(fixed, ", "))
+		}
+		f.mu.Unlock()
+	}
+	return html
+}
+
+// wrapMain wraps the body between the page header and footer in <main>
+func wrapMain(html string) string {
+	body := bodyOpenPattern.FindStringIndex(html)
+	start := body[1]
+	if loc := headerEndPattern.FindStringIndex(html); loc != nil && loc[0] > start {
+		start = loc[1]
+	}
+	end := strings.LastIndex(strings.ToLower(html), "</body>")
+	if end == -1 {
+		end = len(html)
+	}
+	if loc := footerOpenIndex.FindStringIndex(html[start:end]); loc != nil {
+		end = start + loc[0]
+	}
+	return html[:start] + `<main id="main">` + html[start:end] + `</main>` + html[end:]
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// altPlaceholder is the alt text --fix inserts, and the audit keeps
+// reporting until it's replaced
+const altPlaceholder = "TODO: describe image"
+
+var (
+	// markdownImagePattern matches the alt text of an inline markdown image
+	markdownImagePattern = regexp.MustCompile(`!\[([^\]]*)\]\(`)
+	// htmlImagePattern matches an <img> tag
+	htmlImagePattern = regexp.MustCompile(`(?i)<img\b[^>]*>`)
+	altAttrPattern   = regexp.MustCompile(`(?i)\salt\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+)
+
+// altIssue is an image without a useful alt text
+type altIssue struct {
+	File   string
+	Line   int
+	Column int // byte offset of the image in the line
+	Msg    string
+}
+
+func (a altIssue) String() string {
+	return fmt.Sprintf("%s:%d: %s", a.File, a.Line, a.Msg)
+}
+
+// altCmd lists the images in content/ missing alt text, and with --fix
+// gives them a placeholder to fill in
+func altCmd(args []string) bool {
+	flags := flag.NewFlagSet("check alt", flag.ExitOnError)
+	fix := flags.Bool("fix", false, "insert placeholder alt text into the markdown files")
+	flags.Parse(args)
+
+	cfg, err := loadConfig()
+	if err != nil {
+		log.Errorf("loading %s: %v", configFile, err)
+		return false
+	}
+	// Module content belongs to other repositories, so only local files
+	files, err := findMarkdownFiles(cfg.contentDir(), cfg.ignored)
+	if err != nil {
+		log.Errorf("reading content: %v", err)
+		return false
+	}
+
+	var issues []altIssue
+	fixed := 0
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			log.Errorf("reading %s: %v", file, err)
+			return false
+		}
+		if *fix {
+			var n int
+			content, n = fixAltText(content)
+			if n > 0 {
+				if err := os.WriteFile(file, content, 0644); err != nil {
+					log.Errorf("writing %s: %v", file, err)
+					return false
+				}
+				fixed += n
+			}
+		}
+		issues = append(issues, auditAltText(file, content)...)
+	}
+	if *fix {
+		log.Infof("Added %d placeholder(s)", fixed)
+		record("fixed", fixed)
+	}
+
+	for _, issue := range issues {
+		log.Warnf("%s", issue)
+		record("alt", issue.File, issue.Line, issue.Msg)
+	}
+	if len(issues) > 0 {
+		log.Infof("Found %d image(s) without alt text", len(issues))
+		if !*fix {
+			log.Infof("Run slate check alt --fix to insert %q placeholders", altPlaceholder)
+		}
+		return false
+	}
+	log.Infof("Every image has alt text")
+	return true
+}
+
+// auditAltText returns the images in a markdown file with no alt text or
+// the placeholder. Code blocks are skipped, and alt="" on an <img> is left
+// alone as it marks a decorative image.
+func auditAltText(file string, content []byte) []altIssue {
+	var issues []altIssue
+	forEachProseLine(content, func(i int, line string) string {
+		for _, m := range markdownImagePattern.FindAllStringSubmatchIndex(line, -1) {
+			if msg := altProblem(line[m[2]:m[3]], true); msg != "" {
+				issues = append(issues, altIssue{File: file, Line: i + 1, Column: m[0], Msg: msg})
+			}
+		}
+		for _, m := range htmlImagePattern.FindAllStringIndex(line, -1) {
+			alt := altAttrPattern.FindStringSubmatch(line[m[0]:m[1]])
+			if alt == nil {
+				issues = append(issues, altIssue{File: file, Line: i + 1, Column: m[0], Msg: "<img> has no alt attribute"})
+			} else if msg := altProblem(alt[1]+alt[2], false); msg != "" {
+				issues = append(issues, altIssue{File: file, Line: i + 1, Column: m[0], Msg: msg})
+			}
+		}
+		return line
+	})
+	return issues
+}
+
+// altProblem describes what's wrong with an alt text, if anything
+func altProblem(alt string, required bool) string {
+	switch alt = strings.TrimSpace(alt); {
+	case alt == altPlaceholder:
+		return "image alt text is still the placeholder"
+	case alt == "" && required:
+		return "image has no alt text"
+	}
+	return ""
+}
+
+// fixAltText inserts the placeholder into images with no alt text and
+// returns the new content and how many images it changed
+func fixAltText(content []byte) ([]byte, int) {
+	n := 0
+	out := forEachProseLine(content, func(i int, line string) string {
+		line = markdownImagePattern.ReplaceAllStringFunc(line, func(image string) string {
+			if strings.TrimSpace(image[2:len(image)-2]) != "" {
+				return image
+			}
+			n++
+			return "![" + altPlaceholder + "]("
+		})
+		return htmlImagePattern.ReplaceAllStringFunc(line, func(tag string) string {
+			if altAttrPattern.MatchString(tag) {
+				return tag
+			}
+			n++
+			return tag[:4] + ` alt="` + altPlaceholder + `"` + tag[4:]
+		})
+	})
+	return out, n
+}
+
+// forEachProseLine calls fn with every line outside frontmatter and fenced
+// code blocks, replacing the line with what it returns
+func forEachProseLine(content []byte, fn func(i int, line string) string) []byte {
+	lines := strings.Split(string(content), "\n")
+	_, body, _ := parseFrontmatter(content)
+	start := strings.Count(string(content[:len(content)-len(body)]), "\n")
+	fence := ""
+	for i := start; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+			continue
+		}
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fence = trimmed[:3]
+			continue
+		}
+		lines[i] = fn(i, lines[i])
+	}
+	return []byte(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// sharedAssetsDir is where byte-identical assets are written once, named by
+// their content hash
+const sharedAssetsDir = "assets"
+
+// asset is a file copied verbatim into public/
+type asset struct {
+	Source string // e.g. content/blog/trip/photo.jpg
+	Output string // URL path under public/, e.g. /blog/trip/photo.jpg
+}
+
+// findAssets lists the files in static/ and the non-markdown files next to
+// content, sorted by output path
+// Static files already written by the asset pipeline are left out
+func findAssets(cfg *Config) ([]asset, error) {
+	var assets []asset
+	for _, root := range []string{cfg.staticDir(), cfg.contentDir()} {
+		err := walkFollowingLinks(root, func(file string, d fs.DirEntry, err error) error {
+			if os.IsNotExist(err) && file == root {
+				return filepath.SkipDir
+			}
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(root, file)
+			if err != nil {
+				return err
+			}
+			if rel != "." && cfg.ignored(rel, d.IsDir()) {
+				log.Debugf("Ignoring %s", file)
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if d.IsDir() || strings.HasPrefix(d.Name(), ".") || cfg.isContentFile(file) {
+				return nil
+			}
+			// Frontmatter defaults aren't published
+			if root == cfg.contentDir() && d.Name() == defaultsFile {
+				return nil
+			}
+			if builtAssets.fingerprinted(file) {
+				return nil
+			}
+			assets = append(assets, asset{Source: file, Output: "/" + filepath.ToSlash(rel)})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(assets, func(i, j int) bool {
+		return assets[i].Output < assets[j].Output
+	})
+	return assets, nil
+}
+
+// copyAssets copies static files and page assets into publicDir
+// Files with identical contents are written once to /assets/<hash><ext> and
+// references to any of their original paths in generated pages and
+// stylesheets are rewritten
+func copyAssets(cfg *Config, publicDir string) error {
+	assets, err := findAssets(cfg)
+	if err != nil {
+		return err
+	}
+
+	// Group assets by content hash
+	byHash := map[string][]asset{}
+	var hashes []string
+	for _, a := range assets {
+		data, err := os.ReadFile(a.Source)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(data)
+		hash := hex.EncodeToString(sum[:])
+		if byHash[hash] == nil {
+			hashes = append(hashes, hash)
+		}
+		byHash[hash] = append(byHash[hash], a)
+	}
+
+	rewrites := map[string]string{}
+	for _, hash := range hashes {
+		group := byHash[hash]
+		output := group[0].Output
+		if len(group) > 1 {
+			output = "/" + sharedAssetsDir + "/" + hash[:16] + path.Ext(output)
+			for _, a := range group {
+				rewrites[a.Output] = output
+			}
+			log.Debugf("%d copies of %s stored once as %s", len(group), group[0].Source, output)
+		}
+		if err := copyFile(group[0].Source, publicDir+output); err != nil {
+			return err
+		}
+		log.Debugf("Copied: %s", publicDir+output)
+	}
+	log.Infof("Copied %d asset(s), %d after removing duplicates", len(assets), len(hashes))
+
+	if len(rewrites) == 0 {
+		return nil
+	}
+	return rewriteAssetLinks(publicDir, rewrites)
+}
+
+// srcsetPattern matches srcset attributes, a comma-separated list of URLs
+// each followed by an optional width or density
+var srcsetPattern = regexp.MustCompile(`(?i)\bsrcset\s*=\s*["']([^"']*)["']`)
+
+// rewriteAssetLinks points the references in generated pages and
+// stylesheets at the deduplicated copies: href, src and srcset attributes
+// and CSS url()s, in stylesheets and in pages' style blocks and attributes
+func rewriteAssetLinks(publicDir string, rewrites map[string]string) error {
+	return filepath.WalkDir(publicDir, func(file string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		isHTML := strings.HasSuffix(file, ".html")
+		if !isHTML && !strings.HasSuffix(file, ".css") {
+			return nil
+		}
+		rel, err := filepath.Rel(publicDir, file)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		text := string(data)
+		rewritten := rewriteAssetRefs(text, "/"+filepath.ToSlash(rel), assetRefs(text, isHTML), rewrites)
+		if rewritten == text {
+			return nil
+		}
+		return os.WriteFile(file, []byte(rewritten), 0644)
+	})
+}
+
+// assetRefs returns the start and end offsets of the URLs text refers to,
+// in order
+func assetRefs(text string, isHTML bool) [][2]int {
+	var refs [][2]int
+	for _, m := range cssURLPattern.FindAllStringSubmatchIndex(text, -1) {
+		refs = append(refs, [2]int{m[2], m[3]})
+	}
+	if isHTML {
+		for _, m := range linkAttrPattern.FindAllStringSubmatchIndex(text, -1) {
+			refs = append(refs, [2]int{m[2], m[3]})
+		}
+		for _, m := range srcsetPattern.FindAllStringSubmatchIndex(text, -1) {
+			// Each candidate is a URL, then an optional descriptor
+			offset := m[2]
+			for _, candidate := range strings.Split(text[m[2]:m[3]], ",") {
+				trimmed := strings.TrimLeft(candidate, " \t\n\r")
+				start := offset + len(candidate) - len(trimmed)
+				if url, _, _ := strings.Cut(trimmed, " "); url != "" {
+					refs = append(refs, [2]int{start, start + len(url)})
+				}
+				offset += len(candidate) + 1
+			}
+		}
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i][0] < refs[j][0] })
+	return refs
+}
+
+// rewriteAssetRefs replaces the refs in text, a file at fileURL, that point
+// at a deduplicated path, keeping any query string or fragment
+func rewriteAssetRefs(text, fileURL string, refs [][2]int, rewrites map[string]string) string {
+	var b strings.Builder
+	last := 0
+	for _, ref := range refs {
+		if ref[0] < last {
+			continue
+		}
+		href := text[ref[0]:ref[1]]
+		target, ok := resolvePageURL(fileURL, href)
+		if !ok || rewrites[target] == "" {
+			continue
+		}
+		b.WriteString(text[last:ref[0]])
+		b.WriteString(rewrites[target])
+		if i := strings.IndexAny(href, "?#"); i >= 0 {
+			b.WriteString(href[i:])
+		}
+		last = ref[1]
+	}
+	if last == 0 {
+		return text
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+func copyFile(source, dest string) error {
+	data, err := os.ReadFile(source)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(dest, data, 0644)
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRewriteAssetLinks(t *testing.T) {
+	rewrites := map[string]string{
+		"/img/a.png":      "/assets/0123456789abcdef.png",
+		"/blog/img/a.png": "/assets/0123456789abcdef.png",
+	}
+	tests := []struct {
+		name, file, in, want string
+	}{
+		{"src", "blog/post.html", `<img src="img/a.png">`, `<img src="/assets/0123456789abcdef.png">`},
+		{"href with fragment", "index.html", `<a href="/img/a.png#x">`, `<a href="/assets/0123456789abcdef.png#x">`},
+		{"srcset", "blog/post.html", `<img srcset="img/a.png 1x, /img/b.png 2x,/img/a.png 3x">`,
+			`<img srcset="/assets/0123456789abcdef.png 1x, /img/b.png 2x,/assets/0123456789abcdef.png 3x">`},
+		{"style attribute", "index.html", `<div style="background: url('img/a.png')">`, `<div style="background: url('/assets/0123456789abcdef.png')">`},
+		{"stylesheet", "css/site.css", `body { background: url("../img/a.png?v=1") }`, `body { background: url("/assets/0123456789abcdef.png?v=1") }`},
+		{"unrelated", "index.html", `<img src="/img/b.png" srcset="data:image/png;base64,AAAA 1x">`, `<img src="/img/b.png" srcset="data:image/png;base64,AAAA 1x">`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			file := filepath.Join(dir, filepath.FromSlash(tt.file))
+			if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(file, []byte(tt.in), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := rewriteAssetLinks(dir, rewrites); err != nil {
+				t.Fatal(err)
+			}
+			got, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("rewritten %s = %s\nwant %s", tt.file, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"os"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// authorsFile holds author profiles keyed by the id used in frontmatter
+const authorsFile = dataDir + "/authors.yaml"
+
+// Author is a post author, with profile fields from data/authors.yaml
+type Author struct {
+	ID      string `yaml:"-"`
+	Name    string `yaml:"name"`
+	Bio     string `yaml:"bio"`
+	Avatar  string `yaml:"avatar"`
+	Website string `yaml:"website"`
+	// Snippets override the site snippets of the same name on this author's pages
+	Snippets map[string]string `yaml:"snippets"`
+	URL      string            `yaml:"-"` // author listing page
+}
+
+// AuthorPage is the data passed to templates/author.html
+type AuthorPage struct {
+	Author
+	Pages []Page
+}
+
+// loadAuthors reads the author profiles, an absent file means no profiles
+func loadAuthors() (map[string]Author, error) {
+	authors := map[string]Author{}
+
+	data, err := os.ReadFile(authorsFile)
+	if os.IsNotExist(err) {
+		return authors, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := yaml.Unmarshal(data, &authors); err != nil {
+		return nil, err
+	}
+	return authors, nil
+}
+
+// resolveAuthors looks up the profile for each author id
+// Authors without a profile use their id as name
+func resolveAuthors(ids []string, profiles map[string]Author) []Author {
+	var authors []Author
+	for _, id := range ids {
+		if id == "" {
+			continue
+		}
+		author, ok := profiles[id]
+		if !ok || author.Name == "" {
+			author.Name = id
+		}
+		author.ID = id
+		author.URL = "/authors/" + slugify(id) + "/"
+		authors = append(authors, author)
+	}
+	return authors
+}
+
+// collectAuthorPages groups pages by author, sorted by author name
+func collectAuthorPages(pages []Page) []AuthorPage {
+	byAuthor := map[string]*AuthorPage{}
+	for _, page := range pages {
+		for _, author := range page.Authors {
+			if byAuthor[author.ID] == nil {
+				byAuthor[author.ID] = &AuthorPage{Author: author}
+			}
+			byAuthor[author.ID].Pages = append(byAuthor[author.ID].Pages, page)
+		}
+	}
+
+	var authorPages []AuthorPage
+	for _, authorPage := range byAuthor {
+		sort.Slice(authorPage.Pages, func(i, j int) bool {
+			return listedBefore(authorPage.Pages[i], authorPage.Pages[j])
+		})
+		authorPages = append(authorPages, *authorPage)
+	}
+	sort.Slice(authorPages, func(i, j int) bool {
+		return authorPages[i].Name < authorPages[j].Name
+	})
+	return authorPages
+}
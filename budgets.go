@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// BudgetsConfig limits the weight of each kind of page, in KB transferred:
+// HTML, CSS and JS gzipped, images as they are
+type BudgetsConfig struct {
+	Home Budget `yaml:"home"`
+	Post Budget `yaml:"post"`
+	List Budget `yaml:"list"` // blog index, tags, categories and other listings
+	// Default applies to the kinds without a budget of their own
+	Default Budget `yaml:"default"`
+	// Enforce fails the build on a violation, e.g. in production; otherwise
+	// violations are warnings, which fail only strict builds
+	Enforce bool `yaml:"enforce"`
+}
+
+// Budget is the most KB a page may weigh per resource type, 0 for no limit
+type Budget struct {
+	HTML   int `yaml:"html"`
+	CSS    int `yaml:"css"`
+	JS     int `yaml:"js"`
+	Images int `yaml:"images"`
+	Total  int `yaml:"total"`
+}
+
+func (b Budget) isZero() bool {
+	return b == Budget{}
+}
+
+var (
+	stylesheetHrefPattern = regexp.MustCompile(`(?i)<link\b[^>]*\brel="?stylesheet"?[^>]*>`)
+	scriptSrcPattern      = regexp.MustCompile(`(?i)<script\b[^>]*\bsrc="([^"]+)"`)
+	imgSrcPattern         = regexp.MustCompile(`(?i)<img\b[^>]*\bsrc="([^"]+)"`)
+	hrefAttrPattern       = regexp.MustCompile(`(?i)\bhref="([^"]+)"`)
+)
+
+// pageWeight is what a page transfers, in bytes
+type pageWeight struct {
+	HTML, CSS, JS, Images int64
+}
+
+func (w pageWeight) total() int64 {
+	return w.HTML + w.CSS + w.JS + w.Images
+}
+
+func (w pageWeight) String() string {
+	return fmt.Sprintf("%s (HTML %s, CSS %s, JS %s, images %s)",
+		formatKB(w.total()), formatKB(w.HTML), formatKB(w.CSS), formatKB(w.JS), formatKB(w.Images))
+}
+
+// checkBudgets weighs every page in publicDir with the local stylesheets,
+// scripts and images it references, reports the heaviest page of each kind
+// and warns about every page over its budget. External resources aren't
+// counted.
+func checkBudgets(cfg BudgetsConfig, publicDir string, postURLs map[string]bool) error {
+	if cfg.Home.isZero() && cfg.Post.isZero() && cfg.List.isZero() && cfg.Default.isZero() {
+		return nil
+	}
+	files, err := listOutput(publicDir)
+	if err != nil {
+		return err
+	}
+
+	sizes := map[string]int64{} // output file → transferred bytes
+	size := func(name string) (int64, error) {
+		if n, ok := sizes[name]; ok {
+			return n, nil
+		}
+		n, err := transferSize(files[name])
+		sizes[name] = n
+		return n, err
+	}
+
+	heaviest := map[string]string{}
+	weights := map[string]pageWeight{}
+	violations := 0
+	for _, name := range sortedKeys(files) {
+		if filepath.Ext(name) != ".html" {
+			continue
+		}
+		url := "/" + name
+		kind := "list"
+		switch {
+		case url == "/index.html":
+			kind = "home"
+		case postURLs[url]:
+			kind = "post"
+		}
+
+		data, err := os.ReadFile(files[name])
+		if err != nil {
+			return err
+		}
+		page := string(data)
+		var w pageWeight
+		if w.HTML, err = size(name); err != nil {
+			return err
+		}
+		add := func(total *int64, ref string) error {
+			target, ok := resolvePageURL(url, ref)
+			if !ok || files[strings.TrimPrefix(target, "/")] == "" {
+				return nil
+			}
+			n, err := size(strings.TrimPrefix(target, "/"))
+			*total += n
+			return err
+		}
+		for _, link := range stylesheetHrefPattern.FindAllString(page, -1) {
+			if m := hrefAttrPattern.FindStringSubmatch(link); m != nil {
+				if err := add(&w.CSS, m[1]); err != nil {
+					return err
+				}
+			}
+		}
+		for _, m := range scriptSrcPattern.FindAllStringSubmatch(page, -1) {
+			if err := add(&w.JS, m[1]); err != nil {
+				return err
+			}
+		}
+		for _, m := range imgSrcPattern.FindAllStringSubmatch(page, -1) {
+			if err := add(&w.Images, m[1]); err != nil {
+				return err
+			}
+		}
+
+		if heaviest[kind] == "" || w.total() > weights[heaviest[kind]].total() {
+			heaviest[kind] = url
+			weights[url] = w
+		}
+		budget := cfg.budget(kind)
+		for _, over := range budget.exceeded(w) {
+			log.Warnf("%s: %s over the %s page budget", url, over, kind)
+			violations++
+		}
+	}
+
+	for _, kind := range sortedKeys(heaviest) {
+		log.Infof("Heaviest %s page: %s, %s", kind, heaviest[kind], weights[heaviest[kind]])
+	}
+	if violations > 0 && cfg.Enforce {
+		return fmt.Errorf("%d page budget violation(s)", violations)
+	}
+	return nil
+}
+
+// budget returns the budget of a kind of page
+func (c BudgetsConfig) budget(kind string) Budget {
+	b := map[string]Budget{"home": c.Home, "post": c.Post, "list": c.List}[kind]
+	if b.isZero() {
+		return c.Default
+	}
+	return b
+}
+
+// exceeded describes each limit w goes over, e.g. "JS 120 KB of 100 KB"
+func (b Budget) exceeded(w pageWeight) []string {
+	var over []string
+	for _, limit := range []struct {
+		name  string
+		kb    int
+		bytes int64
+	}{
+		{"HTML", b.HTML, w.HTML},
+		{"CSS", b.CSS, w.CSS},
+		{"JS", b.JS, w.JS},
+		{"images", b.Images, w.Images},
+		{"total", b.Total, w.total()},
+	} {
+		if limit.kb > 0 && limit.bytes > int64(limit.kb)*1024 {
+			over = append(over, fmt.Sprintf("%s %s of %d KB", limit.name, formatKB(limit.bytes), limit.kb))
+		}
+	}
+	return over
+}
+
+// transferSize returns the gzipped size of a text file, the size of any
+// other file
+func transferSize(file string) (int64, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return 0, err
+	}
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".html", ".css", ".js", ".mjs", ".svg", ".json":
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		w.Write(data)
+		w.Close()
+		return int64(buf.Len()), nil
+	}
+	return int64(len(data)), nil
+}
+
+func formatKB(n int64) string {
+	return fmt.Sprintf("%.1f KB", float64(n)/1024)
+}
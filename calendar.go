@@ -0,0 +1,269 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// calendarEntry is one content file placed on the editorial calendar
+type calendarEntry struct {
+	File   string
+	Title  string
+	Date   time.Time
+	State  string // frontmatter status, or published/scheduled/unscheduled from the date
+	Period string // week (2025-W03) or month (2025-01) the entry falls in
+}
+
+// calendarPeriod groups the entries of one week or month
+type calendarPeriod struct {
+	Name    string
+	Entries []calendarEntry
+}
+
+// calendarCmd renders the editorial calendar as a table, HTML page or iCal feed
+func calendarCmd(args []string) {
+	flags := flag.NewFlagSet("calendar", flag.ExitOnError)
+	format := flags.String("format", "table", "output format: table, html or ical")
+	by := flags.String("by", "month", "group entries by week or month")
+	output := flags.String("o", "", "write to this file instead of stdout")
+	flags.Parse(args)
+
+	if *by != "week" && *by != "month" {
+		log.Errorf("--by must be week or month")
+		os.Exit(2)
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		log.Errorf("loading %s: %v", configFile, err)
+		os.Exit(1)
+	}
+
+	// Use the last fetched copy of git modules rather than updating them
+	if contentMounts, err = mountModules(cfg, false); err != nil {
+		log.Errorf("%v", err)
+		os.Exit(1)
+	}
+
+	entries, err := calendarEntries(cfg, *by)
+	if err != nil {
+		log.Errorf("reading content: %v", err)
+		os.Exit(1)
+	}
+
+	var out io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			log.Errorf("creating %s: %v", *output, err)
+			os.Exit(1)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	// Records take stdout under --porcelain, whatever the format
+	if porcelainOut != nil && *output == "" {
+		for _, period := range groupCalendar(entries) {
+			for _, entry := range period.Entries {
+				date := ""
+				if !entry.Date.IsZero() {
+					date = entry.Date.Format(time.RFC3339)
+				}
+				record("entry", period.Name, date, entry.State, entry.Title, entry.File)
+			}
+		}
+		return
+	}
+
+	switch *format {
+	case "table":
+		err = writeCalendarTable(out, groupCalendar(entries))
+	case "html":
+		err = writeCalendarHTML(out, cfg, groupCalendar(entries))
+	case "ical":
+		err = writeCalendarICal(out, cfg, entries)
+	default:
+		log.Errorf("unknown format %q, use table, html or ical", *format)
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Errorf("writing calendar: %v", err)
+		os.Exit(1)
+	}
+}
+
+// calendarEntries reads the frontmatter of every content file, sorted by date
+func calendarEntries(cfg *Config, by string) ([]calendarEntry, error) {
+	files, err := findContentFiles(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var entries []calendarEntry
+	for _, file := range files {
+		content, err := readContent(file)
+		if err != nil {
+			return nil, err
+		}
+		fm, _, _ := parseFrontmatter(content)
+
+		entry := calendarEntry{File: file, Title: fm.Title, State: fm.Status}
+		if entry.Title == "" {
+			entry.Title = extractTitle(file)
+		}
+		if fm.Date != "" {
+			if entry.Date, err = cfg.pageDate(fm); err != nil {
+				log.Warnf("%s: %v", file, err)
+			}
+		}
+
+		if entry.State == "" {
+			switch {
+			case entry.Date.IsZero():
+				entry.State = "unscheduled"
+			case entry.Date.After(now):
+				entry.State = "scheduled"
+			default:
+				entry.State = "published"
+			}
+		}
+
+		switch {
+		case entry.Date.IsZero():
+			entry.Period = "unscheduled"
+		case by == "week":
+			year, week := entry.Date.ISOWeek()
+			entry.Period = fmt.Sprintf("%d-W%02d", year, week)
+		default:
+			entry.Period = entry.Date.Format("2006-01")
+		}
+
+		entries = append(entries, entry)
+	}
+
+	// Undated entries go last
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Date.IsZero() != entries[j].Date.IsZero() {
+			return !entries[i].Date.IsZero()
+		}
+		return entries[i].Date.Before(entries[j].Date)
+	})
+	return entries, nil
+}
+
+// groupCalendar splits sorted entries into consecutive periods
+func groupCalendar(entries []calendarEntry) []calendarPeriod {
+	var periods []calendarPeriod
+	for _, entry := range entries {
+		if len(periods) == 0 || periods[len(periods)-1].Name != entry.Period {
+			periods = append(periods, calendarPeriod{Name: entry.Period})
+		}
+		last := &periods[len(periods)-1]
+		last.Entries = append(last.Entries, entry)
+	}
+	return periods
+}
+
+func writeCalendarTable(out io.Writer, periods []calendarPeriod) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	for i, period := range periods {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "%s (%d)\n", period.Name, len(period.Entries))
+		for _, entry := range period.Entries {
+			date := "-"
+			if !entry.Date.IsZero() {
+				date = entry.Date.Format("2006-01-02")
+			}
+			fmt.Fprintf(w, "  %s\t%s\t%s\t%s\n", date, entry.State, entry.Title, entry.File)
+		}
+	}
+	return w.Flush()
+}
+
+var calendarHTMLTemplate = template.Must(template.New("calendar").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <title>{{with .Site}}{{.}} {{end}}Editorial Calendar</title>
+    <style>
+        body { font-family: sans-serif; max-width: 800px; margin: 2rem auto; color: #333; }
+        table { width: 100%; border-collapse: collapse; margin-bottom: 2rem; }
+        td { padding: 0.3rem 0.5rem; border-bottom: 1px solid #eee; }
+        .state { font-size: 0.8rem; text-transform: uppercase; color: #666; }
+        .scheduled { color: #0066cc; }
+    </style>
+</head>
+<body>
+    <h1>Editorial Calendar</h1>
+    {{range .Periods}}
+    <h2>{{.Name}}</h2>
+    <table>
+        {{range .Entries}}
+        <tr>
+            <td>{{if not .Date.IsZero}}{{.Date.Format "Jan 2"}}{{end}}</td>
+            <td class="state {{.State}}">{{.State}}</td>
+            <td>{{.Title}}</td>
+            <td><code>{{.File}}</code></td>
+        </tr>
+        {{end}}
+    </table>
+    {{end}}
+</body>
+</html>
+`))
+
+func writeCalendarHTML(out io.Writer, cfg *Config, periods []calendarPeriod) error {
+	return calendarHTMLTemplate.Execute(out, struct {
+		Site    string
+		Periods []calendarPeriod
+	}{cfg.Title, periods})
+}
+
+// writeCalendarICal writes dated entries as all-day iCalendar events
+func writeCalendarICal(out io.Writer, cfg *Config, entries []calendarEntry) error {
+	var b strings.Builder
+	line := func(s string) {
+		b.WriteString(s)
+		b.WriteString("\r\n")
+	}
+
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//slate//calendar//EN")
+	line("X-WR-CALNAME:" + icalEscape(strings.TrimSpace(cfg.Title+" Editorial Calendar")))
+	for _, entry := range entries {
+		if entry.Date.IsZero() {
+			continue
+		}
+		day := entry.Date.Format("20060102")
+		line("BEGIN:VEVENT")
+		line("UID:" + icalEscape(entry.File) + "@slate")
+		line("DTSTAMP:" + stamp)
+		line("DTSTART;VALUE=DATE:" + day)
+		line("SUMMARY:" + icalEscape(entry.Title))
+		line("CATEGORIES:" + icalEscape(entry.State))
+		line("DESCRIPTION:" + icalEscape(entry.File))
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+
+	_, err := io.WriteString(out, b.String())
+	return err
+}
+
+// icalEscape escapes text values per RFC 5545
+func icalEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(s)
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// linkAttrPattern matches href and src attribute values in generated HTML
+var linkAttrPattern = regexp.MustCompile(`(?i)\b(?:href|src)\s*=\s*["']([^"']*)["']`)
+
+// brokenLink is an internal reference in generated HTML that doesn't resolve
+type brokenLink struct {
+	File   string // generated HTML file
+	Line   int
+	Target string
+	Source string // content file the page was generated from, if known
+}
+
+func (b brokenLink) String() string {
+	msg := fmt.Sprintf("%s:%d: broken link %q", b.File, b.Line, b.Target)
+	if b.Source != "" {
+		msg += " (from " + b.Source + ")"
+	}
+	return msg
+}
+
+// runLinkCheck checks the output directory and prints every broken link
+// Returns false if any were found
+func runLinkCheck(output string) bool {
+	cfg, err := loadConfig()
+	if err != nil {
+		log.Errorf("loading %s: %v", configFile, err)
+		return false
+	}
+	if output != "" {
+		cfg.Dirs.Output = output
+	}
+	broken, err := checkLinks(cfg.outputDir(), cfg.contentDir())
+	if err != nil {
+		log.Errorf("checking links: %v", err)
+		return false
+	}
+
+	for _, b := range broken {
+		log.Errorf("%s", b)
+		record("broken", b.File, b.Line, b.Target, b.Source)
+	}
+	if len(broken) > 0 {
+		log.Infof("Found %d broken internal link(s)", len(broken))
+		return false
+	}
+
+	log.Infof("No broken internal links")
+	return true
+}
+
+// checkLinks verifies that every internal href/src in the HTML files under
+// publicDir resolves to a file in publicDir
+func checkLinks(publicDir, contentDir string) ([]brokenLink, error) {
+	var broken []brokenLink
+
+	err := filepath.WalkDir(publicDir, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(strings.ToLower(file), ".html") {
+			return nil
+		}
+
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+		line := 0
+		for scanner.Scan() {
+			line++
+			for _, m := range linkAttrPattern.FindAllStringSubmatch(scanner.Text(), -1) {
+				target := m[1]
+				if !isInternalLink(target) || linkResolves(publicDir, file, target) {
+					continue
+				}
+				broken = append(broken, brokenLink{
+					File:   file,
+					Line:   line,
+					Target: target,
+					Source: sourceForOutput(publicDir, contentDir, file),
+				})
+			}
+		}
+		return scanner.Err()
+	})
+
+	return broken, err
+}
+
+// isInternalLink reports whether target refers to a file on this site
+func isInternalLink(target string) bool {
+	if target == "" || strings.HasPrefix(target, "#") || strings.HasPrefix(target, "//") {
+		return false
+	}
+	// Anything with a scheme (https:, mailto:, data:) is external
+	if i := strings.IndexAny(target, ":/?#"); i >= 0 && target[i] == ':' {
+		return false
+	}
+	return true
+}
+
+// linkResolves reports whether an internal link from file points at an
+// existing file under publicDir
+func linkResolves(publicDir, file, target string) bool {
+	target, _, _ = strings.Cut(target, "#")
+	target, _, _ = strings.Cut(target, "?")
+	if unescaped, err := url.PathUnescape(target); err == nil {
+		target = unescaped
+	}
+
+	var resolved string
+	if strings.HasPrefix(target, "/") {
+		resolved = filepath.Join(publicDir, filepath.FromSlash(target))
+	} else {
+		resolved = filepath.Join(filepath.Dir(file), filepath.FromSlash(target))
+	}
+
+	info, err := os.Stat(resolved)
+	if err != nil {
+		return false
+	}
+	if info.IsDir() {
+		_, err = os.Stat(filepath.Join(resolved, "index.html"))
+		return err == nil
+	}
+	return true
+}
+
+// sourceForOutput maps a generated file back to its content file
+// e.g., "public/blog/my-post.html" → "content/blog/my-post.md"
+func sourceForOutput(publicDir, contentDir, file string) string {
+	rel, err := filepath.Rel(publicDir, file)
+	if err != nil {
+		return ""
+	}
+	base := path.Join(filepath.ToSlash(contentDir), strings.TrimSuffix(filepath.ToSlash(rel), ".html"))
+	for _, ext := range append([]string{".md", ".ipynb", ".html"}, sortedKeys(defaultFormats)...) {
+		if _, err := os.Stat(base + ext); err == nil {
+			return base + ext
+		}
+	}
+	return ""
+}
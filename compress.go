@@ -0,0 +1,127 @@
+package main
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// CompressConfig writes compressed copies of text files next to them, for
+// web servers that serve precompressed files, e.g. nginx's gzip_static or
+// Caddy's precompressed
+type CompressConfig struct {
+	Formats []string `yaml:"formats"` // gzip and brotli, which needs the brotli command
+	MinSize int      `yaml:"minSize"` // bytes, smaller files aren't worth it, default 1024
+}
+
+// compressedExts are the file types compressed copies are written for
+var compressedExts = []string{".html", ".css", ".js", ".mjs", ".svg", ".json", ".xml", ".txt"}
+
+// precompress writes a .gz and/or .br copy of every text file in publicDir
+// Copies are cached by content, so unchanged files aren't compressed again.
+func precompress(cfg CompressConfig, publicDir string) error {
+	if len(cfg.Formats) == 0 {
+		return nil
+	}
+	minSize := cfg.MinSize
+	if minSize <= 0 {
+		minSize = 1024
+	}
+
+	var gz, br bool
+	for _, format := range cfg.Formats {
+		switch format {
+		case "gzip":
+			gz = true
+		case "brotli":
+			br = true
+		default:
+			return fmt.Errorf("unknown compression format %q", format)
+		}
+	}
+	if br {
+		if _, err := exec.LookPath("brotli"); err != nil {
+			log.Warnf("compress: the brotli command isn't installed, skipping .br files")
+			br = false
+		}
+	}
+
+	files, err := listOutput(publicDir)
+	if err != nil {
+		return err
+	}
+	count := 0
+	for _, name := range sortedKeys(files) {
+		file := files[name]
+		if !slices.Contains(compressedExts, strings.ToLower(filepath.Ext(name))) {
+			continue
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		if len(data) < minSize {
+			continue
+		}
+		sum := sha256.Sum256(data)
+		key := hex.EncodeToString(sum[:])
+		if gz {
+			created, err := cachedDerived("compressed", key+".gz", file+".gz", func(dest string) error {
+				return gzipFile(data, dest)
+			})
+			if err != nil {
+				return fmt.Errorf("compressing %s: %w", name, err)
+			}
+			if created {
+				count++
+			}
+		}
+		if br {
+			created, err := cachedDerived("compressed", key+".br", file+".br", func(dest string) error {
+				cmd := exec.Command("brotli", "--best", "--force", "--keep", "--output="+dest, file)
+				if out, err := cmd.CombinedOutput(); err != nil {
+					return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(out)))
+				}
+				return nil
+			})
+			if err != nil {
+				return fmt.Errorf("compressing %s: %w", name, err)
+			}
+			if created {
+				count++
+			}
+		}
+	}
+	if count > 0 {
+		log.Infof("Compressed %d file(s)", count)
+	}
+	return nil
+}
+
+// gzipFile writes data gzipped to file
+func gzipFile(data []byte, file string) error {
+	out, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	w, err := gzip.NewWriterLevel(out, gzip.BestCompression)
+	if err != nil {
+		out.Close()
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		out.Close()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const configFile = "slate.yaml"
+
+// envConfigFile is the file overriding slate.yaml in an environment
+// e.g., production → slate.production.yaml
+func envConfigFile(env string) string {
+	return "slate." + env + ".yaml"
+}
+
+// Config holds site-wide settings read from slate.yaml
+type Config struct {
+	Title   string     `yaml:"title"`
+	BaseURL string     `yaml:"baseURL"`
+	Head    HeadConfig `yaml:"head"`
+	Tags    TagsConfig `yaml:"tags"`
+
+	Verification VerificationConfig `yaml:"verification"`
+
+	Robots RobotsConfig `yaml:"robots"`
+
+	Digest DigestConfig `yaml:"digest"`
+
+	Search SearchConfig `yaml:"search"`
+
+	OGImages OGImagesConfig `yaml:"ogImages"`
+
+	Graph GraphConfig `yaml:"graph"`
+
+	Snippets SnippetsConfig `yaml:"snippets"`
+
+	Dirs DirsConfig `yaml:"dirs"`
+
+	// Ignore lists patterns of content and static files left out of the
+	// site, like the lines of .slateignore, e.g. [README.md, "*~", _drafts/]
+	Ignore     []string `yaml:"ignore"`
+	ignoreFile []string // patterns from .slateignore
+
+	// ExcludeDrafts leaves out pages whose status is draft, e.g. in production
+	ExcludeDrafts bool `yaml:"excludeDrafts"`
+
+	// CriticalCSS inlines the CSS used by home, post and list pages into
+	// their heads and loads the full stylesheet asynchronously
+	CriticalCSS bool `yaml:"criticalCSS"`
+
+	// Typographer turns straight quotes into curly ones, -- and --- into
+	// dashes and ... into an ellipsis; pages can opt out in frontmatter
+	Typographer bool `yaml:"typographer"`
+
+	// Emoji turns shortcodes like :rocket: in content into emoji characters
+	Emoji bool `yaml:"emoji"`
+
+	Assets AssetsConfig `yaml:"assets"`
+
+	Accessibility AccessibilityConfig `yaml:"accessibility"`
+
+	Hooks HooksConfig `yaml:"hooks"`
+
+	TOC TOCConfig `yaml:"toc"`
+
+	Figures FiguresConfig `yaml:"figures"`
+
+	Deploy DeployConfig `yaml:"deploy"`
+
+	Hosting HostingConfig `yaml:"hosting"`
+
+	Outbound OutboundConfig `yaml:"outbound"`
+
+	Edit EditConfig `yaml:"edit"`
+
+	Compress CompressConfig `yaml:"compress"`
+
+	Fonts FontsConfig `yaml:"fonts"`
+
+	Budgets BudgetsConfig `yaml:"budgets"`
+
+	Math MathConfig `yaml:"math"`
+
+	Mermaid MermaidConfig `yaml:"mermaid"`
+
+	Downloads DownloadsConfig `yaml:"downloads"`
+
+	Markup MarkupConfig `yaml:"markup"`
+
+	// Lint adjusts the content checks of `slate lint`
+	Lint LintConfig `yaml:"lint"`
+
+	// Formats adds or replaces converters of content formats other than
+	// markdown, by extension: a command reading a document on stdin and
+	// writing HTML to stdout, e.g. ".org": [pandoc, --from, org]. An empty
+	// command turns a built-in format off.
+	Formats map[string][]string `yaml:"formats"`
+
+	// Trash keeps what destructive commands remove, see `slate restore`
+	Trash TrashConfig `yaml:"trash"`
+
+	// Modules mount shared content from other directories or repositories
+	Modules []ContentModule `yaml:"modules"`
+
+	// Sources pull pages from headless CMSs into content/
+	Sources []CMSSource `yaml:"sources"`
+
+	// Variants build the same content into more outputs in one run
+	Variants []Variant `yaml:"variants"`
+	variant  *Variant  // set while building a variant
+	staging  string    // where the output is written while building
+
+	// FallbackPages are standalone pages such as 500, maintenance and offline,
+	// written to public/<name>.html
+	FallbackPages map[string]FallbackPage `yaml:"fallbackPages"`
+
+	// EnableGitInfo sets each page's Lastmod and GitAuthor from git history
+	EnableGitInfo bool `yaml:"enableGitInfo"`
+
+	// ProgressThreshold is the number of content files above which the
+	// build shows progress; negative disables it
+	ProgressThreshold int `yaml:"progressThreshold"`
+
+	// Timezone is the IANA zone used for frontmatter dates without an offset
+	// e.g., Europe/Berlin
+	Timezone string `yaml:"timezone"`
+	loc      *time.Location
+
+	// MIMETypes overrides the Content-Type for file extensions
+	// e.g., .webmanifest: application/manifest+json
+	MIMETypes map[string]string `yaml:"mimeTypes"`
+
+	// Env is the environment selected with --env or SLATE_ENV, empty if none
+	Env string `yaml:"-"`
+}
+
+// DirsConfig overrides the default directory layout, e.g. to read content
+// from docs/ and write the site to dist/
+type DirsConfig struct {
+	Content   string `yaml:"content"`   // default content
+	Templates string `yaml:"templates"` // default templates
+	Static    string `yaml:"static"`    // default static
+	Output    string `yaml:"output"`    // default public
+}
+
+// HeadConfig lists the elements rendered by the built-in "head" partial
+type HeadConfig struct {
+	Favicon        string            `yaml:"favicon"`
+	AppleTouchIcon string            `yaml:"appleTouchIcon"`
+	Description    string            `yaml:"description"`
+	Meta           map[string]string `yaml:"meta"`
+	Snippets       []string          `yaml:"snippets"`
+}
+
+// loadConfig reads slate.yaml from the project root, then the overrides in
+// slate.<env>.yaml for the environment selected with --env or SLATE_ENV
+// A missing slate.yaml is not an error, the zero Config is used instead, but a
+// missing slate.<env>.yaml is
+func loadConfig() (*Config, error) {
+	cfg := &Config{Env: os.Getenv("SLATE_ENV")}
+
+	files := []string{configFile}
+	if cfg.Env != "" {
+		files = append(files, envConfigFile(cfg.Env))
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if os.IsNotExist(err) {
+			// Only slate.yaml is optional, a selected environment must have its file
+			if file != configFile {
+				return nil, fmt.Errorf("environment %q: %s not found", cfg.Env, file)
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		// Settings in later files replace those in earlier ones
+		if err := yaml.Unmarshal(data, cfg); err != nil {
+			if file != configFile {
+				return nil, fmt.Errorf("%s: %w", file, err)
+			}
+			return nil, err
+		}
+	}
+
+	var err error
+	if cfg.ignoreFile, err = readIgnoreFile(ignoreFile); err != nil {
+		return nil, fmt.Errorf("%s: %w", ignoreFile, err)
+	}
+
+	if cfg.Timezone != "" {
+		loc, err := time.LoadLocation(cfg.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timezone: %w", err)
+		}
+		cfg.loc = loc
+	}
+	return cfg, nil
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	// stylesheetPattern matches a local stylesheet link, capturing its href
+	stylesheetPattern = regexp.MustCompile(`<link rel="stylesheet" href="(/[^"]+\.css)">`)
+
+	htmlTagPattern   = regexp.MustCompile(`<([a-zA-Z][a-zA-Z0-9-]*)([^>]*)>`)
+	htmlClassPattern = regexp.MustCompile(`\bclass\s*=\s*"([^"]*)"`)
+	htmlIDPattern    = regexp.MustCompile(`\bid\s*=\s*"([^"]*)"`)
+
+	// selectorNoisePattern matches the parts of a selector that don't name
+	// an element, class or id: pseudo-classes, pseudo-elements and attributes
+	selectorNoisePattern = regexp.MustCompile(`::?[a-zA-Z-]+(\([^)]*\))?|\[[^\]]*\]`)
+	selectorTokenPattern = regexp.MustCompile(`[.#]?[a-zA-Z_][\w-]*|\*`)
+)
+
+// inlineCriticalCSS inlines the CSS each kind of page uses into its head and
+// loads the full stylesheet asynchronously
+// Pages are grouped into home, post and list, and the critical CSS of a group
+// is every rule matching an element, class or id used by any of its pages
+func inlineCriticalCSS(publicDir string, postURLs map[string]bool) error {
+	groups := map[string][]string{}
+	err := filepath.WalkDir(publicDir, func(file string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(file, ".html") {
+			return err
+		}
+		rel, err := filepath.Rel(publicDir, file)
+		if err != nil {
+			return err
+		}
+		url := "/" + filepath.ToSlash(rel)
+		switch {
+		case url == "/index.html":
+			groups["home"] = append(groups["home"], file)
+		case postURLs[url]:
+			groups["post"] = append(groups["post"], file)
+		default:
+			groups["list"] = append(groups["list"], file)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, kind := range sortedKeys(groups) {
+		files := groups[kind]
+		// Collect what the group uses per stylesheet
+		used := map[string]map[string]bool{}
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return err
+			}
+			for _, m := range stylesheetPattern.FindAllStringSubmatch(string(data), -1) {
+				if used[m[1]] == nil {
+					used[m[1]] = map[string]bool{}
+				}
+				collectHTMLTokens(string(data), used[m[1]])
+			}
+		}
+
+		critical := map[string]string{}
+		for href, tokens := range used {
+			css, err := os.ReadFile(filepath.Join(publicDir, filepath.FromSlash(href)))
+			if err != nil {
+				log.Warnf("critical CSS: %s: %v", href, err)
+				continue
+			}
+			critical[href] = filterCSS(string(minifyCSS(css)), tokens)
+			log.Debugf("critical CSS for %s pages from %s: %d of %d bytes", kind, href, len(critical[href]), len(css))
+		}
+
+		for _, file := range files {
+			if err := inlineStylesheets(file, critical); err != nil {
+				return fmt.Errorf("%s: %w", file, err)
+			}
+		}
+	}
+	return nil
+}
+
+// inlineStylesheets replaces each stylesheet link in file that has critical
+// CSS with an inline style and an asynchronously loaded link
+func inlineStylesheets(file string, critical map[string]string) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	html := stylesheetPattern.ReplaceAllStringFunc(string(data), func(link string) string {
+		href := stylesheetPattern.FindStringSubmatch(link)[1]
+		css, ok := critical[href]
+		if !ok {
+			return link
+		}
+		return fmt.Sprintf(`<style>%s</style>`+
+			`<link rel="preload" href="%s" as="style" onload="this.onload=null;this.rel='stylesheet'">`+
+			`<noscript>%s</noscript>`, css, href, link)
+	})
+	return os.WriteFile(file, []byte(html), 0644)
+}
+
+// collectHTMLTokens adds the element names, .classes and #ids used in html
+func collectHTMLTokens(html string, tokens map[string]bool) {
+	for _, m := range htmlTagPattern.FindAllStringSubmatch(html, -1) {
+		tokens[strings.ToLower(m[1])] = true
+		if c := htmlClassPattern.FindStringSubmatch(m[2]); c != nil {
+			for _, class := range strings.Fields(c[1]) {
+				tokens["."+class] = true
+			}
+		}
+		if id := htmlIDPattern.FindStringSubmatch(m[2]); id != nil {
+			tokens["#"+id[1]] = true
+		}
+	}
+}
+
+// filterCSS keeps the rules of minified css whose selectors can match tokens
+// Rules inside @media and @supports are filtered, other at-rules are kept whole
+func filterCSS(css string, tokens map[string]bool) string {
+	var b strings.Builder
+	for len(css) > 0 {
+		open := strings.IndexByte(css, '{')
+		semi := statementEnd(css)
+
+		// Statement at-rules such as @import and @charset
+		if semi != -1 && (open == -1 || semi < open) {
+			if strings.HasPrefix(css, "@") {
+				b.WriteString(css[:semi+1])
+			}
+			css = css[semi+1:]
+			continue
+		}
+		if open == -1 {
+			break
+		}
+
+		prelude := css[:open]
+		end := matchingBrace(css, open)
+		body := css[open+1 : end]
+		css = css[min(end+1, len(css)):]
+
+		switch {
+		case strings.HasPrefix(prelude, "@media"), strings.HasPrefix(prelude, "@supports"):
+			if inner := filterCSS(body, tokens); inner != "" {
+				b.WriteString(prelude + "{" + inner + "}")
+			}
+		case strings.HasPrefix(prelude, "@"):
+			b.WriteString(prelude + "{" + body + "}")
+		case selectorUsed(prelude, tokens):
+			b.WriteString(prelude + "{" + body + "}")
+		}
+	}
+	return b.String()
+}
+
+// statementEnd returns the index of the first semicolon outside quotes and
+// parentheses, e.g. the end of @import url(...;...);
+func statementEnd(css string) int {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(css); i++ {
+		c := css[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ';' && depth <= 0:
+			return i
+		}
+	}
+	return -1
+}
+
+// matchingBrace returns the index of the brace closing the one at open
+func matchingBrace(css string, open int) int {
+	depth := 0
+	for i := open; i < len(css); i++ {
+		switch css[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(css)
+}
+
+// selectorUsed reports whether any selector in a selector list only names
+// elements, classes and ids present in tokens
+func selectorUsed(selectors string, tokens map[string]bool) bool {
+	for _, selector := range strings.Split(selectors, ",") {
+		parts := selectorTokenPattern.FindAllString(selectorNoisePattern.ReplaceAllString(selector, " "), -1)
+		used := true
+		for _, part := range parts {
+			if part == "*" {
+				continue
+			}
+			if !strings.HasPrefix(part, ".") && !strings.HasPrefix(part, "#") {
+				part = strings.ToLower(part)
+			}
+			if !tokens[part] {
+				used = false
+				break
+			}
+		}
+		if used {
+			return true
+		}
+	}
+	return false
+}
+
+// postURLSet returns the URLs of posts, for grouping pages by kind
+func postURLSet(posts []Page) map[string]bool {
+	urls := map[string]bool{}
+	for _, post := range posts {
+		urls[post.URL] = true
+	}
+	return urls
+}
+
+// sortedKeys returns the keys of m in order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// dataDir is where data files conventionally live, e.g. data/authors.yaml
+const dataDir = "data"
+
+// projectFile resolves name against the directory base, or the project
+// root when it starts with /, refusing files outside the project, symlinks
+// included, so data functions can't read e.g. ~/.ssh
+func projectFile(base, name string) (string, error) {
+	if strings.HasPrefix(name, "/") {
+		base = "."
+	}
+	root, err := realPath(".")
+	if err != nil {
+		return "", err
+	}
+	file := filepath.Join(base, filepath.FromSlash(name))
+	real, err := realPath(file)
+	if err != nil {
+		return "", err
+	}
+	if !within(real, root) {
+		return "", fmt.Errorf("%s is outside the project", name)
+	}
+	return file, nil
+}
+
+// readCSV returns the rows of a CSV file in the project, the header row
+// first, e.g. {{range readCSV "data/prices.csv"}}
+func readCSV(name string) ([][]string, error) {
+	file, err := projectFile(".", name)
+	if err != nil {
+		return nil, err
+	}
+	return readCSVFile(file)
+}
+
+func readCSVFile(file string) ([][]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1 // short rows are the writer's business
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return rows, nil
+}
+
+// readJSON returns the value in a JSON file in the project: objects as maps,
+// arrays as slices, e.g. {{range (readJSON "data/team.json").members}}
+func readJSON(name string) (any, error) {
+	file, err := projectFile(".", name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return value, nil
+}
+
+// tableShortcode renders a CSV file next to the page as a table, its first
+// row as the header: {{< table "prices.csv" >}}
+func tableShortcode(ctx shortcodeContext, args []string) (string, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("want one CSV file")
+	}
+	file, err := projectFile(filepath.Dir(ctx.file), args[0])
+	if err != nil {
+		return "", err
+	}
+	rows, err := readCSVFile(file)
+	if err != nil {
+		return "", err
+	}
+	if len(rows) == 0 {
+		return "", nil
+	}
+
+	// No blank lines, they would end the HTML block
+	var b strings.Builder
+	b.WriteString("<table>\n<thead>\n<tr>")
+	for _, cell := range rows[0] {
+		b.WriteString("<th>" + html.EscapeString(cell) + "</th>")
+	}
+	b.WriteString("</tr>\n</thead>\n<tbody>\n")
+	for _, row := range rows[1:] {
+		b.WriteString("<tr>")
+		for _, cell := range row {
+			b.WriteString("<td>" + html.EscapeString(cell) + "</td>")
+		}
+		b.WriteString("</tr>\n")
+	}
+	b.WriteString("</tbody>\n</table>")
+	return b.String(), nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	// Zones load the same on machines without a zoneinfo database, e.g.
+	// minimal CI images
+	_ "time/tzdata"
+)
+
+// dateLayouts are the frontmatter date formats slate accepts, tried in order
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"2006/01/02",
+	"January 2, 2006",
+	"Jan 2, 2006",
+	"2 January 2006",
+	"2 Jan 2006",
+	time.RFC1123Z,
+	time.RFC1123,
+}
+
+// parseDate parses a frontmatter date in any of dateLayouts
+// Dates without a zone are interpreted in loc
+func parseDate(value string, loc *time.Location) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized date %q (use e.g. 2006-01-02, 2006-01-02 15:04 or RFC 3339)", value)
+}
+
+// pageDate parses a page's frontmatter date, interpreting a date without
+// an offset in the page's timezone
+func (c *Config) pageDate(fm Frontmatter) (time.Time, error) {
+	loc, err := c.pageLocation(fm)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return parseDate(fm.Date, loc)
+}
+
+// pageLocation returns the timezone from a page's frontmatter, the site
+// timezone without one
+func (c *Config) pageLocation(fm Frontmatter) (*time.Location, error) {
+	if fm.Timezone == "" {
+		return c.location(), nil
+	}
+	loc, err := time.LoadLocation(fm.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q", fm.Timezone)
+	}
+	return loc, nil
+}
+
+// location returns the site timezone from slate.yaml, UTC by default
+func (c *Config) location() *time.Location {
+	if c.loc == nil {
+		return time.UTC
+	}
+	return c.loc
+}
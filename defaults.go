@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultsFile sets default frontmatter for the content files in its
+// directory and below, like the cascade key of an _index.md
+const defaultsFile = "_defaults.yaml"
+
+// sectionIndexFile holds settings for the content directory it's in, like
+// cascade, rather than a page of its own
+const sectionIndexFile = "_index.md"
+
+// isSectionIndex reports whether file is a section's _index.md
+func isSectionIndex(file string) bool {
+	return filepath.Base(file) == sectionIndexFile
+}
+
+// contentDefaults maps content directories to the frontmatter they set for
+// the files in and below them
+type contentDefaults map[string]map[string]any
+
+// loadContentDefaults reads the defaults of the directories holding files
+// and of their parents, up to the content directory
+func loadContentDefaults(cfg *Config, files []string) (contentDefaults, error) {
+	defaults := contentDefaults{}
+	for _, file := range files {
+		for _, dir := range contentAncestors(cfg, file) {
+			if _, ok := defaults[dir]; ok {
+				continue
+			}
+			values, err := readDirDefaults(dir)
+			if err != nil {
+				return nil, err
+			}
+			defaults[dir] = values
+		}
+	}
+	return defaults, nil
+}
+
+// contentAncestors returns the directories from the content directory down
+// to the one holding file
+func contentAncestors(cfg *Config, file string) []string {
+	root := filepath.Clean(cfg.contentDir())
+	var dirs []string
+	for dir := filepath.Dir(file); ; dir = filepath.Dir(dir) {
+		dirs = append([]string{dir}, dirs...)
+		if dir == root || dir == filepath.Dir(dir) {
+			return dirs
+		}
+	}
+}
+
+// readDirDefaults reads the defaults dir sets, from its _defaults.yaml and
+// then the cascade key of its _index.md, which wins where both set a key
+func readDirDefaults(dir string) (map[string]any, error) {
+	values := map[string]any{}
+	file := filepath.Join(dir, defaultsFile)
+	data, err := os.ReadFile(file)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		if err := yaml.Unmarshal(data, &values); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		if values == nil {
+			values = map[string]any{}
+		}
+		warnUnknownDefaults(file, values)
+	}
+
+	// _index.md can be mounted from a module, like any content file
+	index := filepath.Join(dir, sectionIndexFile)
+	content, err := readContent(index)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		fm, _, err := parseFrontmatter(content)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", index, err)
+		}
+		warnUnknownDefaults(index+": cascade", fm.Cascade)
+		for key, value := range fm.Cascade {
+			values[key] = value
+		}
+	}
+
+	// Defaults don't cascade defaults of their own
+	delete(values, "cascade")
+	return values, nil
+}
+
+func warnUnknownDefaults(source string, values map[string]any) {
+	for _, key := range sortedKeys(values) {
+		if !isKnownFrontmatterKey(key) {
+			log.Warnf("%s: unknown frontmatter key %q", source, key)
+		}
+	}
+}
+
+// frontmatter returns the defaults for file, the nearest directory's winning
+// over its parents', as a Frontmatter for parseFrontmatterOver
+func (d contentDefaults) frontmatter(cfg *Config, file string) (Frontmatter, error) {
+	values := map[string]any{}
+	for _, dir := range contentAncestors(cfg, file) {
+		for key, value := range d[dir] {
+			values[key] = value
+		}
+	}
+	var fm Frontmatter
+	if len(values) == 0 {
+		return fm, nil
+	}
+	data, err := yaml.Marshal(values)
+	if err != nil {
+		return fm, err
+	}
+	if err := yaml.Unmarshal(data, &fm); err != nil {
+		return fm, fmt.Errorf("frontmatter defaults: %w", err)
+	}
+	return fm, nil
+}
@@ -0,0 +1,463 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"math/rand"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// DeployConfig lists where `slate deploy` publishes the output
+type DeployConfig struct {
+	Targets      []DeployTarget `yaml:"targets"`
+	CacheControl []CacheRule    `yaml:"cacheControl"` // first match wins, before the defaults
+}
+
+// DeployTarget is one destination, e.g. a bucket
+// Which fields apply depends on Type
+type DeployTarget struct {
+	Name string `yaml:"name"`
+	Type string `yaml:"type"` // s3, github-pages or rsync
+
+	Bucket   string `yaml:"bucket"`
+	Region   string `yaml:"region"`   // defaults to us-east-1, "auto" for R2
+	Endpoint string `yaml:"endpoint"` // for S3-compatible storage, e.g. R2
+	Prefix   string `yaml:"prefix"`   // key prefix inside the bucket
+
+	Remote string `yaml:"remote"` // git remote, defaults to origin
+	Branch string `yaml:"branch"` // defaults to gh-pages
+	Folder string `yaml:"folder"` // commit to this folder of the current branch instead, e.g. docs
+	CNAME  string `yaml:"cname"`  // custom domain
+
+	Host string `yaml:"host"`
+	User string `yaml:"user"`
+	Port int    `yaml:"port"`
+	Path string `yaml:"path"` // directory on the server
+	Key  string `yaml:"key"`  // SSH private key file
+
+	Parallel  int     `yaml:"parallel"`  // concurrent requests, default 8
+	Retries   int     `yaml:"retries"`   // retries of a failed request, default 3, -1 for none
+	RateLimit float64 `yaml:"rateLimit"` // requests per second, unlimited by default
+}
+
+// CacheRule sets the Cache-Control of uploaded files matching a pattern
+type CacheRule struct {
+	Match string `yaml:"match"` // file name or path pattern, e.g. *.pdf or /downloads/*
+	Value string `yaml:"value"`
+}
+
+// deployer publishes a directory to a target
+type deployer interface {
+	deploy(ctx context.Context, dir string, opts deployOptions) (deployStats, error)
+}
+
+// restorer puts a file kept in the trash back on a target, see `slate restore`
+type restorer interface {
+	restore(ctx context.Context, name, file string, opts deployOptions) error
+}
+
+// deployOptions controls a single deploy
+type deployOptions struct {
+	DryRun  bool   // report changes without making them
+	Force   bool   // upload every file, even unchanged ones
+	Message string // commit message, for git based targets
+	Cache   []CacheRule
+	Types   map[string]string
+	Trash   *trashBatch // keeps deleted files, nil deletes them outright
+	Output  string      // the output directory, of which the deployed one is a snapshot
+	Sources []string    // the project's source directories, which a deploy must not replace
+}
+
+// deployStats counts what a deploy changed
+type deployStats struct {
+	Uploaded, Deleted, Unchanged int
+}
+
+// deployers creates the deployer for each target type
+var deployers = map[string]func(DeployTarget) (deployer, error){
+	"s3":           newS3Deployer,
+	"github-pages": newGHPagesDeployer,
+	"rsync":        newRsyncDeployer,
+}
+
+func deployCmd(args []string) {
+	flags := flag.NewFlagSet("deploy", flag.ExitOnError)
+	targetName := flags.String("target", "", "target to deploy to, defaults to the first configured")
+	dryRun := flags.Bool("dry-run", false, "list the changes without uploading or deleting anything")
+	force := flags.Bool("force", false, "upload every file, e.g. after changing cache rules")
+	message := flags.String("message", "", "commit message for git based targets")
+	flags.Parse(args)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	start := time.Now()
+	stats, err := deploy(ctx, *targetName, deployOptions{DryRun: *dryRun, Force: *force, Message: *message})
+	if errors.Is(err, context.Canceled) {
+		err = errors.New("interrupted")
+	}
+	if !*dryRun {
+		recordHistory("deploy", start, stats.Uploaded, err)
+	}
+	if err != nil {
+		log.Errorf("deploy failed: %v", err)
+		stop()
+		os.Exit(1)
+	}
+}
+
+// deploy publishes the built site to the named target
+func deploy(ctx context.Context, targetName string, opts deployOptions) (deployStats, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return deployStats{}, fmt.Errorf("loading %s: %w", configFile, err)
+	}
+	target, err := cfg.Deploy.target(targetName)
+	if err != nil {
+		return deployStats{}, err
+	}
+	newDeployer, ok := deployers[target.Type]
+	if !ok {
+		return deployStats{}, fmt.Errorf("target %s: unknown type %q", target.Name, target.Type)
+	}
+	d, err := newDeployer(target)
+	if err != nil {
+		return deployStats{}, fmt.Errorf("target %s: %w", target.Name, err)
+	}
+
+	dir := cfg.outputDir()
+	if _, err := os.Stat(dir); err != nil {
+		return deployStats{}, fmt.Errorf("missing %s/ directory. Did you run `slate build`?", dir)
+	}
+	opts.Output = dir
+	opts.Sources = []string{cfg.contentDir(), cfg.templateDir(), cfg.staticDir(), dataDir}
+	opts.Cache = cfg.Deploy.CacheControl
+	opts.Types = mimeTypes(cfg)
+	if !opts.DryRun {
+		opts.Trash = newTrashBatch(cfg, "deploy")
+		defer func() {
+			if err := opts.Trash.close(); err != nil {
+				log.Warnf("writing the trash: %v", err)
+			}
+		}()
+	}
+
+	// Deploy a copy of the last completed build, which a build starting
+	// meanwhile can't change
+	snapshot, cleanup, err := snapshotOutput(ctx, dir)
+	if err != nil {
+		return deployStats{}, err
+	}
+	defer cleanup()
+
+	log.Infof("Deploying %s/ to %s", dir, target.Name)
+	stats, err := d.deploy(ctx, snapshot, opts)
+	if err != nil {
+		return stats, err
+	}
+	verb := "Deployed"
+	if opts.DryRun {
+		verb = "Dry run:"
+	}
+	log.Infof("%s %d uploaded, %d deleted, %d unchanged", verb, stats.Uploaded, stats.Deleted, stats.Unchanged)
+	kind := "deployed"
+	if opts.DryRun {
+		kind = "dry-run"
+	}
+	record(kind, target.Name, stats.Uploaded, stats.Deleted, stats.Unchanged)
+	return stats, nil
+}
+
+// target returns the named target, or the first one when name is empty
+// Types that need no settings, like github-pages, can be named directly
+func (c DeployConfig) target(name string) (DeployTarget, error) {
+	if name == "" {
+		if len(c.Targets) == 0 {
+			return DeployTarget{}, fmt.Errorf("no deploy targets in %s", configFile)
+		}
+		return c.Targets[0].withName(0), nil
+	}
+	for i, t := range c.Targets {
+		if t.withName(i).Name == name {
+			return t.withName(i), nil
+		}
+	}
+	if _, ok := deployers[name]; ok {
+		return DeployTarget{Name: name, Type: name}, nil
+	}
+	return DeployTarget{}, fmt.Errorf("no deploy target named %q", name)
+}
+
+// withName defaults an unnamed target's name to its type
+func (t DeployTarget) withName(i int) DeployTarget {
+	if t.Name == "" {
+		t.Name = t.Type
+		if i > 0 {
+			t.Name = fmt.Sprintf("%s-%d", t.Type, i+1)
+		}
+	}
+	return t
+}
+
+// listOutput returns the files under dir, keyed by slash-separated path
+// relative to dir
+func listOutput(dir string) (map[string]string, error) {
+	files := map[string]string{}
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = p
+		return nil
+	})
+	return files, err
+}
+
+// fingerprintPattern matches file names carrying a content hash, e.g.
+// styles.1a2b3c4d.css
+var fingerprintPattern = regexp.MustCompile(`\.[0-9a-f]{8,}\.[a-z0-9]+$`)
+
+// sharedAssetPattern matches the deduplicated copies named by their content
+// hash, e.g. assets/3f2a9c1b5d6e7f80.png, unlike other files under assets/
+var sharedAssetPattern = regexp.MustCompile(`^` + sharedAssetsDir + `/[0-9a-f]{16}\.[a-z0-9]+$`)
+
+// cacheControl returns the Cache-Control for an output file
+// Configured rules come first. By default HTML is revalidated on every
+// request, hashed assets are cached forever and everything else for an hour.
+func cacheControl(rules []CacheRule, name string) string {
+	for _, rule := range rules {
+		pattern := rule.Match
+		target := path.Base(name)
+		if strings.Contains(pattern, "/") {
+			pattern = strings.TrimPrefix(pattern, "/")
+			target = name
+		}
+		if ok, _ := path.Match(pattern, target); ok {
+			return rule.Value
+		}
+	}
+
+	switch {
+	case strings.HasSuffix(name, ".html"):
+		return "public, max-age=0, must-revalidate"
+	case sharedAssetPattern.MatchString(name), fingerprintPattern.MatchString(name):
+		return "public, max-age=31536000, immutable"
+	default:
+		return "public, max-age=3600"
+	}
+}
+
+// transferOptions controls how a target's uploads and deletes run
+type transferOptions struct {
+	Parallel  int     // requests in flight
+	Retries   int     // attempts after the first failure of a request
+	RateLimit float64 // requests per second, 0 for no limit
+}
+
+// transfer returns the target's transfer settings with defaults applied
+func (t DeployTarget) transfer() transferOptions {
+	opts := transferOptions{Parallel: t.Parallel, Retries: t.Retries, RateLimit: t.RateLimit}
+	if opts.Parallel <= 0 {
+		opts.Parallel = 8
+	}
+	if opts.Retries == 0 {
+		opts.Retries = 3
+	}
+	return opts
+}
+
+// deployTask is a single upload or delete
+type deployTask struct {
+	name string
+	run  func(ctx context.Context) error
+	done func() // called after run succeeds
+}
+
+// permanentError marks a failure that retrying won't fix, e.g. access denied
+type permanentError struct{ error }
+
+func (e permanentError) Unwrap() error { return e.error }
+
+// runDeployTasks runs tasks concurrently, retrying failed ones with
+// exponential backoff. The first task that still fails cancels the rest.
+func runDeployTasks(ctx context.Context, opts transferOptions, tasks []deployTask) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	limiter := newRateLimiter(opts.RateLimit)
+	queue := make(chan deployTask)
+	var wg sync.WaitGroup
+	for range min(opts.Parallel, len(tasks)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for task := range queue {
+				if err := runWithRetries(ctx, opts.Retries, limiter, task); err != nil {
+					cancel(fmt.Errorf("%s: %w", task.name, err))
+					return
+				}
+				if task.done != nil {
+					task.done()
+				}
+			}
+		}()
+	}
+
+feed:
+	for _, task := range tasks {
+		select {
+		case queue <- task:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(queue)
+	wg.Wait()
+	return context.Cause(ctx)
+}
+
+// runWithRetries runs task until it succeeds, fails permanently or has
+// been retried retries times, waiting 0.5s, 1s, 2s... between attempts
+func runWithRetries(ctx context.Context, retries int, limiter *rateLimiter, task deployTask) error {
+	for attempt := 0; ; attempt++ {
+		if err := limiter.wait(ctx); err != nil {
+			return err
+		}
+		err := task.run(ctx)
+		var permanent permanentError
+		if err == nil || ctx.Err() != nil || errors.As(err, &permanent) || attempt >= retries {
+			return err
+		}
+
+		delay := 500 * time.Millisecond << attempt
+		delay += time.Duration(rand.Int63n(int64(delay) / 2)) // spread out retries of parallel requests
+		log.Warnf("%s: %v, retrying in %s", task.name, err, delay.Round(time.Millisecond))
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// rateLimiter spaces requests evenly, nil for no limit
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func newRateLimiter(perSecond float64) *rateLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	return &rateLimiter{interval: time.Duration(float64(time.Second) / perSecond)}
+}
+
+// wait blocks until the next request may start
+func (l *rateLimiter) wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	delay := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+	select {
+	case <-time.After(delay):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// deployState records the files a deploy has uploaded, so an interrupted
+// deploy continues where it left off instead of starting over
+// It is kept in .slate/deploy-<target>.state, one "name<TAB>md5" line per
+// file, and removed once the deploy completes.
+type deployState struct {
+	path string
+	done map[string]string // name → MD5 of the uploaded content
+
+	mu   sync.Mutex
+	file *os.File
+}
+
+// openDeployState reads the state an interrupted deploy to target left,
+// or with fresh, discards it and starts over
+func openDeployState(target string, fresh bool) (*deployState, error) {
+	s := &deployState{
+		path: filepath.Join(".slate", "deploy-"+slugify(target)+".state"),
+		done: map[string]string{},
+	}
+	flags := os.O_APPEND | os.O_CREATE | os.O_WRONLY
+	if fresh {
+		flags |= os.O_TRUNC
+	} else if data, err := os.ReadFile(s.path); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			if name, sum, ok := strings.Cut(line, "\t"); ok {
+				s.done[name] = sum
+			}
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(s.path, flags, 0644)
+	if err != nil {
+		return nil, err
+	}
+	s.file = file
+	return s, nil
+}
+
+// uploaded reports whether an earlier, interrupted run already uploaded
+// this version of the file
+func (s *deployState) uploaded(name, sum string) bool {
+	return s != nil && s.done[name] == sum
+}
+
+// record notes a finished upload
+func (s *deployState) record(name, sum string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fmt.Fprintf(s.file, "%s\t%s\n", name, sum)
+}
+
+// close keeps the state for the next run after a failure, and removes it
+// after a complete deploy
+func (s *deployState) close(complete bool) {
+	if s == nil {
+		return
+	}
+	s.file.Close()
+	if complete {
+		os.Remove(s.path)
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import "testing"
+
+func TestCacheControl(t *testing.T) {
+	const (
+		revalidate = "public, max-age=0, must-revalidate"
+		immutable  = "public, max-age=31536000, immutable"
+		hour       = "public, max-age=3600"
+	)
+	rules := []CacheRule{
+		{Match: "*.woff2", Value: "public, max-age=604800"},
+		{Match: "/downloads/*", Value: "no-store"},
+	}
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"index.html", revalidate},
+		{"blog/post.html", revalidate},
+		{"styles.1a2b3c4d.css", immutable},
+		{"js/app.0123456789abcdef.js", immutable},
+		{"assets/0123456789abcdef.png", immutable},
+		{"assets/logo.png", hour},
+		{"assets/img/0123456789abcdef.png", hour},
+		{"styles.css", hour},
+		{"fonts/inter.woff2", "public, max-age=604800"},
+		{"downloads/report.pdf", "no-store"},
+		{"blog/downloads/report.pdf", hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cacheControl(rules, tt.name); got != tt.want {
+				t.Errorf("cacheControl(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
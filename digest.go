@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// DigestConfig turns posts into periodic roundup pages and a feed
+type DigestConfig struct {
+	Cadence string `yaml:"cadence"` // weekly or monthly, empty disables digests
+	Title   string `yaml:"title"`   // e.g. Monthly Roundup
+}
+
+// DigestPage is the data passed to templates/digest.html
+type DigestPage struct {
+	Title string
+	Label string // e.g. "January 2025" or "Week of Jan 6, 2025"
+	URL   string
+	Start time.Time
+	End   time.Time
+	Pages []Page
+}
+
+// collectDigests groups posts into weekly or monthly digests, newest first
+func collectDigests(cfg *Config, posts []Page) ([]DigestPage, error) {
+	if cfg.Digest.Cadence != "weekly" && cfg.Digest.Cadence != "monthly" {
+		return nil, fmt.Errorf("digest cadence must be weekly or monthly, got %q", cfg.Digest.Cadence)
+	}
+
+	title := cfg.Digest.Title
+	if title == "" {
+		title = "Digest"
+	}
+
+	byStart := map[time.Time]*DigestPage{}
+	for _, post := range posts {
+		if post.Date.IsZero() {
+			continue
+		}
+
+		var digest DigestPage
+		date := post.Date
+		if cfg.Digest.Cadence == "weekly" {
+			// Weeks start on Monday
+			offset := (int(date.Weekday()) + 6) % 7
+			digest.Start = time.Date(date.Year(), date.Month(), date.Day()-offset, 0, 0, 0, 0, date.Location())
+			digest.End = digest.Start.AddDate(0, 0, 7)
+			year, week := digest.Start.ISOWeek()
+			digest.URL = fmt.Sprintf("/digest/%d-w%02d/", year, week)
+			digest.Label = "Week of " + digest.Start.Format("Jan 2, 2006")
+		} else {
+			digest.Start = time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, date.Location())
+			digest.End = digest.Start.AddDate(0, 1, 0)
+			digest.URL = "/digest/" + digest.Start.Format("2006-01") + "/"
+			digest.Label = digest.Start.Format("January 2006")
+		}
+		digest.Title = title + ": " + digest.Label
+
+		if byStart[digest.Start] == nil {
+			byStart[digest.Start] = &digest
+		}
+		byStart[digest.Start].Pages = append(byStart[digest.Start].Pages, post)
+	}
+
+	var digests []DigestPage
+	for _, digest := range byStart {
+		sort.Slice(digest.Pages, func(i, j int) bool {
+			return digest.Pages[i].Date.Before(digest.Pages[j].Date)
+		})
+		digests = append(digests, *digest)
+	}
+	sort.Slice(digests, func(i, j int) bool {
+		return digests[i].Start.After(digests[j].Start)
+	})
+	return digests, nil
+}
+
+// digestFeedItem renders the HTML body of a digest feed entry
+const digestFeedItem = `<ul>{{range .Pages}}<li><a href="{{absURL .URL}}">{{.Title}}</a>{{with .Summary}} — {{.}}{{end}}</li>{{end}}</ul>`
+
+// writeDigestFeed writes an Atom feed with one entry per digest
+func writeDigestFeed(cfg *Config, digests []DigestPage, outputPath string) error {
+	title := cfg.Digest.Title
+	if title == "" {
+		title = "Digest"
+	}
+
+	feed := atomFeed{
+		ID:    absURL(cfg, "/digest/"),
+		Title: title,
+		Links: []atomLink{
+			{Href: absURL(cfg, "/digest/feed.xml"), Rel: "self"},
+			{Href: absURL(cfg, "/")},
+		},
+	}
+
+	tmpl, err := template.New("digest").Funcs(template.FuncMap{
+		"absURL": func(url string) string { return absURL(cfg, url) },
+	}).Parse(digestFeedItem)
+	if err != nil {
+		return err
+	}
+	for _, digest := range digests {
+		var body bytes.Buffer
+		if err := tmpl.Execute(&body, digest); err != nil {
+			return err
+		}
+		updated := digest.Pages[len(digest.Pages)-1].Date
+		feed.Entries = append(feed.Entries, atomEntry{
+			ID:      absURL(cfg, digest.URL),
+			Title:   digest.Title,
+			Updated: feedTime(updated),
+			Link:    atomLink{Href: absURL(cfg, digest.URL)},
+			Content: atomContent{Type: "html", Body: body.String()},
+		})
+	}
+	if len(digests) > 0 {
+		feed.Updated = feed.Entries[0].Updated
+	} else {
+		feed.Updated = feedTime(buildTime)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		return err
+	}
+	return writeAtomFeed(feed, outputPath)
+}
@@ -0,0 +1,440 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	_ "golang.org/x/image/webp"
+	"gopkg.in/yaml.v3"
+)
+
+// Severities of doctor findings, most pressing first
+const (
+	doctorError   = iota // the site won't build, or builds wrong
+	doctorWarning        // the site builds, but something is likely off
+	doctorTip            // worth a look
+)
+
+var doctorSeverities = []string{"error", "warning", "tip"}
+
+// doctorFinding is a problem `slate doctor` found and what to do about it
+type doctorFinding struct {
+	Severity int
+	Area     string // e.g. config, templates, images
+	Problem  string
+	Fix      string
+}
+
+// largeImageSize is the size above which an image is worth shrinking
+const largeImageSize = 500 << 10
+
+// largeImageWidth is the width above which an image is larger than any
+// screen shows it
+const largeImageWidth = 2560
+
+// doctorCmd inspects the project for problems that make a site fail to
+// build or come out wrong, and prints them most pressing first with a fix
+// for each. It exits with 1 when one is an error.
+func doctorCmd(args []string) {
+	flags := flag.NewFlagSet("doctor", flag.ExitOnError)
+	skipTheme := flags.Bool("skip-theme", false, "don't render the theme against the test fixtures to find fields slate no longer has")
+	flags.Parse(args)
+
+	findings := diagnose(*skipTheme)
+	sort.SliceStable(findings, func(i, j int) bool { return findings[i].Severity < findings[j].Severity })
+
+	failed := 0
+	for _, f := range findings {
+		if f.Severity == doctorError {
+			failed++
+		}
+		record("finding", doctorSeverities[f.Severity], f.Area, f.Problem, f.Fix)
+	}
+	if porcelainOut == nil {
+		for _, f := range findings {
+			fmt.Printf("%-8s %s: %s\n%8s → %s\n", doctorSeverities[f.Severity], f.Area, f.Problem, "", f.Fix)
+		}
+		if len(findings) == 0 {
+			log.Infof("No problems found")
+		} else {
+			log.Infof("Found %d problem(s), %d error(s)", len(findings), failed)
+		}
+	}
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// diagnose runs every check against the project in the working directory
+func diagnose(skipTheme bool) []doctorFinding {
+	var findings []doctorFinding
+	add := func(severity int, area, fix, format string, args ...any) {
+		findings = append(findings, doctorFinding{Severity: severity, Area: area, Problem: fmt.Sprintf(format, args...), Fix: fix})
+	}
+
+	// Builds work without a config, with the default settings
+	if _, err := os.Stat(configFile); os.IsNotExist(err) {
+		add(doctorTip, "config", "add one to set the title, baseURL and other settings, or run slate from the project root",
+			"no %s here, the defaults apply", configFile)
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		add(doctorError, "config", "fix the file, slate falls back to no settings until then",
+			"%s doesn't load: %v", configFile, err)
+		cfg = &Config{}
+	}
+	findings = append(findings, diagnoseConfigKeys(cfg)...)
+	if cfg.BaseURL == "" {
+		add(doctorWarning, "config", fmt.Sprintf("set baseURL in %s, e.g. baseURL: https://example.com", configFile),
+			"baseURL isn't set, so there's no sitemap or digest feed and canonical URLs are relative")
+	}
+
+	if _, err := os.Stat(cfg.contentDir()); err != nil {
+		add(doctorError, "content", "create it and add pages, or point dirs.content at your content",
+			"no content directory %s/", cfg.contentDir())
+	}
+
+	missing := false
+	for _, name := range requiredTemplates {
+		if _, err := os.Stat(filepath.Join(cfg.templateDir(), name)); err != nil {
+			add(doctorError, "templates", "add it, `slate init` in an empty directory writes a starter one to copy",
+				"missing required template %s", filepath.Join(cfg.templateDir(), name))
+			missing = true
+		}
+	}
+	for _, name := range optionalTemplates {
+		if name == "fallback.html" {
+			continue // fallback pages use post.html without it
+		}
+		if _, err := os.Stat(filepath.Join(cfg.templateDir(), name)); err != nil {
+			add(doctorTip, "templates", "add it if you want those pages",
+				"no %s, so those pages aren't rendered", filepath.Join(cfg.templateDir(), name))
+		}
+	}
+	if cfg.Downloads.enabled() {
+		if _, err := os.Stat(filepath.Join(cfg.templateDir(), "download.html")); err != nil {
+			add(doctorError, "templates", "add it, or remove downloads from "+configFile,
+				"downloads are enabled but %s is missing", filepath.Join(cfg.templateDir(), "download.html"))
+		}
+	}
+	if cfg.Digest.Cadence != "" {
+		if _, err := os.Stat(filepath.Join(cfg.templateDir(), "digest.html")); err != nil {
+			add(doctorError, "templates", "add it, or remove digest from "+configFile,
+				"digests are enabled but %s is missing", filepath.Join(cfg.templateDir(), "digest.html"))
+		}
+	}
+	// The theme fixtures can't render templates that don't parse
+	templateFindings := diagnoseTemplates(cfg)
+	findings = append(findings, templateFindings...)
+	if !missing && !skipTheme && len(templateFindings) == 0 {
+		findings = append(findings, diagnoseTheme(cfg)...)
+	}
+
+	findings = append(findings, diagnoseContent(cfg)...)
+	findings = append(findings, diagnoseImages(cfg)...)
+	findings = append(findings, diagnoseSymlinks(cfg)...)
+	return findings
+}
+
+// diagnoseConfigKeys reports keys in the config files that no setting
+// reads, which yaml otherwise ignores without a word
+func diagnoseConfigKeys(cfg *Config) []doctorFinding {
+	files := []string{configFile}
+	if cfg.Env != "" {
+		files = append(files, envConfigFile(cfg.Env))
+	}
+	var findings []doctorFinding
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		var doc yaml.Node
+		if err := yaml.Unmarshal(data, &doc); err != nil || len(doc.Content) == 0 {
+			continue
+		}
+		for _, key := range unknownConfigKeys(doc.Content[0], reflect.TypeOf(Config{}), "") {
+			fix := "remove it, slate ignores it"
+			if key.suggestion != "" {
+				fix = fmt.Sprintf("did you mean %s?", key.suggestion)
+			}
+			findings = append(findings, doctorFinding{
+				Severity: doctorWarning,
+				Area:     "config",
+				Problem:  fmt.Sprintf("%s:%d: unknown key %s", file, key.line, key.path),
+				Fix:      fix,
+			})
+		}
+	}
+	return findings
+}
+
+// unknownConfigKey is a key of a config file no setting reads
+type unknownConfigKey struct {
+	path       string // dotted, e.g. deploy.tagret
+	line       int
+	suggestion string // the key probably meant, if any
+}
+
+// unknownConfigKeys returns the keys under node that typ has no field for,
+// descending into nested settings, lists and maps of settings
+func unknownConfigKeys(node *yaml.Node, typ reflect.Type, prefix string) []unknownConfigKey {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	var unknown []unknownConfigKey
+	switch {
+	case typ.Kind() == reflect.Struct && node.Kind == yaml.MappingNode:
+		fields := map[string]reflect.Type{}
+		var names []string
+		for i := 0; i < typ.NumField(); i++ {
+			name, _, _ := strings.Cut(typ.Field(i).Tag.Get("yaml"), ",")
+			if name != "" && name != "-" && typ.Field(i).IsExported() {
+				fields[name] = typ.Field(i).Type
+				names = append(names, name)
+			}
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			path := prefix + key.Value
+			field, ok := fields[key.Value]
+			if !ok {
+				u := unknownConfigKey{path: path, line: key.Line}
+				if suggestion := closestKey(key.Value, names); suggestion != "" {
+					u.suggestion = prefix + suggestion
+				}
+				unknown = append(unknown, u)
+				continue
+			}
+			unknown = append(unknown, unknownConfigKeys(value, field, path+".")...)
+		}
+	case typ.Kind() == reflect.Map && node.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			unknown = append(unknown, unknownConfigKeys(node.Content[i+1], typ.Elem(), prefix+node.Content[i].Value+".")...)
+		}
+	case typ.Kind() == reflect.Slice && node.Kind == yaml.SequenceNode:
+		for i, item := range node.Content {
+			unknown = append(unknown, unknownConfigKeys(item, typ.Elem(), fmt.Sprintf("%s[%d].", strings.TrimSuffix(prefix, "."), i))...)
+		}
+	}
+	return unknown
+}
+
+// diagnoseTemplates reports templates that don't parse, with their partials
+// and the built-in ones
+func diagnoseTemplates(cfg *Config) []doctorFinding {
+	files, _ := filepath.Glob(filepath.Join(cfg.templateDir(), "*.html"))
+	var findings []doctorFinding
+	seen := map[string]bool{}
+	for _, file := range files {
+		_, err := parseTemplate(cfg, filepath.Base(file))
+		// A broken partial breaks every template, once is enough
+		if err == nil || seen[err.Error()] {
+			continue
+		}
+		seen[err.Error()] = true
+		findings = append(findings, doctorFinding{
+			Severity: doctorError,
+			Area:     "templates",
+			Problem:  err.Error(),
+			Fix:      "fix the template syntax, pages using it fail to build",
+		})
+	}
+	return findings
+}
+
+// diagnoseContent reads every content file and reports pages sharing an
+// output URL, posts without a usable date and links to files that neither
+// static/ nor content/ has
+func diagnoseContent(cfg *Config) []doctorFinding {
+	files, err := findContentFiles(cfg)
+	if err != nil {
+		return nil
+	}
+	outputs := map[string]bool{}
+	if assets, err := findAssets(cfg); err == nil {
+		for _, a := range assets {
+			outputs[a.Output] = true
+		}
+	}
+
+	var findings []doctorFinding
+	add := func(severity int, fix, format string, args ...any) {
+		findings = append(findings, doctorFinding{Severity: severity, Area: "content", Problem: fmt.Sprintf(format, args...), Fix: fix})
+	}
+	urls := map[string]string{}
+	for _, file := range files {
+		content, err := readContent(file)
+		if err != nil {
+			add(doctorError, "fix or remove the file", "%s doesn't read: %v", file, err)
+			continue
+		}
+		fm, body, _ := parseFrontmatter(content)
+		if cfg.ExcludeDrafts && fm.Status == "draft" {
+			continue
+		}
+
+		url := cfg.pathToURL(file)
+		if other, ok := urls[url]; ok {
+			add(doctorWarning, "rename one of them, only one page ends up at the URL",
+				"%s and %s are both built to %s", other, file, url)
+		} else {
+			urls[url] = file
+		}
+
+		if strings.Contains(file, "/blog/") {
+			if fm.Date == "" {
+				add(doctorWarning, "add a date, e.g. date: 2006-01-02, so the post is ordered and listed in feeds",
+					"%s has no date", file)
+			} else if _, err := cfg.pageDate(fm); err != nil {
+				add(doctorWarning, "fix the date, the post is treated as undated until then",
+					"%s: %v", file, err)
+			}
+		}
+
+		if layout := pageLayout(fm); layout != "" {
+			if _, err := os.Stat(filepath.Join(cfg.templateDir(), layout)); err != nil {
+				add(doctorError, fmt.Sprintf("add %s/%s or fix the layout", cfg.templateDir(), layout),
+					"%s asks for the layout %s, which doesn't exist", file, layout)
+			}
+		}
+
+		if _, ok := cfg.contentFormat(file); ok && filepath.Ext(file) != ".html" {
+			continue // converted by another tool, its links aren't markdown
+		}
+		offset := len(content) - len(body)
+		for _, m := range append(markdownLinkPattern.FindAllSubmatchIndex(content, -1), linkAttrPattern.FindAllSubmatchIndex(content, -1)...) {
+			if m[2] < offset {
+				continue
+			}
+			href := string(content[m[2]:m[3]])
+			target, ok := resolvePageURL(url, href)
+			ext := path.Ext(target)
+			if !ok || strings.Contains(href, "{{") || ext == "" || ext == ".html" || cfg.isContentFile(target) || outputs[target] {
+				continue
+			}
+			line := bytes.Count(content[:m[2]], []byte("\n")) + 1
+			add(doctorWarning, fmt.Sprintf("add the file to %s/ or next to the page, or fix the link", cfg.staticDir()),
+				"%s:%d: links to %s, which doesn't exist", file, line, href)
+		}
+	}
+	return findings
+}
+
+// diagnoseTheme renders the theme against the fixtures of `slate theme
+// test` and reports the fields it reads that slate's data doesn't have,
+// which usually means it was written for an older slate, or else why the
+// fixtures fail to build
+func diagnoseTheme(cfg *Config) []doctorFinding {
+	theme := cfg.templateDir()
+	// The fixture build records which data each template gets; its output
+	// is noise here
+	level := log.level
+	log.level = levelError + 1
+	site, buildErr := renderFixtureSite(theme, cfg.staticDir())
+	log.level = level
+	os.RemoveAll(site)
+
+	vars, err := themeVars(theme)
+	if err != nil {
+		return []doctorFinding{{
+			Severity: doctorError,
+			Area:     "templates",
+			Problem:  err.Error(),
+			Fix:      "fix the template syntax",
+		}}
+	}
+	var findings []doctorFinding
+	for _, v := range vars {
+		if v.OK {
+			continue
+		}
+		findings = append(findings, doctorFinding{
+			Severity: doctorError,
+			Area:     "templates",
+			Problem:  fmt.Sprintf("%s reads .%s, which %s doesn't have; the theme may be outdated", v.Location, v.Field, v.Type),
+			Fix:      "update the theme, `slate theme vars` lists what each template reads",
+		})
+	}
+	if buildErr != nil && len(findings) == 0 {
+		findings = append(findings, doctorFinding{
+			Severity: doctorError,
+			Area:     "templates",
+			Problem:  fmt.Sprintf("the theme in %s/ fails to build: %v", theme, buildErr),
+			Fix:      "run `slate theme test` for details",
+		})
+	}
+	return findings
+}
+
+// diagnoseImages reports images heavy enough to slow pages down
+func diagnoseImages(cfg *Config) []doctorFinding {
+	var findings []doctorFinding
+	for _, root := range []string{cfg.staticDir(), cfg.contentDir()} {
+		walkFollowingLinks(root, func(file string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return nil
+			}
+			switch strings.ToLower(filepath.Ext(file)) {
+			case ".jpg", ".jpeg", ".png", ".gif", ".webp":
+			default:
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil || info.Size() <= largeImageSize {
+				return nil
+			}
+			problem := fmt.Sprintf("%s is %s", file, formatSize(info.Size()))
+			fix := "compress it, e.g. as WebP or a lower JPEG quality"
+			if f, err := os.Open(file); err == nil {
+				if img, _, err := image.DecodeConfig(f); err == nil {
+					problem += fmt.Sprintf(" (%d×%d)", img.Width, img.Height)
+					if img.Width > largeImageWidth {
+						fix = fmt.Sprintf("resize it to at most %d pixels wide and compress it", largeImageWidth)
+					}
+				}
+				f.Close()
+			}
+			findings = append(findings, doctorFinding{Severity: doctorWarning, Area: "images", Problem: problem, Fix: fix})
+			return nil
+		})
+	}
+	return findings
+}
+
+// diagnoseSymlinks reports symlinks in the project's directories that
+// point nowhere, which builds skip
+func diagnoseSymlinks(cfg *Config) []doctorFinding {
+	var findings []doctorFinding
+	for _, root := range []string{cfg.contentDir(), cfg.staticDir(), cfg.templateDir()} {
+		filepath.WalkDir(root, func(file string, d fs.DirEntry, err error) error {
+			if err != nil || d.Type()&fs.ModeSymlink == 0 {
+				return nil
+			}
+			if _, err := os.Stat(file); err == nil {
+				return nil
+			}
+			target, _ := os.Readlink(file)
+			findings = append(findings, doctorFinding{
+				Severity: doctorWarning,
+				Area:     "files",
+				Problem:  fmt.Sprintf("%s is a broken symlink to %s", file, target),
+				Fix:      "point it at an existing file or remove it",
+			})
+			return nil
+		})
+	}
+	return findings
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"mime"
+	"os"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+// DownloadsConfig sends links to files such as PDFs through a page about
+// the file, rendered with templates/download.html, instead of the raw file
+type DownloadsConfig struct {
+	Extensions []string `yaml:"extensions"` // e.g. [pdf, zip]
+	Sections   []string `yaml:"sections"`   // directories under content/ whose links are rewritten
+}
+
+func (c DownloadsConfig) enabled() bool {
+	return len(c.Extensions) > 0 && len(c.Sections) > 0
+}
+
+// handles reports whether links to a file named name go through a
+// download page
+func (c DownloadsConfig) handles(name string) bool {
+	ext := strings.TrimPrefix(strings.ToLower(path.Ext(name)), ".")
+	return ext != "" && slices.ContainsFunc(c.Extensions, func(e string) bool {
+		return strings.ToLower(strings.TrimPrefix(e, ".")) == ext
+	})
+}
+
+// Download is the data of a download page
+type Download struct {
+	URL      string // the file
+	PageURL  string // the download page
+	Name     string // file name, e.g. report.pdf
+	Ext      string // upper-case extension, e.g. PDF
+	Type     string // MIME type
+	Size     int64
+	SizeText string // e.g. 1.2 MB
+	Modified time.Time
+	Pages    []PageLink // pages linking to the file, sorted by title
+}
+
+// builtDownloads holds the files linked through download pages during the
+// current build, by file URL
+var builtDownloads = map[string]*Download{}
+
+// downloadPageURL returns the URL of the download page for a file URL,
+// e.g. /files/report.pdf → /files/report.pdf.html
+func downloadPageURL(fileURL string) string {
+	return fileURL + ".html"
+}
+
+// downloadTransformer points links to downloadable files in the configured
+// sections at the files' download pages
+type downloadTransformer struct {
+	cfg   *Config
+	files map[string]string // output URL → source file, of the site's assets
+}
+
+func (t *downloadTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	source, _ := pc.Get(sourcePathKey).(string)
+	if !slices.Contains(t.cfg.Downloads.Sections, t.cfg.contentSection(source)) {
+		return
+	}
+	pageURL := t.cfg.pathToURL(source)
+
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		link, ok := n.(*ast.Link)
+		if !ok || !entering {
+			return ast.WalkContinue, nil
+		}
+		target, ok := resolvePageURL(pageURL, string(link.Destination))
+		if !ok || !t.cfg.Downloads.handles(target) || t.files[target] == "" {
+			return ast.WalkContinue, nil
+		}
+		download := builtDownloads[target]
+		if download == nil {
+			download = &Download{URL: target, PageURL: downloadPageURL(target)}
+			builtDownloads[target] = download
+		}
+		download.Pages = append(download.Pages, PageLink{URL: pageURL})
+		link.Destination = []byte(download.PageURL)
+		return ast.WalkContinue, nil
+	})
+}
+
+// downloadAssetIndex maps the output URL of each asset to its source file
+func downloadAssetIndex(cfg *Config) (map[string]string, error) {
+	assets, err := findAssets(cfg)
+	if err != nil {
+		return nil, err
+	}
+	files := map[string]string{}
+	for _, a := range assets {
+		files[a.Output] = a.Source
+	}
+	return files, nil
+}
+
+// renderDownloads renders a download page for every file linked through
+// one, filling in the file's metadata and the titles of the pages linking
+// to it
+func renderDownloads(cfg *Config, pages []Page, out string) error {
+	if len(builtDownloads) == 0 {
+		return nil
+	}
+	files, err := downloadAssetIndex(cfg)
+	if err != nil {
+		return err
+	}
+	tmpl, err := parseTemplate(cfg, "download.html")
+	if err != nil {
+		return fmt.Errorf("parsing download template: %w", err)
+	}
+	titles := map[string]string{}
+	for _, page := range pages {
+		titles[page.URL] = page.Title
+	}
+
+	for _, url := range sortedKeys(builtDownloads) {
+		download := builtDownloads[url]
+		info, err := os.Stat(files[url])
+		if err != nil {
+			return err
+		}
+		download.Name = path.Base(url)
+		download.Ext = strings.ToUpper(strings.TrimPrefix(path.Ext(url), "."))
+		download.Type = mime.TypeByExtension(path.Ext(url))
+		download.Size = info.Size()
+		download.SizeText = formatSize(info.Size())
+		// Checkouts set modification times to when they ran; clamped to the
+		// build time, SOURCE_DATE_EPOCH makes them reproducible too
+		download.Modified = info.ModTime()
+		if download.Modified.After(buildTime) {
+			download.Modified = buildTime
+		}
+
+		// A page linking to the file several times is listed once
+		seen := map[string]bool{}
+		var linking []PageLink
+		for _, link := range download.Pages {
+			if !seen[link.URL] {
+				seen[link.URL] = true
+				linking = append(linking, PageLink{Title: titles[link.URL], URL: link.URL})
+			}
+		}
+		sort.Slice(linking, func(i, j int) bool { return linking[i].Title < linking[j].Title })
+		download.Pages = linking
+
+		if err := renderPage(tmpl, *download, out+download.PageURL); err != nil {
+			return fmt.Errorf("rendering download page for %s: %w", url, err)
+		}
+	}
+	return nil
+}
+
+// formatSize formats a file size for people, e.g. 1.2 MB
+func formatSize(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1f GB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d bytes", n)
+}
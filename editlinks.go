@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// editMetadataFile lists the source of every page for the suggestEdit widget
+const editMetadataFile = "edit.json"
+
+// EditConfig points "suggest an edit" links at the site's repository
+type EditConfig struct {
+	Repo   string `yaml:"repo"`   // web URL, e.g. https://github.com/user/site
+	Branch string `yaml:"branch"` // defaults to the checked out branch
+	Host   string `yaml:"host"`   // github, gitlab, bitbucket or gitea, inferred from repo
+	Dir    string `yaml:"dir"`    // directory of the site in the repository, detected with git
+}
+
+// editMetadata is the content of edit.json
+type editMetadata struct {
+	Repo   string                  `json:"repo"`
+	Branch string                  `json:"branch"`
+	Pages  map[string]editPageInfo `json:"pages"` // page URL → source
+}
+
+type editPageInfo struct {
+	Source string `json:"source"` // path in the repository
+	Edit   string `json:"edit"`   // the host's web editor for it
+}
+
+// writeEditMetadata writes edit.json when edit.repo is set
+// Pages from content modules live in other repositories and are left out.
+func writeEditMetadata(cfg *Config, pages []Page, publicDir string) error {
+	if cfg.Edit.Repo == "" {
+		return nil
+	}
+	repo := strings.TrimSuffix(strings.TrimSuffix(cfg.Edit.Repo, "/"), ".git")
+	u, err := url.Parse(repo)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("edit.repo must be the repository's web URL, e.g. https://github.com/user/site")
+	}
+
+	host := cfg.Edit.Host
+	if host == "" {
+		host = inferEditHost(u.Host)
+	}
+	branch := cfg.Edit.Branch
+	if branch == "" {
+		branch, err = runGit(context.Background(), nil, "rev-parse", "--abbrev-ref", "HEAD")
+		if err != nil || branch == "HEAD" {
+			branch = "main"
+		}
+	}
+	dir := cfg.Edit.Dir
+	if dir == "" {
+		// Empty outside a repository or at its root
+		dir, _ = runGit(context.Background(), nil, "rev-parse", "--show-prefix")
+	}
+
+	meta := editMetadata{Repo: repo, Branch: branch, Pages: map[string]editPageInfo{}}
+	for _, page := range pages {
+		if _, mounted := contentMounts[filepath.ToSlash(page.Path)]; mounted {
+			continue
+		}
+		source := path.Join(filepath.ToSlash(dir), filepath.ToSlash(page.Path))
+		editURL, err := editorURL(host, repo, branch, source)
+		if err != nil {
+			return err
+		}
+		meta.Pages[page.URL] = editPageInfo{Source: source, Edit: editURL}
+	}
+
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	outputPath := filepath.Join(publicDir, editMetadataFile)
+	if err := os.WriteFile(outputPath, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	logGenerated(outputPath)
+	return nil
+}
+
+// inferEditHost guesses the kind of git host from its domain
+func inferEditHost(domain string) string {
+	switch {
+	case strings.Contains(domain, "gitlab"):
+		return "gitlab"
+	case strings.Contains(domain, "bitbucket"):
+		return "bitbucket"
+	case strings.Contains(domain, "gitea"), strings.Contains(domain, "codeberg"):
+		return "gitea"
+	default:
+		return "github"
+	}
+}
+
+// editorURL returns the URL of the host's web editor for a file
+func editorURL(host, repo, branch, file string) (string, error) {
+	segments := strings.Split(file, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	file = strings.Join(segments, "/")
+	switch host {
+	case "github":
+		return repo + "/edit/" + branch + "/" + file, nil
+	case "gitlab":
+		return repo + "/-/edit/" + branch + "/" + file, nil
+	case "bitbucket":
+		return repo + "/src/" + branch + "/" + file + "?mode=edit", nil
+	case "gitea":
+		return repo + "/_edit/" + branch + "/" + file, nil
+	default:
+		return "", fmt.Errorf("unknown edit.host %q", host)
+	}
+}
+
+// suggestEditPartial renders a "Suggest an edit" link to the web editor of
+// the current page's source, looked up in edit.json
+const suggestEditPartial = `
+{{define "suggestEdit"}}{{if site.Edit.Repo}}<a class="suggest-edit" href="{{site.Edit.Repo}}" hidden>Suggest an edit</a>
+<script>
+(function () {
+    var link = document.currentScript.previousElementSibling;
+    var path = location.pathname;
+    if (path.endsWith("/")) path += "index.html";
+    fetch("/` + editMetadataFile + `").then(function (r) { return r.json(); }).then(function (meta) {
+        var page = meta.pages[path] || meta.pages[path + ".html"];
+        if (!page) return;
+        link.href = page.edit;
+        link.hidden = false;
+    }).catch(function () {});
+})();
+</script>{{end}}{{end}}
+`
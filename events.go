@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Build lifecycle event types written by --events-json
+const (
+	eventStart      = "start"
+	eventDiscovered = "discovered"
+	eventRendered   = "rendered"
+	eventWarning    = "warning"
+	eventError      = "error"
+	eventDone       = "done"
+)
+
+// buildEvents is the event stream of the running build, nil unless
+// --events-json was given
+var buildEvents *eventStream
+
+// buildEvent is one line of the event stream
+// Fields that don't apply to an event type are omitted
+type buildEvent struct {
+	Time     string `json:"time"`
+	Type     string `json:"type"`
+	File     string `json:"file,omitempty"`   // source file, relative to the project root
+	Output   string `json:"output,omitempty"` // generated file
+	Msg      string `json:"msg,omitempty"`
+	OK       *bool  `json:"ok,omitempty"` // done only
+	Warnings int    `json:"warnings,omitempty"`
+	Duration int64  `json:"durationMs,omitempty"`
+}
+
+// eventStream writes build events as newline-delimited JSON so editor
+// plugins and GUIs can follow a build without parsing log output
+type eventStream struct {
+	out   io.Writer
+	start time.Time
+}
+
+func newEventStream(out io.Writer) *eventStream {
+	return &eventStream{out: out, start: time.Now()}
+}
+
+// The methods below are no-ops on a nil *eventStream, like *progress
+
+func (s *eventStream) emit(ev buildEvent) {
+	if s == nil {
+		return
+	}
+	ev.Time = time.Now().Format(time.RFC3339Nano)
+	line, _ := json.Marshal(ev)
+	fmt.Fprintln(s.out, string(line))
+}
+
+func (s *eventStream) Start() {
+	s.emit(buildEvent{Type: eventStart})
+}
+
+func (s *eventStream) Discovered(file string) {
+	s.emit(buildEvent{Type: eventDiscovered, File: file})
+}
+
+func (s *eventStream) Rendered(file, output string) {
+	s.emit(buildEvent{Type: eventRendered, File: file, Output: output})
+}
+
+// Log forwards warnings and errors reported through the logger
+func (s *eventStream) Log(level logLevel, msg string) {
+	switch level {
+	case levelWarn:
+		s.emit(buildEvent{Type: eventWarning, Msg: msg})
+	case levelError:
+		s.emit(buildEvent{Type: eventError, Msg: msg})
+	}
+}
+
+// Done ends the stream with the build result
+func (s *eventStream) Done(err error, warnings int) {
+	if s == nil {
+		return
+	}
+	ok := err == nil
+	ev := buildEvent{Type: eventDone, OK: &ok, Warnings: warnings, Duration: time.Since(s.start).Milliseconds()}
+	if err != nil {
+		ev.Msg = err.Error()
+	}
+	s.emit(ev)
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	_ "embed"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// exportServerSource is the program compiled around the site by
+// `slate export --binary`, with exportHandlerSource; %s is replaced with the
+// MIME type map entries
+const exportServerSource = `// Code generated by slate export. DO NOT EDIT.
+
+package main
+
+import (
+	"embed"
+	"flag"
+	"io/fs"
+	"log"
+	"mime"
+	"net/http"
+)
+
+//go:embed all:site
+var site embed.FS
+
+var mimeTypes = map[string]string{
+%s}
+
+func main() {
+	addr := flag.String("addr", "localhost:8080", "address to listen on")
+	flag.Parse()
+
+	for ext, t := range mimeTypes {
+		mime.AddExtensionType(ext, t)
+	}
+	root, err := fs.Sub(site, "site")
+	if err != nil {
+		log.Fatal(err)
+	}
+	http.Handle("/", exportHandler(root))
+
+	log.Printf("Serving the site at http://%%s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, nil))
+}
+`
+
+// exportHandlerSource is exporthandler.go, the exported server's handler
+//
+//go:embed exporthandler.go
+var exportHandlerSource string
+
+func exportCmd(args []string) {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	binary := flags.Bool("binary", false, "compile the site into a self-contained web server executable")
+	output := flags.String("o", "", "executable to write, defaults to the site title")
+	goos := flags.String("os", runtime.GOOS, "operating system to build for, e.g. linux or windows")
+	goarch := flags.String("arch", runtime.GOARCH, "architecture to build for, e.g. amd64 or arm64")
+	flags.Parse(args)
+
+	if !*binary {
+		log.Errorf("Usage: slate export --binary [-o file] [--os linux] [--arch amd64]")
+		os.Exit(2)
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		log.Errorf("loading %s: %v", configFile, err)
+		os.Exit(1)
+	}
+	if *output == "" {
+		*output = slugify(cfg.Title)
+		if *output == "" {
+			*output = "site"
+		}
+		if *goos == "windows" {
+			*output += ".exe"
+		}
+	}
+
+	if err := exportBinary(cfg, *output, *goos, *goarch); err != nil {
+		log.Errorf("export failed: %v", err)
+		os.Exit(1)
+	}
+}
+
+// exportBinary embeds the output directory into a small HTTP server and
+// compiles it with the Go toolchain into output
+func exportBinary(cfg *Config, output, goos, goarch string) error {
+	dir := cfg.outputDir()
+	if _, err := os.Stat(dir); err != nil {
+		return fmt.Errorf("missing %s/ directory. Did you run `slate build`?", dir)
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		return fmt.Errorf("the Go toolchain is needed to compile the server, see https://go.dev/dl/")
+	}
+	output, err := filepath.Abs(output)
+	if err != nil {
+		return err
+	}
+
+	work, err := os.MkdirTemp("", "slate-export")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(work)
+
+	if err := copyDir(dir, filepath.Join(work, "site")); err != nil {
+		return err
+	}
+	var types strings.Builder
+	mimes := mimeTypes(cfg)
+	for _, ext := range sortedKeys(mimes) {
+		fmt.Fprintf(&types, "\t%q: %q,\n", ext, mimes[ext])
+	}
+	files := map[string]string{
+		"main.go":    fmt.Sprintf(exportServerSource, types.String()),
+		"handler.go": "// Code generated by slate export. DO NOT EDIT.\n\n" + exportHandlerSource,
+		"go.mod":     "module site\n\ngo 1.22\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(work, name), []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+
+	log.Infof("Compiling %s/ into %s for %s/%s", dir, filepath.Base(output), goos, goarch)
+	cmd := exec.Command("go", "build", "-trimpath", "-ldflags", "-s -w", "-o", output, ".")
+	cmd.Dir = work
+	cmd.Env = append(os.Environ(), "GOOS="+goos, "GOARCH="+goarch, "CGO_ENABLED=0")
+	cmd.Stdout = log.out
+	cmd.Stderr = log.errOut
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("go build: %w", err)
+	}
+
+	info, err := os.Stat(output)
+	if err != nil {
+		return err
+	}
+	log.Infof("Wrote %s (%.1f MB), run it to serve the site", output, float64(info.Size())/(1<<20))
+	record("exported", output, info.Size())
+	return nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func TestExportHandler(t *testing.T) {
+	site := fstest.MapFS{
+		"index.html":      {Data: []byte("home")},
+		"blog/index.html": {Data: []byte("blog")},
+		"blog/post.html":  {Data: []byte("post")},
+		"styles.css":      {Data: []byte("css")},
+		"404.html":        {Data: []byte("not found")},
+	}
+	noNotFound := fstest.MapFS{"index.html": {Data: []byte("home")}}
+	tests := []struct {
+		name       string
+		root       fstest.MapFS
+		url        string
+		wantStatus int
+		wantBody   string
+	}{
+		{"home", site, "/", http.StatusOK, "home"},
+		{"home without 404 page", noNotFound, "/", http.StatusOK, "home"},
+		{"section", site, "/blog/", http.StatusOK, "blog"},
+		{"page", site, "/blog/post.html", http.StatusOK, "post"},
+		{"pretty URL", site, "/blog/post", http.StatusOK, "post"},
+		{"pretty URL with slash", site, "/blog/post/", http.StatusOK, "post"},
+		{"asset", site, "/styles.css", http.StatusOK, "css"},
+		{"missing", site, "/nope", http.StatusNotFound, "not found"},
+		{"missing without 404 page", noNotFound, "/nope", http.StatusNotFound, "404 page not found\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			exportHandler(tt.root).ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.url, nil))
+			body, _ := io.ReadAll(w.Result().Body)
+			if w.Code != tt.wantStatus || string(body) != tt.wantBody {
+				t.Errorf("GET %s = %d %q, want %d %q", tt.url, w.Code, body, tt.wantStatus, tt.wantBody)
+			}
+		})
+	}
+}
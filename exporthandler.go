@@ -0,0 +1,34 @@
+package main
+
+// This file is also compiled, as is, into the servers `slate export --binary`
+// writes, so it only uses the standard library.
+
+import (
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// exportHandler serves the site in root, pages also without their .html
+// extension, and 404.html, when there is one, for anything else
+func exportHandler(root fs.FS) http.Handler {
+	files := http.FileServer(http.FS(root))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(path.Clean(r.URL.Path), "/")
+		if name == "" {
+			name = "."
+		}
+		if _, err := fs.Stat(root, name); err != nil {
+			if _, err := fs.Stat(root, name+".html"); err == nil {
+				r.URL.Path = "/" + name + ".html"
+			} else if page, err := fs.ReadFile(root, "404.html"); err == nil {
+				w.Header().Set("Content-Type", "text/html; charset=utf-8")
+				w.WriteHeader(http.StatusNotFound)
+				w.Write(page)
+				return
+			}
+		}
+		files.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"html/template"
+	"sort"
+
+	"github.com/yuin/goldmark"
+)
+
+// FallbackPage configures a standalone page served when the site can't be,
+// e.g. a host's custom 500 page or a service worker's offline page
+type FallbackPage struct {
+	Title   string `yaml:"title"`
+	Message string `yaml:"message"` // markdown
+}
+
+// defaultFallbackPages fills in titles and messages for well-known pages
+var defaultFallbackPages = map[string]FallbackPage{
+	"500": {
+		Title:   "Something went wrong",
+		Message: "The server hit an unexpected error. Please try again in a few minutes.",
+	},
+	"maintenance": {
+		Title:   "Down for maintenance",
+		Message: "The site is being updated and will be back shortly.",
+	},
+	"offline": {
+		Title:   "You're offline",
+		Message: "This page isn't available offline. Check your connection and try again.",
+	},
+}
+
+// fallbackPages returns the configured fallback pages as Pages keyed by
+// output file name, with defaults applied, sorted by name
+func fallbackPages(cfg *Config) ([]Page, error) {
+	names := make([]string, 0, len(cfg.FallbackPages))
+	for name := range cfg.FallbackPages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var pages []Page
+	for _, name := range names {
+		fallback := cfg.FallbackPages[name]
+		defaults := defaultFallbackPages[name]
+		if fallback.Title == "" {
+			fallback.Title = defaults.Title
+		}
+		if fallback.Message == "" {
+			fallback.Message = defaults.Message
+		}
+
+		var buf bytes.Buffer
+		if err := goldmark.Convert([]byte(fallback.Message), &buf); err != nil {
+			return nil, err
+		}
+		pages = append(pages, Page{
+			Path:    name,
+			URL:     "/" + name + ".html",
+			Title:   fallback.Title,
+			Content: template.HTML(buf.String()),
+		})
+	}
+	return pages, nil
+}
+
+// offlineBannerPartial shows a banner while the browser reports being offline
+const offlineBannerPartial = `
+{{define "offline-banner"}}<div class="offline-banner" role="status" hidden>{{with site.FallbackPages.offline.Title}}{{.}}{{else}}You're offline{{end}}</div>
+<script>
+(function () {
+    var banner = document.currentScript.previousElementSibling;
+    function update() { banner.hidden = navigator.onLine; }
+    window.addEventListener("online", update);
+    window.addEventListener("offline", update);
+    update();
+})();
+</script>{{end}}
+`
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/xml"
+	"os"
+	"time"
+)
+
+// atomFeed is an Atom 1.0 feed document
+type atomFeed struct {
+	XMLName xml.Name    `xml:"feed"`
+	Xmlns   string      `xml:"xmlns,attr"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomEntry struct {
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Link    atomLink    `xml:"link"`
+	Summary string      `xml:"summary,omitempty"`
+	Content atomContent `xml:"content"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// feedTime formats t as an RFC 3339 timestamp for feeds
+func feedTime(t time.Time) string {
+	return t.Format(time.RFC3339)
+}
+
+// writeAtomFeed writes feed to outputPath
+func writeAtomFeed(feed atomFeed, outputPath string) error {
+	feed.Xmlns = "http://www.w3.org/2005/Atom"
+
+	data, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append([]byte(xml.Header), data...)
+	if err := os.WriteFile(outputPath, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	logGenerated(outputPath)
+	return nil
+}
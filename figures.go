@@ -0,0 +1,212 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	east "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// FiguresConfig numbers and captions figures and tables
+type FiguresConfig struct {
+	Sections    []string `yaml:"sections"`    // directories under content/, e.g. [docs]
+	FigureLabel string   `yaml:"figureLabel"` // default Figure
+	TableLabel  string   `yaml:"tableLabel"`  // default Table
+}
+
+// figureIDPattern matches an explicit id at the end of a caption,
+// e.g. "Request flow {#fig-flow}"
+var figureIDPattern = regexp.MustCompile(`\s*\{#([\w:-]+)\}\s*$`)
+
+// captionParts splits a caption from its explicit id
+func captionParts(caption string) (string, string) {
+	if m := figureIDPattern.FindStringSubmatch(caption); m != nil {
+		return strings.TrimSpace(caption[:len(caption)-len(m[0])]), m[1]
+	}
+	return strings.TrimSpace(caption), ""
+}
+
+// kindFigure wraps an image and its caption
+var kindFigure = ast.NewNodeKind("Figure")
+
+// figureNode is an image with a title, rendered as <figure>
+type figureNode struct {
+	ast.BaseBlock
+	ID      string
+	Label   string // e.g. Figure 3
+	Caption string
+}
+
+func (n *figureNode) Kind() ast.NodeKind { return kindFigure }
+
+func (n *figureNode) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"ID": n.ID, "Label": n.Label}, nil)
+}
+
+// kindTableCaption is the caption of a numbered table
+var kindTableCaption = ast.NewNodeKind("TableCaption")
+
+// tableCaptionNode is inserted as the first child of a table
+type tableCaptionNode struct {
+	ast.BaseBlock
+	Label   string
+	Caption string
+}
+
+func (n *tableCaptionNode) Kind() ast.NodeKind { return kindTableCaption }
+
+func (n *tableCaptionNode) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Label": n.Label}, nil)
+}
+
+// figureTransformer numbers images with a title and tables preceded by a
+// "Table: caption" paragraph, then fills in empty links to their ids,
+// e.g. [](#fig-flow) becomes "Figure 3"
+type figureTransformer struct {
+	cfg *Config
+}
+
+func (t *figureTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	source, _ := pc.Get(sourcePathKey).(string)
+	if !slices.Contains(t.cfg.Figures.Sections, t.cfg.contentSection(source)) {
+		return
+	}
+	figureLabel, tableLabel := t.cfg.Figures.FigureLabel, t.cfg.Figures.TableLabel
+	if figureLabel == "" {
+		figureLabel = "Figure"
+	}
+	if tableLabel == "" {
+		tableLabel = "Table"
+	}
+
+	// Collect first, the tree can't change while it's walked
+	var images []*ast.Paragraph
+	var tables []*east.Table
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.Paragraph:
+			if img, ok := n.FirstChild().(*ast.Image); ok && n.ChildCount() == 1 && len(img.Title) > 0 {
+				images = append(images, n)
+			}
+			return ast.WalkSkipChildren, nil
+		case *east.Table:
+			tables = append(tables, n)
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+
+	labels := map[string]string{} // id → label
+	for i, para := range images {
+		img := para.FirstChild().(*ast.Image)
+		caption, id := captionParts(string(img.Title))
+		if id == "" {
+			id = fmt.Sprintf("figure-%d", i+1)
+		}
+		fig := &figureNode{ID: id, Label: fmt.Sprintf("%s %d", figureLabel, i+1), Caption: caption}
+		img.Title = nil
+		para.Parent().ReplaceChild(para.Parent(), para, fig)
+		fig.AppendChild(fig, img)
+		labels[id] = fig.Label
+	}
+
+	n := 0
+	for _, table := range tables {
+		prev, ok := table.PreviousSibling().(*ast.Paragraph)
+		if !ok {
+			continue
+		}
+		caption, found := strings.CutPrefix(nodeText(prev, reader.Source()), "Table:")
+		if !found {
+			continue
+		}
+		n++
+		caption, id := captionParts(caption)
+		if id == "" {
+			id = fmt.Sprintf("table-%d", n)
+		}
+		label := fmt.Sprintf("%s %d", tableLabel, n)
+		table.SetAttributeString("id", []byte(id))
+		table.InsertBefore(table, table.FirstChild(), &tableCaptionNode{Label: label, Caption: caption})
+		prev.Parent().RemoveChild(prev.Parent(), prev)
+		labels[id] = label
+	}
+
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		link, ok := n.(*ast.Link)
+		if !entering || !ok || link.HasChildren() {
+			return ast.WalkContinue, nil
+		}
+		id, ok := strings.CutPrefix(string(link.Destination), "#")
+		if !ok {
+			return ast.WalkContinue, nil
+		}
+		if label, ok := labels[id]; ok {
+			link.AppendChild(link, ast.NewString([]byte(label)))
+		} else {
+			log.Warnf("%s: reference to unknown figure or table %q", source, id)
+		}
+		return ast.WalkSkipChildren, nil
+	})
+}
+
+// figureRenderer renders figures and table captions
+type figureRenderer struct{}
+
+func (r *figureRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(kindFigure, r.renderFigure)
+	reg.Register(kindTableCaption, r.renderTableCaption)
+}
+
+func (r *figureRenderer) renderFigure(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	n := node.(*figureNode)
+	if entering {
+		fmt.Fprintf(w, "<figure id=\"%s\">\n", html.EscapeString(n.ID))
+		return ast.WalkContinue, nil
+	}
+	fmt.Fprintf(w, "\n<figcaption><span class=\"figure-label\">%s:</span> %s</figcaption>\n</figure>\n",
+		html.EscapeString(n.Label), html.EscapeString(n.Caption))
+	return ast.WalkContinue, nil
+}
+
+func (r *figureRenderer) renderTableCaption(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	n := node.(*tableCaptionNode)
+	if entering {
+		fmt.Fprintf(w, "<caption><span class=\"figure-label\">%s:</span> %s</caption>\n",
+			html.EscapeString(n.Label), html.EscapeString(n.Caption))
+	}
+	return ast.WalkSkipChildren, nil
+}
+
+// figureShortcode inserts a captioned image:
+// {{< figure "diagram.png" "Request flow" "fig-flow" >}}, the id is optional
+func figureShortcode(ctx shortcodeContext, args []string) (string, error) {
+	if len(args) < 2 || len(args) > 3 {
+		return "", fmt.Errorf("want an image, a caption and optionally an id")
+	}
+	title := args[1]
+	if len(args) == 3 {
+		title += " {#" + args[2] + "}"
+	}
+	return fmt.Sprintf("![%s](%s %q)", args[1], args[0], title), nil
+}
+
+// refShortcode links to a figure or table by id, with its number as the
+// text: {{< ref "fig-flow" >}} → Figure 3
+func refShortcode(ctx shortcodeContext, args []string) (string, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("want one figure or table id")
+	}
+	return "[](#" + args[0] + ")", nil
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// FontsConfig subsets self-hosted web fonts to the characters the site
+// uses, with the pyftsubset command from fonttools
+type FontsConfig struct {
+	Subset bool   `yaml:"subset"`
+	Keep   string `yaml:"keep"` // characters always kept, e.g. for text added by scripts
+}
+
+var (
+	fontFacePattern     = regexp.MustCompile(`(?s)@font-face\s*\{[^}]*\}`)
+	fontSrcPattern      = regexp.MustCompile(`(?i)\bsrc\s*:[^;}]*`)
+	cssURLPattern       = regexp.MustCompile(`url\(\s*['"]?([^'")]+)['"]?\s*\)`)
+	unicodeRangePattern = regexp.MustCompile(`(?i)\bunicode-range\s*:`)
+	htmlLangPattern     = regexp.MustCompile(`(?i)<html[^>]*\slang\s*=\s*"([^"]*)"`)
+	hiddenTextPattern   = regexp.MustCompile(`(?is)<(script|style)\b.*?</(script|style)>|<!--.*?-->`)
+	// visibleAttrPattern matches attribute values shown as text
+	visibleAttrPattern = regexp.MustCompile(`(?i)\s(?:alt|title|placeholder|value|aria-label)\s*=\s*"([^"]*)"`)
+)
+
+// fontExts are the font files subsetting applies to
+var fontExts = []string{".woff2", ".woff", ".ttf", ".otf"}
+
+// subsetFonts replaces each @font-face in the stylesheets in publicDir with
+// one rule per language, each pointing at a WOFF2 file holding only the
+// characters that language's pages use and not an earlier one's, limited
+// with unicode-range so browsers fetch only what a page needs.
+// Rules that already have a unicode-range are left alone.
+func subsetFonts(cfg FontsConfig, publicDir string) error {
+	if !cfg.Subset {
+		return nil
+	}
+	if _, err := exec.LookPath("pyftsubset"); err != nil {
+		log.Warnf("fonts: the pyftsubset command isn't installed (pip install fonttools brotli), skipping subsetting")
+		return nil
+	}
+
+	files, err := listOutput(publicDir)
+	if err != nil {
+		return err
+	}
+	byLang, err := charsByLanguage(files)
+	if err != nil {
+		return err
+	}
+	subsets := splitCharsets(byLang, cfg.Keep)
+
+	s := &fontSubsetter{publicDir: publicDir, subsets: subsets, done: map[string]string{}}
+	rewrites := map[string]string{}
+	for _, name := range sortedKeys(files) {
+		if path.Ext(name) != ".css" {
+			continue
+		}
+		url, err := s.rewriteStylesheet("/"+name, files[name])
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		if url != "" {
+			rewrites["/"+name] = url
+		}
+	}
+	if s.count > 0 {
+		log.Infof("Subset %d font(s) into %d file(s)", len(s.fonts), s.count)
+	}
+	if len(rewrites) == 0 {
+		return nil
+	}
+	return rewriteAssetLinks(publicDir, rewrites)
+}
+
+// fontSubset is a set of characters with the language it was made for
+type fontSubset struct {
+	Lang  string
+	Chars []rune // sorted
+}
+
+type fontSubsetter struct {
+	publicDir string
+	subsets   []fontSubset
+	done      map[string]string // font file and language → subset URL
+	fonts     map[string]bool
+	count     int
+}
+
+// rewriteStylesheet subsets the fonts of a stylesheet's @font-face rules
+// and rewrites them. A fingerprinted stylesheet gets a new fingerprint, whose
+// URL is returned.
+func (s *fontSubsetter) rewriteStylesheet(url, file string) (string, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	css := string(data)
+	var firstErr error
+	out := fontFacePattern.ReplaceAllStringFunc(css, func(rule string) string {
+		if firstErr != nil || unicodeRangePattern.MatchString(rule) {
+			return rule
+		}
+		src := fontSrcPattern.FindString(rule)
+		font := ""
+		for _, m := range cssURLPattern.FindAllStringSubmatch(src, -1) {
+			target, ok := resolvePageURL(url, m[1])
+			if ok && slices.Contains(fontExts, strings.ToLower(path.Ext(target))) {
+				font = target
+				break
+			}
+		}
+		if font == "" {
+			return rule
+		}
+		var rules []string
+		for _, subset := range s.subsets {
+			subsetURL, err := s.subset(font, subset)
+			if err != nil {
+				firstErr = fmt.Errorf("subsetting %s: %w", font, err)
+				return rule
+			}
+			r := strings.Replace(rule, src, `src:url("`+subsetURL+`") format("woff2")`, 1)
+			r = strings.TrimRight(strings.TrimSuffix(r, "}"), "; \t\n")
+			rules = append(rules, r+";unicode-range:"+unicodeRange(subset.Chars)+"}")
+		}
+		return strings.Join(rules, "\n")
+	})
+	if firstErr != nil {
+		return "", firstErr
+	}
+	if out == css {
+		return "", nil
+	}
+
+	// A fingerprint must change with the content, or caches keep the old rules
+	if newURL, ok := builtAssets.refingerprint(url, []byte(out)); ok {
+		if err := os.Remove(file); err != nil {
+			return "", err
+		}
+		return newURL, nil
+	}
+	return "", os.WriteFile(file, []byte(out), 0644)
+}
+
+// subset writes the subset of the font at url for a language and returns
+// its URL, e.g. /fonts/inter.en.1f5ba8b5.woff2
+func (s *fontSubsetter) subset(url string, subset fontSubset) (string, error) {
+	key := url + " " + subset.Lang
+	if done, ok := s.done[key]; ok {
+		return done, nil
+	}
+	source := filepath.Join(s.publicDir, filepath.FromSlash(url))
+	data, err := os.ReadFile(source)
+	if err != nil {
+		return "", err
+	}
+	unicodes := unicodeRange(subset.Chars)
+	sum := sha256.Sum256(append(data, unicodes...))
+	ext := path.Ext(url)
+	subsetURL := strings.TrimSuffix(url, ext) + "." + subset.Lang + "." + hex.EncodeToString(sum[:4]) + ".woff2"
+	output := filepath.Join(s.publicDir, filepath.FromSlash(subsetURL))
+
+	// The name changes with the font and the characters, so a cached file
+	// from an earlier build is the same subset
+	created, err := cachedDerived("fonts", path.Base(subsetURL), output, func(file string) error {
+		cmd := exec.Command("pyftsubset", source, "--unicodes="+unicodes, "--flavor=woff2",
+			"--layout-features=*", "--output-file="+file)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(out)))
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if created {
+		logGenerated(output)
+	}
+	s.done[key] = subsetURL
+	if s.fonts == nil {
+		s.fonts = map[string]bool{}
+	}
+	s.fonts[url] = true
+	s.count++
+	return subsetURL, nil
+}
+
+// charsByLanguage collects the characters shown by the HTML pages, keyed by
+// the lang of their <html> element, "und" without one
+func charsByLanguage(files map[string]string) (map[string]map[rune]bool, error) {
+	byLang := map[string]map[rune]bool{}
+	for _, name := range sortedKeys(files) {
+		if path.Ext(name) != ".html" {
+			continue
+		}
+		data, err := os.ReadFile(files[name])
+		if err != nil {
+			return nil, err
+		}
+		page := string(data)
+		lang := "und"
+		if m := htmlLangPattern.FindStringSubmatch(page); m != nil && m[1] != "" {
+			lang = strings.ToLower(m[1])
+		}
+		if byLang[lang] == nil {
+			byLang[lang] = map[rune]bool{}
+		}
+		page = hiddenTextPattern.ReplaceAllString(page, "")
+		var text strings.Builder
+		for _, m := range visibleAttrPattern.FindAllStringSubmatch(page, -1) {
+			text.WriteString(m[1])
+		}
+		text.WriteString(tagPattern.ReplaceAllString(page, ""))
+		for _, r := range html.UnescapeString(text.String()) {
+			if r > ' ' {
+				byLang[lang][r] = true
+			}
+		}
+	}
+	return byLang, nil
+}
+
+// splitCharsets gives each language the characters it uses that no
+// language before it does, largest first, so pages share the common
+// characters and fetch only their own on top. Printable ASCII and keep go
+// into the first subset.
+func splitCharsets(byLang map[string]map[rune]bool, keep string) []fontSubset {
+	langs := sortedKeys(byLang)
+	slices.SortStableFunc(langs, func(a, b string) int {
+		return len(byLang[b]) - len(byLang[a])
+	})
+
+	assigned := map[rune]bool{}
+	var subsets []fontSubset
+	for i, lang := range langs {
+		chars := byLang[lang]
+		if i == 0 {
+			for r := rune(' '); r <= '~'; r++ {
+				chars[r] = true
+			}
+			for _, r := range keep {
+				chars[r] = true
+			}
+		}
+		var own []rune
+		for r := range chars {
+			if !assigned[r] {
+				own = append(own, r)
+				assigned[r] = true
+			}
+		}
+		if len(own) == 0 {
+			continue
+		}
+		slices.Sort(own)
+		subsets = append(subsets, fontSubset{Lang: lang, Chars: own})
+	}
+	return subsets
+}
+
+// unicodeRange formats sorted characters as a CSS unicode-range, which
+// pyftsubset's --unicodes also reads, e.g. U+20-7E,U+E9
+func unicodeRange(chars []rune) string {
+	var ranges []string
+	for i := 0; i < len(chars); {
+		j := i
+		for j+1 < len(chars) && chars[j+1] == chars[j]+1 {
+			j++
+		}
+		if i == j {
+			ranges = append(ranges, fmt.Sprintf("U+%X", chars[i]))
+		} else {
+			ranges = append(ranges, fmt.Sprintf("U+%X-%X", chars[i], chars[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(ranges, ",")
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// defaultFormats are the content formats other than markdown slate knows,
+// by extension, each with the command converting a document on stdin to an
+// HTML fragment on stdout
+var defaultFormats = map[string][]string{
+	".adoc":     {"asciidoctor", "--no-header-footer", "--out-file", "-", "-"},
+	".asciidoc": {"asciidoctor", "--no-header-footer", "--out-file", "-", "-"},
+	".rst":      {"pandoc", "--from", "rst", "--to", "html5"},
+}
+
+// contentFormat returns the command converting a content file to HTML, or
+// false for markdown and notebooks, which slate renders itself. HTML pages
+// have a nil command, their body is used as is.
+func (c *Config) contentFormat(file string) ([]string, bool) {
+	ext := strings.ToLower(filepath.Ext(file))
+	if ext == ".md" || ext == ".ipynb" {
+		return nil, false
+	}
+	if command, ok := c.Formats[ext]; ok {
+		return command, len(command) > 0
+	}
+	if ext == ".html" {
+		return nil, true
+	}
+	command, ok := defaultFormats[ext]
+	return command, ok
+}
+
+// contentExts returns the extensions of content files, markdown first
+func (c *Config) contentExts() []string {
+	exts := []string{".md", ".ipynb"}
+	for _, ext := range append([]string{".html"}, sortedKeys(defaultFormats)...) {
+		if _, ok := c.contentFormat(ext); ok {
+			exts = append(exts, ext)
+		}
+	}
+	for _, ext := range sortedKeys(c.Formats) {
+		ext = strings.ToLower(ext)
+		if _, ok := c.contentFormat(ext); ok && !slices.Contains(exts, ext) {
+			exts = append(exts, ext)
+		}
+	}
+	return exts
+}
+
+// isContentFile reports whether file is a page source rather than an asset
+// Complete HTML documents, e.g. a demo with its own <head>, stay assets.
+func (c *Config) isContentFile(file string) bool {
+	ext := strings.ToLower(filepath.Ext(file))
+	if !slices.Contains(c.contentExts(), ext) {
+		return false
+	}
+	return ext != ".html" || !isHTMLDocument(file)
+}
+
+// htmlDocumentPattern matches the start of a complete HTML document
+var htmlDocumentPattern = regexp.MustCompile(`(?i)^\s*(<!--.*?-->\s*)*<(!doctype\s+html|html[\s>])`)
+
+// isHTMLDocument reports whether file is a complete HTML document rather
+// than a page body, optionally after frontmatter
+func isHTMLDocument(file string) bool {
+	f, err := os.Open(file)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	head := make([]byte, 1024)
+	n, _ := io.ReadFull(f, head)
+	return htmlDocumentPattern.Match(head[:n])
+}
+
+// convertContent runs command on a document, without its frontmatter, and
+// returns the HTML it writes. A nil command returns the document, which is
+// HTML already.
+func convertContent(command []string, document []byte) ([]byte, error) {
+	if command == nil {
+		return document, nil
+	}
+	if _, err := exec.LookPath(command[0]); err != nil {
+		return nil, fmt.Errorf("converting needs %s, which isn't installed", command[0])
+	}
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stdin = bytes.NewReader(document)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v %s", command[0], err, strings.TrimSpace(stderr.String()))
+	}
+	if msg := strings.TrimSpace(stderr.String()); msg != "" {
+		log.Debugf("%s: %s", command[0], msg)
+	}
+	return out, nil
+}
+
+// findSourceFiles finds the files under root match reports, leaving out the
+// files and directories ignored reports, given their path relative to root
+func findSourceFiles(root string, ignored func(rel string, dir bool) bool, match func(file string) bool) ([]string, error) {
+	var files []string
+
+	// Traverse the directory tree rooted at "root", following symlinked
+	// directories
+	err := walkFollowingLinks(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			log.Warnf("could not access %s: %v", path, err)
+			return nil
+		}
+
+		if rel, err := filepath.Rel(root, path); err == nil && rel != "." && ignored(rel, d.IsDir()) {
+			log.Debugf("Ignoring %s", path)
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if d.IsDir() {
+			return nil
+		}
+
+		if match(path) {
+			files = append(files, path)
+		}
+
+		return nil
+	})
+
+	return files, err
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// frontmatterIssue is a problem found in a file's frontmatter
+type frontmatterIssue struct {
+	Line int // line in the content file, starting at 1
+	Msg  string
+}
+
+// yamlLinePattern extracts the line number yaml.v3 puts in its error messages
+var yamlLinePattern = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+// knownFrontmatterKeys lists the yaml keys of the Frontmatter struct
+var knownFrontmatterKeys = func() []string {
+	var keys []string
+	t := reflect.TypeOf(Frontmatter{})
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		if name != "" && name != "-" {
+			keys = append(keys, name)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}()
+
+// lintFrontmatter validates content's frontmatter against the Frontmatter
+// schema and returns syntax errors, type mismatches and unknown keys
+func lintFrontmatter(content []byte) []frontmatterIssue {
+	if !bytes.HasPrefix(content, []byte("---")) {
+		return nil
+	}
+
+	rest := content[3:]
+	endIndex := bytes.Index(rest, []byte("\n---"))
+	if endIndex == -1 {
+		return []frontmatterIssue{{Line: 1, Msg: "frontmatter is missing its closing ---"}}
+	}
+	yamlContent := bytes.TrimPrefix(rest[:endIndex], []byte("\n"))
+
+	// The YAML starts on the line after the opening ---
+	const offset = 1
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(yamlContent, &doc); err != nil {
+		return yamlIssues(err, offset)
+	}
+
+	var issues []frontmatterIssue
+	var fm Frontmatter
+	if err := doc.Decode(&fm); err != nil {
+		issues = append(issues, yamlIssues(err, offset)...)
+	}
+
+	if len(doc.Content) == 0 {
+		return issues
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return append(issues, frontmatterIssue{Line: root.Line + offset, Msg: "frontmatter must be a mapping of keys to values"})
+	}
+
+	for i := 0; i < len(root.Content); i += 2 {
+		key := root.Content[i]
+		if isKnownFrontmatterKey(key.Value) {
+			continue
+		}
+		msg := fmt.Sprintf("unknown frontmatter key %q", key.Value)
+		if suggestion := closestKey(key.Value, knownFrontmatterKeys); suggestion != "" {
+			msg += fmt.Sprintf(" (did you mean %q?)", suggestion)
+		}
+		issues = append(issues, frontmatterIssue{Line: key.Line + offset, Msg: msg})
+	}
+	return issues
+}
+
+func isKnownFrontmatterKey(key string) bool {
+	i := sort.SearchStrings(knownFrontmatterKeys, key)
+	return i < len(knownFrontmatterKeys) && knownFrontmatterKeys[i] == key
+}
+
+// yamlIssues converts a yaml.v3 error into issues with file line numbers
+func yamlIssues(err error, offset int) []frontmatterIssue {
+	var messages []string
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		messages = typeErr.Errors
+	} else {
+		messages = []string{err.Error()}
+	}
+
+	var issues []frontmatterIssue
+	for _, msg := range messages {
+		issue := frontmatterIssue{Line: 1 + offset, Msg: strings.TrimPrefix(msg, "yaml: ")}
+		if m := yamlLinePattern.FindStringSubmatch(msg); m != nil {
+			line, _ := strconv.Atoi(m[1])
+			issue = frontmatterIssue{Line: line + offset, Msg: m[2]}
+		}
+		issues = append(issues, issue)
+	}
+	return issues
+}
+
+// closestKey returns the candidate within two edits of key, if any
+func closestKey(key string, candidates []string) string {
+	best, bestDistance := "", 3
+	for _, candidate := range candidates {
+		if d := editDistance(strings.ToLower(key), strings.ToLower(candidate)); d < bestDistance {
+			best, bestDistance = candidate, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,384 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ghPagesDeployer publishes the output for GitHub Pages, either as the
+// only content of a branch or as a folder committed to the current branch
+type ghPagesDeployer struct {
+	name   string
+	remote string
+	branch string // branch holding just the site, e.g. gh-pages
+	folder string // or folder on the current branch, e.g. docs
+	cname  string
+}
+
+func newGHPagesDeployer(t DeployTarget) (deployer, error) {
+	d := &ghPagesDeployer{name: t.Name, remote: t.Remote, branch: t.Branch, folder: t.Folder, cname: t.CNAME}
+	if d.remote == "" {
+		d.remote = "origin"
+	}
+	if d.branch != "" && d.folder != "" {
+		return nil, fmt.Errorf("set either branch or folder, not both")
+	}
+	if d.branch == "" && d.folder == "" {
+		d.branch = "gh-pages"
+	}
+	if d.folder != "" {
+		// The folder is replaced on every deploy, so it has to be a
+		// subdirectory of the project of its own
+		folder := filepath.Clean(d.folder)
+		first, _, _ := strings.Cut(filepath.ToSlash(folder), "/")
+		if !filepath.IsLocal(folder) || folder == "." || first == ".git" || first == ".slate" {
+			return nil, fmt.Errorf("folder %s must be a subdirectory of the project", d.folder)
+		}
+		d.folder = folder
+	}
+	return d, nil
+}
+
+func (d *ghPagesDeployer) deploy(ctx context.Context, dir string, opts deployOptions) (deployStats, error) {
+	message := opts.Message
+	if message == "" {
+		message = "Deploy site"
+		if head := gitHead(); head != "" {
+			message += " from " + head
+		}
+	}
+	if d.folder != "" {
+		return d.deployFolder(ctx, dir, message, opts)
+	}
+	return d.deployBranch(ctx, dir, message, opts)
+}
+
+// deployBranch commits dir as the whole tree of the branch on top of its
+// current remote state and pushes it, leaving the working tree alone
+func (d *ghPagesDeployer) deployBranch(ctx context.Context, dir, message string, opts deployOptions) (deployStats, error) {
+	var stats deployStats
+
+	// A first deploy has no branch to build on
+	parent := ""
+	if _, err := runGit(ctx, nil, "fetch", "--quiet", d.remote, d.branch); err == nil {
+		parent, _ = runGit(ctx, nil, "rev-parse", "FETCH_HEAD")
+	} else {
+		log.Infof("No %s branch on %s yet, creating it", d.branch, d.remote)
+	}
+
+	tree, err := d.siteTree(ctx, dir)
+	if err != nil {
+		return stats, err
+	}
+	if stats, err = diffTrees(ctx, parent, tree); err != nil || stats.Uploaded+stats.Deleted == 0 {
+		if err == nil {
+			log.Infof("%s is up to date", d.branch)
+		}
+		return stats, err
+	}
+	if opts.DryRun {
+		return stats, nil
+	}
+	if opts.Trash != nil && parent != "" {
+		deleted, err := runGit(ctx, nil, "diff-tree", "-r", "--name-only", "--diff-filter=D", parent, tree)
+		if err != nil {
+			return stats, err
+		}
+		if err := d.keepDeletions(ctx, parent, "", deleted, opts.Trash); err != nil {
+			return stats, fmt.Errorf("keeping deleted files: %w", err)
+		}
+	}
+
+	args := []string{"commit-tree", tree, "-m", message}
+	if parent != "" {
+		args = append(args, "-p", parent)
+	}
+	commit, err := runGit(ctx, nil, args...)
+	if err != nil {
+		return stats, err
+	}
+	log.Infof("Pushing %s to %s/%s", commit[:min(len(commit), 7)], d.remote, d.branch)
+	_, err = runGit(ctx, nil, "push", "--quiet", d.remote, commit+":refs/heads/"+d.branch)
+	return stats, err
+}
+
+// deployFolder replaces the folder with dir, commits it on the current
+// branch and pushes the branch
+func (d *ghPagesDeployer) deployFolder(ctx context.Context, dir, message string, opts deployOptions) (deployStats, error) {
+	if err := d.checkFolder(opts); err != nil {
+		return deployStats{}, err
+	}
+
+	// A dry run compares against the committed folder without touching it
+	if opts.DryRun {
+		tree, err := d.siteTree(ctx, dir)
+		if err != nil {
+			return deployStats{}, err
+		}
+		committed, _ := runGit(ctx, nil, "rev-parse", "--verify", "--quiet", "HEAD:"+filepath.ToSlash(d.folder))
+		return diffTrees(ctx, committed, tree)
+	}
+
+	files, err := listOutput(dir)
+	if err != nil {
+		return deployStats{}, err
+	}
+	if err := d.clearFolder(ctx, opts.Trash); err != nil {
+		return deployStats{}, err
+	}
+	for name, source := range files {
+		if err := copyFile(source, filepath.Join(d.folder, filepath.FromSlash(name))); err != nil {
+			return deployStats{}, err
+		}
+	}
+	if err := d.writeExtras(d.folder); err != nil {
+		return deployStats{}, err
+	}
+
+	if _, err := runGit(ctx, nil, "add", "--all", "--force", "--", d.folder); err != nil {
+		return deployStats{}, err
+	}
+	changes, err := runGit(ctx, nil, "diff", "--cached", "--name-status", "--", d.folder)
+	if err != nil {
+		return deployStats{}, err
+	}
+	stats := countChanges(changes)
+	if stats.Uploaded+stats.Deleted == 0 {
+		log.Infof("%s/ is up to date", d.folder)
+		return stats, nil
+	}
+	if opts.Trash != nil {
+		deleted, err := runGit(ctx, nil, "diff", "--cached", "--name-only", "--diff-filter=D", "--", d.folder)
+		if err != nil {
+			return stats, err
+		}
+		if err := d.keepDeletions(ctx, "HEAD", filepath.ToSlash(d.folder)+"/", deleted, opts.Trash); err != nil {
+			return stats, fmt.Errorf("keeping deleted files: %w", err)
+		}
+	}
+
+	if _, err := runGit(ctx, nil, "commit", "--quiet", "-m", message, "--", d.folder); err != nil {
+		return stats, err
+	}
+	log.Infof("Pushing the current branch to %s", d.remote)
+	_, err = runGit(ctx, nil, "push", "--quiet", d.remote, "HEAD")
+	return stats, err
+}
+
+// checkFolder refuses a folder that is, holds or is inside the output or a
+// source directory, which replacing it would delete
+func (d *ghPagesDeployer) checkFolder(opts deployOptions) error {
+	folder, err := filepath.Abs(d.folder)
+	if err != nil {
+		return err
+	}
+	for _, dir := range append([]string{opts.Output}, opts.Sources...) {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return err
+		}
+		if within(folder, abs) || within(abs, folder) {
+			return fmt.Errorf("folder %s overlaps %s/, which deploying would replace", d.folder, dir)
+		}
+	}
+	return nil
+}
+
+// clearFolder removes the folder's files before the site is copied in.
+// Files git can't bring back, untracked, ignored or modified ones, go to the
+// trash, the committed ones are deleted.
+func (d *ghPagesDeployer) clearFolder(ctx context.Context, trash *trashBatch) error {
+	kept, err := runGit(ctx, nil, "ls-files", "--others", "--modified", "-z", "--", d.folder)
+	if err != nil {
+		return err
+	}
+	for _, file := range strings.Split(kept, "\x00") {
+		if file == "" {
+			continue
+		}
+		if err := trash.remove(filepath.FromSlash(file)); err != nil {
+			return err
+		}
+	}
+	return os.RemoveAll(d.folder)
+}
+
+// keepDeletions stores the files listed in deleted, one per line, as they
+// were in commit into the trash, named without prefix
+func (d *ghPagesDeployer) keepDeletions(ctx context.Context, commit, prefix, deleted string, trash *trashBatch) error {
+	for _, file := range strings.Split(deleted, "\n") {
+		if file == "" {
+			continue
+		}
+		data, err := exec.CommandContext(ctx, "git", "cat-file", "blob", commit+":"+file).Output()
+		if err != nil {
+			return fmt.Errorf("git cat-file %s: %w", file, err)
+		}
+		if err := trash.keepRemote(d.name, strings.TrimPrefix(file, prefix), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restore commits a file kept in the trash back as name and pushes it
+func (d *ghPagesDeployer) restore(ctx context.Context, name, file string, opts deployOptions) error {
+	message := "Restore " + name
+	if d.folder != "" {
+		dest := filepath.Join(d.folder, filepath.FromSlash(name))
+		if err := copyFile(file, dest); err != nil {
+			return err
+		}
+		if _, err := runGit(ctx, nil, "add", "--force", "--", dest); err != nil {
+			return err
+		}
+		if _, err := runGit(ctx, nil, "commit", "--quiet", "-m", message, "--", dest); err != nil {
+			return err
+		}
+		_, err := runGit(ctx, nil, "push", "--quiet", d.remote, "HEAD")
+		return err
+	}
+
+	// The branch isn't checked out, so the file is added to its tree in a
+	// throwaway index
+	if _, err := runGit(ctx, nil, "fetch", "--quiet", d.remote, d.branch); err != nil {
+		return err
+	}
+	parent, err := runGit(ctx, nil, "rev-parse", "FETCH_HEAD")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.MkdirTemp("", "slate-ghpages")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	env := []string{"GIT_INDEX_FILE=" + filepath.Join(tmp, "index")}
+	if _, err := runGit(ctx, env, "read-tree", parent); err != nil {
+		return err
+	}
+	blob, err := runGit(ctx, nil, "hash-object", "-w", "--", file)
+	if err != nil {
+		return err
+	}
+	if _, err := runGit(ctx, env, "update-index", "--add", "--cacheinfo", "100644,"+blob+","+name); err != nil {
+		return err
+	}
+	tree, err := runGit(ctx, env, "write-tree")
+	if err != nil {
+		return err
+	}
+	commit, err := runGit(ctx, nil, "commit-tree", tree, "-p", parent, "-m", message)
+	if err != nil {
+		return err
+	}
+	_, err = runGit(ctx, nil, "push", "--quiet", d.remote, commit+":refs/heads/"+d.branch)
+	return err
+}
+
+// siteTree writes dir, with the GitHub Pages extras, as a git tree object
+// and returns its hash. A throwaway index keeps the repository's own index
+// untouched.
+func (d *ghPagesDeployer) siteTree(ctx context.Context, dir string) (string, error) {
+	if err := d.writeExtras(dir); err != nil {
+		return "", err
+	}
+	gitDir, err := runGit(ctx, nil, "rev-parse", "--absolute-git-dir")
+	if err != nil {
+		return "", err
+	}
+	workTree, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	tmp, err := os.MkdirTemp("", "slate-ghpages")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmp)
+	env := []string{
+		"GIT_DIR=" + gitDir,
+		"GIT_WORK_TREE=" + workTree,
+		"GIT_INDEX_FILE=" + filepath.Join(tmp, "index"),
+	}
+	if _, err := runGit(ctx, env, "add", "--all", "--force"); err != nil {
+		return "", err
+	}
+	return runGit(ctx, env, "write-tree")
+}
+
+// diffTrees counts the files changed from tree old to tree new
+// An empty old counts every file as new
+func diffTrees(ctx context.Context, old, new string) (deployStats, error) {
+	if old == "" {
+		files, err := runGit(ctx, nil, "ls-tree", "-r", "--name-only", new)
+		if err != nil {
+			return deployStats{}, err
+		}
+		var stats deployStats
+		for _, name := range strings.Split(files, "\n") {
+			if name == "" {
+				continue
+			}
+			log.Infof("Uploading: %s", name)
+			stats.Uploaded++
+		}
+		return stats, nil
+	}
+	changes, err := runGit(ctx, nil, "diff-tree", "-r", "--name-status", old, new)
+	if err != nil {
+		return deployStats{}, err
+	}
+	return countChanges(changes), nil
+}
+
+// writeExtras adds the files GitHub Pages looks for: .nojekyll so files
+// starting with an underscore are served, and CNAME for a custom domain
+func (d *ghPagesDeployer) writeExtras(dir string) error {
+	if err := os.WriteFile(filepath.Join(dir, ".nojekyll"), nil, 0644); err != nil {
+		return err
+	}
+	if d.cname == "" {
+		return nil
+	}
+	return os.WriteFile(filepath.Join(dir, "CNAME"), []byte(d.cname+"\n"), 0644)
+}
+
+// countChanges counts the files in git's --name-status output
+func countChanges(nameStatus string) deployStats {
+	var stats deployStats
+	for _, line := range strings.Split(nameStatus, "\n") {
+		status, name, ok := strings.Cut(line, "\t")
+		if !ok {
+			continue
+		}
+		if strings.HasPrefix(status, "D") {
+			log.Infof("Deleting: %s", name)
+			stats.Deleted++
+		} else {
+			log.Infof("Uploading: %s", name)
+			stats.Uploaded++
+		}
+	}
+	return stats
+}
+
+// runGit runs git with extra environment variables and returns its
+// trimmed output, or an error carrying what git printed
+func runGit(ctx context.Context, env []string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Env = append(os.Environ(), env...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %s", args[0], strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
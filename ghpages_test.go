@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestGHPagesFolder(t *testing.T) {
+	tests := []struct {
+		folder  string
+		wantErr bool
+	}{
+		{"docs", false},
+		{"site/docs/", false},
+		{".", true},
+		{"./", true},
+		{"..", true},
+		{"../elsewhere", true},
+		{"/var/www", true},
+		{".git", true},
+		{".slate/trash", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.folder, func(t *testing.T) {
+			_, err := newGHPagesDeployer(DeployTarget{Folder: tt.folder})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("newGHPagesDeployer(folder %q) error = %v, want error %v", tt.folder, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGHPagesCheckFolder(t *testing.T) {
+	opts := deployOptions{Output: "public", Sources: []string{"content", "templates", "static", "data"}}
+	tests := []struct {
+		folder  string
+		wantErr bool
+	}{
+		{"docs", false},
+		{"public-docs", false},
+		{"public", true},
+		{"public/site", true},
+		{"content", true},
+		{"content/blog", true},
+		{"docs/../templates", true},
+		{"static", true},
+		{"data", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.folder, func(t *testing.T) {
+			d, err := newGHPagesDeployer(DeployTarget{Folder: tt.folder})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := d.(*ghPagesDeployer).checkFolder(opts); (err != nil) != tt.wantErr {
+				t.Errorf("checkFolder(%q) error = %v, want error %v", tt.folder, err, tt.wantErr)
+			}
+		})
+	}
+	// A folder holding a source directory would delete it too
+	d := &ghPagesDeployer{folder: "site"}
+	if err := d.checkFolder(deployOptions{Output: "public", Sources: []string{"site/content"}}); err == nil {
+		t.Error("checkFolder accepted a folder holding the content directory")
+	}
+}
+
+// Files in the folder that git can't restore go to the trash
+func TestGHPagesClearFolder(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	t.Chdir(t.TempDir())
+	git := func(args ...string) {
+		t.Helper()
+		if _, err := runGit(context.Background(), []string{"GIT_AUTHOR_NAME=t", "GIT_AUTHOR_EMAIL=t@example.com",
+			"GIT_COMMITTER_NAME=t", "GIT_COMMITTER_EMAIL=t@example.com"}, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	git("init", "--quiet")
+	write("docs/index.html", "committed")
+	write("docs/edited.html", "committed")
+	git("add", ".")
+	git("commit", "--quiet", "-m", "site")
+	write("docs/edited.html", "edited")
+	write("docs/notes.txt", "untracked")
+
+	trash := newTrashBatch(&Config{}, "deploy")
+	d := &ghPagesDeployer{folder: "docs"}
+	if err := d.clearFolder(context.Background(), trash); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat("docs"); !os.IsNotExist(err) {
+		t.Errorf("docs still exists (err %v)", err)
+	}
+
+	kept := map[string]bool{}
+	for _, entry := range trash.manifest.Entries {
+		kept[filepath.ToSlash(entry.Original)] = true
+	}
+	tests := []struct {
+		file string
+		want bool
+	}{
+		{"docs/notes.txt", true},
+		{"docs/edited.html", true},
+		{"docs/index.html", false},
+	}
+	for _, tt := range tests {
+		if kept[tt.file] != tt.want {
+			t.Errorf("%s in the trash: %v, want %v", tt.file, kept[tt.file], tt.want)
+		}
+		if tt.want {
+			if _, err := os.Stat(filepath.Join(trash.dir, "files", tt.file)); err != nil {
+				t.Errorf("%s not stored in the batch: %v", tt.file, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// gitCommitMarker prefixes commit lines in the git log output so they can
+// be told apart from file names
+const gitCommitMarker = "@@slate "
+
+// gitFileInfo is the last commit touching a file
+type gitFileInfo struct {
+	Date   time.Time
+	Author string
+}
+
+// gitLastCommits returns the last commit touching each file under dir,
+// keyed by path relative to the working directory
+// e.g., "content/blog/hello.md" → {2025-01-17 10:00:00, Jane Doe}
+func gitLastCommits(dir string) (map[string]gitFileInfo, error) {
+	cmd := exec.Command("git", "-c", "core.quotepath=off", "log",
+		"--format="+gitCommitMarker+"%cI%x09%an", "--name-only", "--relative", "--", dir)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	// git log lists newest commits first, so the first commit seen for a
+	// file is its last one
+	commits := map[string]gitFileInfo{}
+	var current gitFileInfo
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if commit, ok := strings.CutPrefix(line, gitCommitMarker); ok {
+			date, author, _ := strings.Cut(commit, "\t")
+			current.Date, _ = time.Parse(time.RFC3339, date)
+			current.Author = author
+			continue
+		}
+		if line == "" {
+			continue
+		}
+		if _, seen := commits[line]; !seen {
+			commits[line] = current
+		}
+	}
+	return commits, scanner.Err()
+}
+
+// daysSince returns the number of whole days between t and the build, 0
+// for the zero time of a page without git history
+func daysSince(t time.Time) int {
+	if t.IsZero() {
+		return 0
+	}
+	return int(buildTime.Sub(t).Hours() / 24)
+}
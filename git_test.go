@@ -0,0 +1,30 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDaysSince(t *testing.T) {
+	saved := buildTime
+	buildTime = time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	t.Cleanup(func() { buildTime = saved })
+
+	tests := []struct {
+		name string
+		t    time.Time
+		want int
+	}{
+		{"no history", time.Time{}, 0},
+		{"same day", time.Date(2025, 3, 10, 1, 0, 0, 0, time.UTC), 0},
+		{"whole days", time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC), 9},
+		{"partial day rounds down", time.Date(2025, 3, 8, 13, 0, 0, 0, time.UTC), 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := daysSince(tt.t); got != tt.want {
+				t.Errorf("daysSince(%v) = %d, want %d", tt.t, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"html/template"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// GraphConfig exports the link graph for wiki-style sites
+type GraphConfig struct {
+	Enabled bool `yaml:"enabled"` // write public/graph.json
+	Page    bool `yaml:"page"`    // also write an interactive graph at /graph/
+}
+
+type graphNode struct {
+	ID         string `json:"id"` // page URL
+	Title      string `json:"title"`
+	URL        string `json:"url"`
+	Popularity int    `json:"popularity"`
+}
+
+type graphEdge struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+}
+
+type graphData struct {
+	Nodes []graphNode `json:"nodes"`
+	Edges []graphEdge `json:"edges"`
+}
+
+// collectGraph converts the link graph into nodes and edges, sorted by URL
+func collectGraph(pages []Page, links *linkGraph) graphData {
+	data := graphData{Nodes: []graphNode{}, Edges: []graphEdge{}}
+	for _, page := range pages {
+		data.Nodes = append(data.Nodes, graphNode{ID: page.URL, Title: page.Title, URL: page.URL, Popularity: page.Popularity})
+		for _, target := range links.outbound[page.URL] {
+			data.Edges = append(data.Edges, graphEdge{Source: page.URL, Target: target})
+		}
+	}
+	sort.Slice(data.Nodes, func(i, j int) bool {
+		return data.Nodes[i].ID < data.Nodes[j].ID
+	})
+	sort.Slice(data.Edges, func(i, j int) bool {
+		if data.Edges[i].Source != data.Edges[j].Source {
+			return data.Edges[i].Source < data.Edges[j].Source
+		}
+		return data.Edges[i].Target < data.Edges[j].Target
+	})
+	return data
+}
+
+// writeGraph writes graph.json and, when enabled, the graph page to publicDir
+func writeGraph(cfg *Config, data graphData, publicDir string) error {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	outputPath := filepath.Join(publicDir, "graph.json")
+	if err := os.WriteFile(outputPath, encoded, 0644); err != nil {
+		return err
+	}
+	logGenerated(outputPath)
+
+	if !cfg.Graph.Page {
+		return nil
+	}
+	tmpl, err := template.New("graph").Funcs(templateFuncs(cfg)).Parse(graphPageTemplate)
+	if err != nil {
+		return err
+	}
+	return renderPage(tmpl, nil, filepath.Join(publicDir, "graph", "index.html"))
+}
+
+// graphPageTemplate draws graph.json with a small force-directed layout
+// Clicking a node opens the page
+const graphPageTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>{{with site.Title}}{{.}} {{end}}Graph</title>
+    <style>
+        html, body, main { margin: 0; height: 100%; font-family: sans-serif; }
+        canvas { display: block; width: 100%; height: 100%; cursor: pointer; }
+        h1 { position: absolute; width: 1px; height: 1px; overflow: hidden; clip: rect(0 0 0 0); }
+    </style>
+</head>
+<body>
+<main id="main">
+    <h1>{{with site.Title}}{{.}} {{end}}Graph</h1>
+    <canvas id="graph" role="img" aria-label="The pages of the site and the links between them"></canvas>
+    <noscript><p><a href="/graph.json">The graph as JSON</a></p></noscript>
+    <script>
+    (async function () {
+        const data = await (await fetch("/graph.json")).json();
+        const canvas = document.getElementById("graph");
+        const ctx = canvas.getContext("2d");
+        const byId = {};
+        data.nodes.forEach(function (n, i) {
+            const angle = 2 * Math.PI * i / data.nodes.length;
+            n.x = Math.cos(angle) * 200; n.y = Math.sin(angle) * 200; n.vx = 0; n.vy = 0;
+            n.r = 4 + 2 * Math.sqrt(n.popularity);
+            byId[n.id] = n;
+        });
+        const edges = data.edges.filter(function (e) { return byId[e.source] && byId[e.target]; });
+        let hover = null;
+
+        function step() {
+            data.nodes.forEach(function (a) {
+                data.nodes.forEach(function (b) {
+                    if (a === b) return;
+                    const dx = a.x - b.x, dy = a.y - b.y, d2 = dx * dx + dy * dy + 0.01;
+                    a.vx += dx / d2 * 50; a.vy += dy / d2 * 50;
+                });
+                a.vx -= a.x * 0.002; a.vy -= a.y * 0.002;
+            });
+            edges.forEach(function (e) {
+                const s = byId[e.source], t = byId[e.target];
+                const dx = t.x - s.x, dy = t.y - s.y;
+                s.vx += dx * 0.005; s.vy += dy * 0.005; t.vx -= dx * 0.005; t.vy -= dy * 0.005;
+            });
+            data.nodes.forEach(function (n) { n.vx *= 0.85; n.vy *= 0.85; n.x += n.vx; n.y += n.vy; });
+        }
+
+        function draw() {
+            canvas.width = canvas.clientWidth; canvas.height = canvas.clientHeight;
+            ctx.translate(canvas.width / 2, canvas.height / 2);
+            ctx.strokeStyle = "#ccc";
+            edges.forEach(function (e) {
+                ctx.beginPath(); ctx.moveTo(byId[e.source].x, byId[e.source].y);
+                ctx.lineTo(byId[e.target].x, byId[e.target].y); ctx.stroke();
+            });
+            data.nodes.forEach(function (n) {
+                ctx.fillStyle = n === hover ? "#0066cc" : "#333";
+                ctx.beginPath(); ctx.arc(n.x, n.y, n.r, 0, 2 * Math.PI); ctx.fill();
+                ctx.fillText(n.title, n.x + n.r + 3, n.y + 3);
+            });
+        }
+
+        function nodeAt(event) {
+            const x = event.offsetX - canvas.width / 2, y = event.offsetY - canvas.height / 2;
+            return data.nodes.find(function (n) { return (n.x - x) ** 2 + (n.y - y) ** 2 <= (n.r + 3) ** 2; }) || null;
+        }
+        canvas.addEventListener("mousemove", function (event) { hover = nodeAt(event); });
+        canvas.addEventListener("click", function (event) {
+            const n = nodeAt(event);
+            if (n) location.href = n.url;
+        });
+
+        (function frame() { step(); draw(); requestAnimationFrame(frame); })();
+    })();
+    </script>
+</main>
+</body>
+</html>
+`
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// historyFile is the append-only log of builds and deploys, one JSON
+// object per line
+var historyFile = filepath.Join(".slate", "history.log")
+
+// historyEntry records one build or deploy
+type historyEntry struct {
+	Time     time.Time `json:"time"`
+	Command  string    `json:"command"` // e.g. build
+	Duration int64     `json:"durationMs"`
+	OK       bool      `json:"ok"`
+	Error    string    `json:"error,omitempty"`
+	Pages    int       `json:"pages"` // files rendered
+	Warnings int       `json:"warnings"`
+	Commit   string    `json:"commit,omitempty"` // git HEAD of the project
+}
+
+// recordHistory appends an entry for a command that started at start
+// Failing to write the history never fails the command itself
+func recordHistory(command string, start time.Time, pages int, err error) {
+	entry := historyEntry{
+		Time:     start,
+		Command:  command,
+		Duration: time.Since(start).Milliseconds(),
+		OK:       err == nil,
+		Pages:    pages,
+		Warnings: log.warnings,
+		Commit:   gitHead(),
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+
+	if err := appendHistory(entry); err != nil {
+		log.Debugf("writing %s: %v", historyFile, err)
+	}
+}
+
+func appendHistory(entry historyEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(historyFile), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(historyFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// readHistory returns the recorded entries, oldest first
+// Lines that can't be parsed are skipped
+func readHistory() ([]historyEntry, error) {
+	file, err := os.Open(historyFile)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []historyEntry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry historyEntry
+		if json.Unmarshal(scanner.Bytes(), &entry) == nil {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// gitHead returns the short hash of the project's current commit, empty
+// outside a git repository
+func gitHead() string {
+	out, err := exec.Command("git", "rev-parse", "--short", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// historyCmd prints the most recent builds and deploys
+func historyCmd(args []string) {
+	flags := flag.NewFlagSet("history", flag.ExitOnError)
+	limit := flags.Int("n", 20, "number of entries to show, 0 for all")
+	asJSON := flags.Bool("json", false, "print entries as JSON lines")
+	flags.Parse(args)
+
+	entries, err := readHistory()
+	if os.IsNotExist(err) {
+		log.Infof("No history yet, it is recorded in %s", historyFile)
+		return
+	}
+	if err != nil {
+		log.Errorf("reading %s: %v", historyFile, err)
+		os.Exit(1)
+	}
+	if *limit > 0 && len(entries) > *limit {
+		entries = entries[len(entries)-*limit:]
+	}
+
+	// Records take stdout under --porcelain, whatever the format
+	if *asJSON && porcelainOut == nil {
+		for _, entry := range entries {
+			line, _ := json.Marshal(entry)
+			fmt.Println(string(line))
+		}
+		return
+	}
+
+	if porcelainOut != nil {
+		for _, entry := range entries {
+			result := "ok"
+			if !entry.OK {
+				result = "failed"
+			}
+			record("history", entry.Time.Format(time.RFC3339), entry.Command, result,
+				entry.Duration, entry.Pages, entry.Warnings, entry.Commit, entry.Error)
+		}
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tCOMMAND\tRESULT\tDURATION\tPAGES\tWARNINGS\tCOMMIT")
+	for _, entry := range entries {
+		result := "ok"
+		if !entry.OK {
+			result = "failed: " + entry.Error
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%d\t%s\n",
+			entry.Time.Local().Format("2006-01-02 15:04:05"), entry.Command, result,
+			time.Duration(entry.Duration)*time.Millisecond, entry.Pages, entry.Warnings, entry.Commit)
+	}
+	w.Flush()
+}
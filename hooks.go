@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+// HooksConfig lists shell commands run around each build
+type HooksConfig struct {
+	PreBuild  []string `yaml:"preBuild"`  // run before any content is read
+	PostBuild []string `yaml:"postBuild"` // run after a successful build
+}
+
+// runHooks runs each command in a shell, in order, stopping at the first
+// that fails. Output is streamed as it's produced.
+func runHooks(cfg *Config, stage string, commands []string) error {
+	for _, command := range commands {
+		log.Infof("Running %s hook: %s", stage, command)
+
+		var cmd *exec.Cmd
+		if runtime.GOOS == "windows" {
+			cmd = exec.Command("cmd", "/C", command)
+		} else {
+			cmd = exec.Command("sh", "-c", command)
+		}
+		cmd.Stdout = log.out
+		cmd.Stderr = log.errOut
+		cmd.Env = append(os.Environ(), hookEnv(cfg)...)
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("%s hook %q: %w", stage, command, err)
+		}
+	}
+	return nil
+}
+
+// hookEnv describes the build to hook commands
+func hookEnv(cfg *Config) []string {
+	return []string{
+		"SLATE_OUTPUT_DIR=" + cfg.outputDir(),
+		"SLATE_BASE_URL=" + cfg.BaseURL,
+		"SLATE_ENV=" + cfg.Env,
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// HostingConfig describes redirects and headers once, written out in the
+// formats hosting platforms read from the published site
+type HostingConfig struct {
+	Platforms []string         `yaml:"platforms"` // netlify (also Cloudflare Pages) and vercel
+	Redirects []HostRedirect   `yaml:"redirects"`
+	Headers   []HostHeaderRule `yaml:"headers"`
+	CleanURLs bool             `yaml:"cleanURLs"` // serve /blog/post for /blog/post.html
+}
+
+// HostRedirect sends requests for From to To
+// From may end in * to match a whole path, reused in To as :splat
+type HostRedirect struct {
+	From   string `yaml:"from"`
+	To     string `yaml:"to"`
+	Status int    `yaml:"status"` // defaults to 301
+	Force  bool   `yaml:"force"`  // redirect even when a file exists at From
+}
+
+// HostHeaderRule sets response headers on paths matching Path
+type HostHeaderRule struct {
+	Path   string            `yaml:"path"` // e.g. /* or /assets/*
+	Values map[string]string `yaml:"values"`
+}
+
+// writeHostingConfig writes the configuration files of each platform to
+// publicDir, including a redirect from every page alias and from every
+// page with a redirect in its frontmatter
+func writeHostingConfig(cfg HostingConfig, pages []Page, publicDir string) error {
+	if len(cfg.Platforms) == 0 {
+		return nil
+	}
+
+	redirects := slices.Clone(cfg.Redirects)
+	for _, page := range pages {
+		for _, alias := range page.Aliases {
+			redirects = append(redirects, HostRedirect{From: alias, To: page.URL})
+		}
+		// The page's own file is a meta refresh, which the redirect must win over
+		if page.Redirect != "" {
+			redirects = append(redirects, HostRedirect{From: page.URL, To: page.Redirect, Force: true})
+		}
+	}
+	for i := range redirects {
+		if redirects[i].Status == 0 {
+			redirects[i].Status = 301
+		}
+	}
+
+	for _, platform := range cfg.Platforms {
+		var err error
+		switch platform {
+		case "netlify":
+			err = writeNetlifyConfig(cfg, redirects, publicDir)
+		case "vercel":
+			err = writeVercelConfig(cfg, redirects, publicDir)
+		default:
+			err = fmt.Errorf("unknown hosting platform %q", platform)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeNetlifyConfig writes _redirects and _headers
+// Netlify serves pages without their .html extension on its own.
+func writeNetlifyConfig(cfg HostingConfig, redirects []HostRedirect, publicDir string) error {
+	if len(redirects) > 0 {
+		var b strings.Builder
+		for _, r := range redirects {
+			force := ""
+			if r.Force {
+				force = "!"
+			}
+			fmt.Fprintf(&b, "%s  %s  %d%s\n", r.From, r.To, r.Status, force)
+		}
+		if err := writeHostingFile(publicDir, "_redirects", b.String()); err != nil {
+			return err
+		}
+	}
+
+	if len(cfg.Headers) > 0 {
+		var b strings.Builder
+		for _, rule := range cfg.Headers {
+			b.WriteString(rule.Path + "\n")
+			for _, name := range sortedKeys(rule.Values) {
+				fmt.Fprintf(&b, "  %s: %s\n", name, rule.Values[name])
+			}
+		}
+		if err := writeHostingFile(publicDir, "_headers", b.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// vercelConfig is the subset of vercel.json slate writes
+type vercelConfig struct {
+	CleanURLs bool             `json:"cleanUrls,omitempty"`
+	Redirects []vercelRedirect `json:"redirects,omitempty"`
+	Headers   []vercelHeaders  `json:"headers,omitempty"`
+}
+
+type vercelRedirect struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+	StatusCode  int    `json:"statusCode"`
+}
+
+type vercelHeaders struct {
+	Source  string         `json:"source"`
+	Headers []vercelHeader `json:"headers"`
+}
+
+type vercelHeader struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// writeVercelConfig writes vercel.json
+// Vercel applies redirects before serving files, so Force needs nothing.
+func writeVercelConfig(cfg HostingConfig, redirects []HostRedirect, publicDir string) error {
+	vc := vercelConfig{CleanURLs: cfg.CleanURLs}
+	for _, r := range redirects {
+		vc.Redirects = append(vc.Redirects, vercelRedirect{
+			Source:      vercelPath(r.From),
+			Destination: strings.ReplaceAll(r.To, ":splat", ":splat*"),
+			StatusCode:  r.Status,
+		})
+	}
+	for _, rule := range cfg.Headers {
+		headers := vercelHeaders{Source: vercelPath(rule.Path)}
+		for _, name := range sortedKeys(rule.Values) {
+			headers.Headers = append(headers.Headers, vercelHeader{Key: name, Value: rule.Values[name]})
+		}
+		vc.Headers = append(vc.Headers, headers)
+	}
+
+	data, err := json.MarshalIndent(vc, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeHostingFile(publicDir, "vercel.json", string(data)+"\n")
+}
+
+// vercelPath converts a trailing * to Vercel's named wildcard
+// e.g., "/blog/*" → "/blog/:splat*"
+func vercelPath(p string) string {
+	if strings.HasSuffix(p, "*") {
+		return strings.TrimSuffix(p, "*") + ":splat*"
+	}
+	return p
+}
+
+func writeHostingFile(publicDir, name, content string) error {
+	outputPath := filepath.Join(publicDir, name)
+	if err := os.WriteFile(outputPath, []byte(content), 0644); err != nil {
+		return err
+	}
+	logGenerated(outputPath)
+	return nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ignoreFile lists patterns of content and static files left out of the
+// site, in addition to the ignore setting
+const ignoreFile = ".slateignore"
+
+// readIgnoreFile returns the patterns in file, one per line
+// Blank lines and lines starting with # are skipped.
+func readIgnoreFile(file string) ([]string, error) {
+	f, err := os.Open(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var patterns []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			patterns = append(patterns, line)
+		}
+	}
+	return patterns, scanner.Err()
+}
+
+// ignored reports whether a file or directory, relative to the content or
+// static directory, matches an ignore pattern
+// Patterns without a slash match names at any depth, e.g. *~ or README.md,
+// others match the whole relative path, e.g. blog/old/*. A trailing slash
+// only matches directories, e.g. _drafts/.
+func (c *Config) ignored(rel string, dir bool) bool {
+	rel = filepath.ToSlash(rel)
+	for _, patterns := range [][]string{c.Ignore, c.ignoreFile} {
+		for _, pattern := range patterns {
+			dirOnly := strings.HasSuffix(pattern, "/")
+			if dirOnly && !dir {
+				continue
+			}
+			anchored := strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+			pattern = strings.Trim(pattern, "/")
+			name := rel
+			if !anchored {
+				name = path.Base(rel)
+			}
+			if ok, _ := path.Match(pattern, name); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
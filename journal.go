@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// failedJournalFile lists the files that failed in the last build so
+// `slate build --retry-failed` can reprocess only those
+const failedJournalFile = ".slate-failed.json"
+
+// Build stages a content file can fail in
+const (
+	stageRead    = "read"
+	stageConvert = "convert"
+	stageRender  = "render"
+	stageListing = "listing" // a tag, category or other listing page, recorded under its template
+)
+
+// buildFailure records a content file that failed and the stage it failed in
+type buildFailure struct {
+	File  string `json:"file"`
+	Stage string `json:"stage"`
+	Error string `json:"error"`
+}
+
+// failureJournal collects per-file failures so one broken file doesn't stop
+// the rest of the build
+type failureJournal struct {
+	Failures []buildFailure `json:"failures"`
+}
+
+func (j *failureJournal) add(file, stage string, err error) {
+	log.Errorf("%s: %s failed: %v", file, stage, err)
+	j.Failures = append(j.Failures, buildFailure{File: file, Stage: stage, Error: err.Error()})
+}
+
+// err summarizes the failures as a single error, nil if there were none
+func (j *failureJournal) err() error {
+	if len(j.Failures) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d page(s) failed", len(j.Failures))
+}
+
+// summarize lists the failures again once the build is done, where they
+// don't scroll away, and with retry how to rebuild just those
+func (j *failureJournal) summarize(retry bool) {
+	if len(j.Failures) == 0 {
+		return
+	}
+	log.Infof("%d page(s) failed:", len(j.Failures))
+	for _, f := range j.Failures {
+		log.Infof("  %s: %s failed: %s", f.File, f.Stage, f.Error)
+	}
+	if retry {
+		log.Infof("Fix the files listed in %s and run `slate build --retry-failed`", failedJournalFile)
+	}
+}
+
+// save writes the journal, or removes a stale one when nothing failed
+func (j *failureJournal) save() error {
+	if len(j.Failures) == 0 {
+		if err := os.Remove(failedJournalFile); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(failedJournalFile, append(data, '\n'), 0644)
+}
+
+// readFailureJournal loads the failures recorded by the last build
+func readFailureJournal() ([]buildFailure, error) {
+	data, err := os.ReadFile(failedJournalFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var j failureJournal
+	if err := json.Unmarshal(data, &j); err != nil {
+		return nil, err
+	}
+	return j.Failures, nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"strings"
+	"time"
+)
+
+// jsonLD returns Schema.org structured data for a template's data:
+// BlogPosting for posts, WebSite for the home page and BreadcrumbList for
+// listing pages. It returns nil for anything else.
+// The head partial marshals the result into a ld+json script tag.
+func jsonLD(cfg *Config, data any) map[string]any {
+	switch d := data.(type) {
+	case Page:
+		if d.URL == "/index.html" {
+			return websiteLD(cfg)
+		}
+		if strings.Contains(d.Path, "/blog/") {
+			return blogPostingLD(cfg, d)
+		}
+	case []Page:
+		return breadcrumbLD(cfg, PageLink{Title: "Blog", URL: "/blog/"})
+	case TagPage:
+		return breadcrumbLD(cfg, PageLink{Title: d.Name, URL: d.URL})
+	case CategoryPage:
+		return breadcrumbLD(cfg, d.Breadcrumbs...)
+	case AuthorPage:
+		return breadcrumbLD(cfg, PageLink{Title: d.Name, URL: d.URL})
+	case SeriesPage:
+		return breadcrumbLD(cfg, PageLink{Title: d.Name, URL: d.URL})
+	}
+	return nil
+}
+
+func websiteLD(cfg *Config) map[string]any {
+	ld := map[string]any{
+		"@context": "https://schema.org",
+		"@type":    "WebSite",
+		"url":      absURL(cfg, "/"),
+	}
+	if cfg.Title != "" {
+		ld["name"] = cfg.Title
+	}
+	if cfg.Head.Description != "" {
+		ld["description"] = cfg.Head.Description
+	}
+	return ld
+}
+
+func blogPostingLD(cfg *Config, page Page) map[string]any {
+	ld := map[string]any{
+		"@context": "https://schema.org",
+		"@type":    "BlogPosting",
+		"headline": page.Title,
+		"url":      absURL(cfg, page.URL),
+	}
+	if page.Summary != "" {
+		ld["description"] = page.Summary
+	}
+	if !page.Date.IsZero() {
+		ld["datePublished"] = page.Date.Format(time.RFC3339)
+	}
+	if !page.Lastmod.IsZero() {
+		ld["dateModified"] = page.Lastmod.Format(time.RFC3339)
+	}
+	if page.OGImage != "" {
+		ld["image"] = absURL(cfg, page.OGImage)
+	}
+	if len(page.Tags) > 0 {
+		ld["keywords"] = strings.Join(page.Tags, ", ")
+	}
+
+	var authors []map[string]any
+	for _, author := range page.Authors {
+		person := map[string]any{"@type": "Person", "name": author.Name}
+		if author.Website != "" {
+			person["url"] = author.Website
+		}
+		authors = append(authors, person)
+	}
+	if len(authors) > 0 {
+		ld["author"] = authors
+	}
+	if cfg.Title != "" {
+		ld["publisher"] = map[string]any{"@type": "Organization", "name": cfg.Title}
+	}
+	return ld
+}
+
+// breadcrumbLD lists the home page followed by crumbs
+func breadcrumbLD(cfg *Config, crumbs ...PageLink) map[string]any {
+	home := cfg.Title
+	if home == "" {
+		home = "Home"
+	}
+	crumbs = append([]PageLink{{Title: home, URL: "/"}}, crumbs...)
+
+	items := make([]map[string]any, len(crumbs))
+	for i, crumb := range crumbs {
+		items[i] = map[string]any{
+			"@type":    "ListItem",
+			"position": i + 1,
+			"name":     crumb.Title,
+			"item":     absURL(cfg, crumb.URL),
+		}
+	}
+	return map[string]any{
+		"@context":        "https://schema.org",
+		"@type":           "BreadcrumbList",
+		"itemListElement": items,
+	}
+}
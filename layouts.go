@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"path/filepath"
+)
+
+// pageLayout returns the template file fm asks to be rendered with, from
+// layout or its alias template, e.g. landing.html for layout: landing
+func pageLayout(fm Frontmatter) string {
+	name := fm.Layout
+	if name == "" {
+		name = fm.Template
+	}
+	if name != "" && filepath.Ext(name) == "" {
+		name += ".html"
+	}
+	return name
+}
+
+// pageLayouts parses the templates pages choose with layout, each once
+type pageLayouts struct {
+	cfg    *Config
+	parsed map[string]*template.Template
+	errs   map[string]error
+}
+
+func newPageLayouts(cfg *Config) *pageLayouts {
+	return &pageLayouts{cfg: cfg, parsed: map[string]*template.Template{}, errs: map[string]error{}}
+}
+
+// template returns the template to render page with, its layout's or else
+// the default for where it is
+func (l *pageLayouts) template(page Page, fallback *template.Template) (*template.Template, error) {
+	name := page.Layout
+	if name == "" {
+		return fallback, nil
+	}
+	if tmpl, ok := l.parsed[name]; ok {
+		return tmpl, nil
+	}
+	if err, ok := l.errs[name]; ok {
+		return nil, err
+	}
+	var tmpl *template.Template
+	err := fmt.Errorf("layout %q isn't a file in %s/", name, l.cfg.templateDir())
+	if filepath.Base(name) == name {
+		if tmpl, err = parseTemplate(l.cfg, name); err != nil {
+			err = fmt.Errorf("layout %s: %w", name, err)
+		}
+	}
+	if err != nil {
+		l.errs[name] = err
+		return nil, err
+	}
+	l.parsed[name] = tmpl
+	return tmpl, nil
+}
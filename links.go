@@ -0,0 +1,162 @@
+package main
+
+import (
+	"net/url"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+// sourcePathKey stores the content file being converted in the parser context
+var sourcePathKey = parser.NewContextKey()
+
+// mdLinkTransformer rewrites links to .md files into their published URLs
+// e.g., [post](other-post.md) in content/blog/a.md → /blog/other-post.html
+type mdLinkTransformer struct {
+	cfg *Config
+}
+
+func (t *mdLinkTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	source, _ := pc.Get(sourcePathKey).(string)
+	if source == "" {
+		return
+	}
+
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		if link, ok := n.(*ast.Link); ok {
+			if rewritten, ok := t.cfg.rewriteMarkdownLink(source, string(link.Destination)); ok {
+				link.Destination = []byte(rewritten)
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+// rewriteMarkdownLink resolves dest relative to the source file and returns
+// the published URL if it points at a markdown file inside content/
+func (c *Config) rewriteMarkdownLink(source, dest string) (string, bool) {
+	resolved, fragment, ok := c.resolveMarkdownLink(source, dest)
+	if !ok {
+		return "", false
+	}
+
+	rewritten := c.pathToURL(resolved)
+	if fragment != "" {
+		rewritten += "#" + fragment
+	}
+	return rewritten, true
+}
+
+// resolveMarkdownLink returns the content file dest points at and its
+// fragment, if dest is a link to a markdown file inside content/
+// e.g., ("content/blog/a.md", "../about.md#team") → "content/about.md", "team"
+func (c *Config) resolveMarkdownLink(source, dest string) (string, string, bool) {
+	if dest == "" || strings.HasPrefix(dest, "#") || strings.Contains(dest, ":") {
+		return "", "", false
+	}
+
+	target, fragment, _ := strings.Cut(dest, "#")
+	if !strings.HasSuffix(strings.ToLower(target), ".md") {
+		return "", "", false
+	}
+	if unescaped, err := url.PathUnescape(target); err == nil {
+		target = unescaped
+	}
+
+	// Absolute links are relative to the content root
+	root := filepath.ToSlash(c.contentDir())
+	var resolved string
+	if strings.HasPrefix(target, "/") {
+		resolved = path.Join(root, target)
+	} else {
+		resolved = path.Join(path.Dir(filepath.ToSlash(source)), target)
+	}
+	if !strings.HasPrefix(resolved, root+"/") {
+		return "", "", false
+	}
+	return resolved[:len(resolved)-len(".md")] + ".md", fragment, true
+}
+
+// linkGraph records internal links between pages, keyed by page URL
+type linkGraph struct {
+	outbound map[string][]string
+	inbound  map[string][]string
+}
+
+// buildLinkGraph scans each page's rendered content for links to other pages
+func buildLinkGraph(pages []Page) *linkGraph {
+	g := &linkGraph{outbound: map[string][]string{}, inbound: map[string][]string{}}
+
+	known := map[string]bool{}
+	for _, page := range pages {
+		known[page.URL] = true
+	}
+
+	for _, page := range pages {
+		seen := map[string]bool{}
+		for _, m := range linkAttrPattern.FindAllStringSubmatch(string(page.Content), -1) {
+			target, ok := resolvePageURL(page.URL, m[1])
+			if !ok || target == page.URL || seen[target] || !known[target] {
+				continue
+			}
+			seen[target] = true
+			g.outbound[page.URL] = append(g.outbound[page.URL], target)
+			g.inbound[target] = append(g.inbound[target], page.URL)
+		}
+	}
+	return g
+}
+
+// resolvePageURL resolves an href found on the page at base into a page URL
+// e.g., ("/blog/a.html", "b.html#x") → "/blog/b.html", ("/x.html", "/blog/") → "/blog/index.html"
+func resolvePageURL(base, href string) (string, bool) {
+	if !isInternalLink(href) {
+		return "", false
+	}
+	href, _, _ = strings.Cut(href, "#")
+	href, _, _ = strings.Cut(href, "?")
+	if href == "" {
+		return "", false
+	}
+	if unescaped, err := url.PathUnescape(href); err == nil {
+		href = unescaped
+	}
+
+	resolved := href
+	if !strings.HasPrefix(href, "/") {
+		resolved = path.Join(path.Dir(base), href)
+		if strings.HasSuffix(href, "/") {
+			resolved += "/"
+		}
+	}
+	if strings.HasSuffix(resolved, "/") {
+		resolved += "index.html"
+	}
+	return resolved, true
+}
+
+// popularPages holds the site's pages ordered by inbound links, most linked
+// first, for the popular template function
+var popularPages []Page
+
+// rankByPopularity returns the pages that have inbound links, most linked first
+func rankByPopularity(pages []Page) []Page {
+	var ranked []Page
+	for _, page := range pages {
+		if page.Popularity > 0 {
+			ranked = append(ranked, page)
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].Popularity > ranked[j].Popularity
+	})
+	return ranked
+}
@@ -0,0 +1,32 @@
+package main
+
+import "testing"
+
+func TestResolveMarkdownLink(t *testing.T) {
+	cfg := &Config{}
+	tests := []struct {
+		source, dest string
+		want, frag   string
+		wantOK       bool
+	}{
+		{"content/blog/a.md", "b.md", "content/blog/b.md", "", true},
+		{"content/blog/a.md", "../about.md#team", "content/about.md", "team", true},
+		{"content/blog/a.md", "/docs/guide.md", "content/docs/guide.md", "", true},
+		{"content/blog/a.md", "My%20Post.md", "content/blog/My Post.md", "", true},
+		{"content/blog/a.md", "Upper.MD", "content/blog/Upper.md", "", true},
+		{"content/blog/a.md", "../../outside.md", "", "", false},
+		{"content/blog/a.md", "https://example.com/a.md", "", "", false},
+		{"content/blog/a.md", "#section", "", "", false},
+		{"content/blog/a.md", "image.png", "", "", false},
+		{"content/blog/a.md", "", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dest, func(t *testing.T) {
+			got, frag, ok := cfg.resolveMarkdownLink(tt.source, tt.dest)
+			if got != tt.want || frag != tt.frag || ok != tt.wantOK {
+				t.Errorf("resolveMarkdownLink(%q, %q) = %q, %q, %v, want %q, %q, %v",
+					tt.source, tt.dest, got, frag, ok, tt.want, tt.frag, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// LintConfig adjusts the rules of `slate lint`
+type LintConfig struct {
+	Disable        []string `yaml:"disable"`        // rules left out, e.g. [description]
+	MaxTitleLength int      `yaml:"maxTitleLength"` // in characters, 70 by default, about what search results show
+}
+
+// lintRules are the checks `slate lint` runs, in the order it reports them
+var lintRules = []string{"frontmatter", "title", "title-length", "date", "description", "alt"}
+
+// lintIssue is a problem `slate lint` found in a content file
+type lintIssue struct {
+	File string
+	Line int
+	Rule string
+	Msg  string
+}
+
+func (i lintIssue) String() string {
+	return fmt.Sprintf("%s:%d: %s (%s)", i.File, i.Line, i.Msg, i.Rule)
+}
+
+// maxTitleLength returns the configured title limit or the default
+func (c LintConfig) maxTitleLength() int {
+	if c.MaxTitleLength > 0 {
+		return c.MaxTitleLength
+	}
+	return 70
+}
+
+// lintCmd checks every local content file and lists the issues, exiting
+// with 1 when there are any
+func lintCmd(args []string) bool {
+	flags := flag.NewFlagSet("lint", flag.ExitOnError)
+	flags.Parse(args)
+
+	cfg, err := loadConfig()
+	if err != nil {
+		log.Errorf("loading %s: %v", configFile, err)
+		return false
+	}
+	for _, rule := range cfg.Lint.Disable {
+		if !slices.Contains(lintRules, rule) {
+			log.Warnf("lint.disable: unknown rule %q, the rules are %s", rule, strings.Join(lintRules, ", "))
+		}
+	}
+	// Modules aren't mounted, their content belongs to other repositories
+	files, err := findContentFiles(cfg)
+	if err != nil {
+		log.Errorf("reading content: %v", err)
+		return false
+	}
+
+	var issues []lintIssue
+	for _, file := range files {
+		content, err := readContent(file)
+		if err != nil {
+			log.Errorf("reading %s: %v", file, err)
+			return false
+		}
+		issues = append(issues, lintContent(cfg, file, content)...)
+	}
+
+	for _, issue := range issues {
+		log.Warnf("%s", issue)
+		record("lint", issue.File, issue.Line, issue.Rule, issue.Msg)
+	}
+	if len(issues) > 0 {
+		log.Infof("Found %d issue(s) in %d file(s)", len(issues), len(files))
+		return false
+	}
+	log.Infof("No issues in %d file(s)", len(files))
+	return true
+}
+
+// lintContent runs the enabled rules against a content file
+func lintContent(cfg *Config, file string, content []byte) []lintIssue {
+	var issues []lintIssue
+	add := func(line int, rule, format string, args ...any) {
+		if !slices.Contains(cfg.Lint.Disable, rule) {
+			issues = append(issues, lintIssue{File: file, Line: line, Rule: rule, Msg: fmt.Sprintf(format, args...)})
+		}
+	}
+
+	for _, issue := range lintFrontmatter(content) {
+		add(issue.Line, "frontmatter", "%s", issue.Msg)
+	}
+	fm, _, _ := parseFrontmatter(content)
+	lines := strings.Split(string(content), "\n")
+
+	title := strings.TrimSpace(fm.Title)
+	if title == "" {
+		add(frontmatterKeyLine(lines, "title"), "title", "no title, the page is titled %q after its file name", extractTitle(file))
+	} else if n := utf8.RuneCountInString(title); n > cfg.Lint.maxTitleLength() {
+		add(frontmatterKeyLine(lines, "title"), "title-length", "title is %d characters, over %d", n, cfg.Lint.maxTitleLength())
+	}
+
+	if fm.Date != "" {
+		if _, err := cfg.pageDate(fm); err != nil {
+			add(frontmatterKeyLine(lines, "date"), "date", "%v", err)
+		}
+	}
+
+	if strings.TrimSpace(fm.Description) == "" {
+		add(frontmatterKeyLine(lines, "description"), "description", "no description, search results and link previews fall back to the first paragraph")
+	}
+
+	// Other formats have their own image syntax
+	if _, converted := cfg.contentFormat(file); !converted {
+		for _, issue := range auditAltText(file, content) {
+			add(issue.Line, "alt", "%s", issue.Msg)
+		}
+	}
+	return issues
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+type logLevel int
+
+const (
+	levelDebug logLevel = iota
+	levelInfo
+	levelWarn
+	levelError
+)
+
+func (l logLevel) String() string {
+	switch l {
+	case levelDebug:
+		return "debug"
+	case levelInfo:
+		return "info"
+	case levelWarn:
+		return "warn"
+	default:
+		return "error"
+	}
+}
+
+// logger writes leveled messages as human-readable text or JSON lines
+type logger struct {
+	level    logLevel
+	json     bool
+	out      io.Writer // debug and info
+	errOut   io.Writer // warnings and errors
+	warnings int       // warnings reported since the last reset
+}
+
+var log = &logger{level: levelInfo, out: os.Stdout, errOut: os.Stderr}
+
+func (l *logger) Debugf(format string, args ...any) {
+	l.logf(levelDebug, format, args...)
+}
+
+func (l *logger) Infof(format string, args ...any) {
+	l.logf(levelInfo, format, args...)
+}
+
+// Warnf reports a problem that doesn't stop the current command
+func (l *logger) Warnf(format string, args ...any) {
+	l.warnings++
+	l.logf(levelWarn, format, args...)
+}
+
+func (l *logger) Errorf(format string, args ...any) {
+	l.logf(levelError, format, args...)
+}
+
+func (l *logger) logf(level logLevel, format string, args ...any) {
+	if level < l.level {
+		return
+	}
+
+	out := l.out
+	if level >= levelWarn {
+		out = l.errOut
+	}
+	msg := fmt.Sprintf(format, args...)
+	buildEvents.Log(level, msg)
+	switch level {
+	case levelWarn:
+		record("warning", msg)
+	case levelError:
+		record("error", msg)
+	}
+
+	// Keep the progress bar below log output
+	buildProgress.Clear()
+	defer buildProgress.Redraw()
+
+	if l.json {
+		line, _ := json.Marshal(struct {
+			Time  string `json:"time"`
+			Level string `json:"level"`
+			Msg   string `json:"msg"`
+		}{time.Now().Format(time.RFC3339), level.String(), msg})
+		fmt.Fprintln(out, string(line))
+		return
+	}
+
+	switch level {
+	case levelWarn:
+		msg = "Warning: " + msg
+	case levelError:
+		msg = "Error: " + msg
+	}
+	fmt.Fprintln(out, msg)
+}
+
+// parseGlobalFlags applies the logging and environment flags accepted by
+// every command and returns the remaining arguments. Only flags before the
+// command are global, later ones belong to the command.
+func parseGlobalFlags(args []string) []string {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if env, ok := strings.CutPrefix(arg, "--env="); ok {
+			os.Setenv("SLATE_ENV", env)
+			continue
+		}
+		switch arg {
+		case "-q", "--quiet":
+			log.level = levelWarn
+		case "-v", "--verbose":
+			log.level = levelDebug
+		case "--log-json":
+			log.json = true
+		case "--porcelain":
+			enablePorcelain()
+		case "--env":
+			if i+1 < len(args) {
+				i++
+				os.Setenv("SLATE_ENV", args[i])
+			}
+		default:
+			return args[i:]
+		}
+	}
+	return nil
+}
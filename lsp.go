@@ -0,0 +1,320 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// LSP diagnostic severities
+const (
+	severityError   = 1
+	severityWarning = 2
+)
+
+// lspMessage is a JSON-RPC 2.0 request, response or notification
+type lspMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  any             `json:"result,omitempty"`
+	Error   *lspError       `json:"error,omitempty"`
+}
+
+type lspError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+type lspDiagnostic struct {
+	Range    lspRange `json:"range"`
+	Severity int      `json:"severity"`
+	Source   string   `json:"source"`
+	Message  string   `json:"message"`
+}
+
+// lspDocument carries the fields slate needs from the textDocument
+// notifications it handles
+type lspDocument struct {
+	TextDocument struct {
+		URI  string `json:"uri"`
+		Text string `json:"text"`
+	} `json:"textDocument"`
+	ContentChanges []struct {
+		Text string `json:"text"`
+	} `json:"contentChanges"`
+}
+
+// markdownLinkPattern matches inline markdown links and images, capturing
+// the destination
+var markdownLinkPattern = regexp.MustCompile(`\]\(\s*<?([^)\s>]+)>?(?:\s+"[^"]*")?\s*\)`)
+
+// lspCmd runs a Language Server Protocol server on stdin/stdout that reports
+// diagnostics for markdown files in content/
+func lspCmd() {
+	// stdout carries the protocol, so logs go to stderr
+	log.out = os.Stderr
+
+	cfg, err := loadConfig()
+	if err != nil {
+		log.Errorf("loading %s: %v", configFile, err)
+		os.Exit(1)
+	}
+
+	s := &lspServer{cfg: cfg, in: bufio.NewReader(os.Stdin), out: os.Stdout}
+	if err := s.serve(); err != nil && err != io.EOF {
+		log.Errorf("lsp: %v", err)
+		os.Exit(1)
+	}
+}
+
+type lspServer struct {
+	cfg      *Config
+	in       *bufio.Reader
+	out      io.Writer
+	shutdown bool
+}
+
+// serve handles messages until the client sends exit or closes stdin
+func (s *lspServer) serve() error {
+	for {
+		msg, err := s.read()
+		if err != nil {
+			return err
+		}
+
+		switch msg.Method {
+		case "initialize":
+			s.reply(msg.ID, map[string]any{
+				"capabilities": map[string]any{
+					// Full document sync: every change sends the whole text
+					"textDocumentSync": map[string]any{"openClose": true, "change": 1, "save": true},
+				},
+				"serverInfo": map[string]string{"name": "slate"},
+			})
+		case "shutdown":
+			s.shutdown = true
+			s.reply(msg.ID, nil)
+		case "exit":
+			if !s.shutdown {
+				os.Exit(1)
+			}
+			return nil
+		case "textDocument/didOpen", "textDocument/didChange", "textDocument/didSave":
+			var doc lspDocument
+			if err := json.Unmarshal(msg.Params, &doc); err != nil {
+				log.Warnf("lsp: %s: %v", msg.Method, err)
+				continue
+			}
+			text := doc.TextDocument.Text
+			if n := len(doc.ContentChanges); n > 0 {
+				text = doc.ContentChanges[n-1].Text
+			} else if msg.Method == "textDocument/didSave" {
+				// Saves without text are checked against the file on disk
+				data, err := os.ReadFile(uriToPath(doc.TextDocument.URI))
+				if err != nil {
+					continue
+				}
+				text = string(data)
+			}
+			s.publish(doc.TextDocument.URI, s.diagnose(doc.TextDocument.URI, []byte(text)))
+		case "textDocument/didClose":
+			var doc lspDocument
+			if err := json.Unmarshal(msg.Params, &doc); err == nil {
+				s.publish(doc.TextDocument.URI, []lspDiagnostic{})
+			}
+		default:
+			// Requests need an answer, unknown notifications are ignored
+			if msg.ID != nil {
+				s.send(lspMessage{ID: msg.ID, Error: &lspError{Code: -32601, Message: "method not found: " + msg.Method}})
+			}
+		}
+	}
+}
+
+// read reads one Content-Length framed message
+func (s *lspServer) read() (lspMessage, error) {
+	var msg lspMessage
+	length := -1
+	for {
+		line, err := s.in.ReadString('\n')
+		if err != nil {
+			return msg, err
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+		if value, ok := strings.CutPrefix(line, "Content-Length:"); ok {
+			length, err = strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				return msg, fmt.Errorf("invalid Content-Length %q", value)
+			}
+		}
+	}
+	if length < 0 {
+		return msg, fmt.Errorf("message without Content-Length")
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(s.in, body); err != nil {
+		return msg, err
+	}
+	return msg, json.Unmarshal(body, &msg)
+}
+
+func (s *lspServer) send(msg lspMessage) {
+	msg.JSONRPC = "2.0"
+	body, _ := json.Marshal(msg)
+	fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(body), body)
+}
+
+func (s *lspServer) reply(id json.RawMessage, result any) {
+	if result == nil {
+		// A null result must still be sent, which omitempty would drop
+		body := fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,"result":null}`, id)
+		fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(body), body)
+		return
+	}
+	s.send(lspMessage{ID: id, Result: result})
+}
+
+func (s *lspServer) publish(uri string, diagnostics []lspDiagnostic) {
+	params, _ := json.Marshal(map[string]any{"uri": uri, "diagnostics": diagnostics})
+	s.send(lspMessage{Method: "textDocument/publishDiagnostics", Params: params})
+}
+
+// diagnose checks a markdown document the same way the build does
+func (s *lspServer) diagnose(uri string, content []byte) []lspDiagnostic {
+	file := uriToPath(uri)
+	if wd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(wd, file); err == nil {
+			file = filepath.ToSlash(rel)
+		}
+	}
+	if !strings.HasSuffix(file, ".md") {
+		return []lspDiagnostic{}
+	}
+
+	diagnostics := []lspDiagnostic{}
+	lines := strings.Split(string(content), "\n")
+	lineDiagnostic := func(line, severity int, msg string) {
+		// LSP lines start at 0, issue lines at 1
+		line = min(max(line-1, 0), len(lines)-1)
+		diagnostics = append(diagnostics, lspDiagnostic{
+			Range: lspRange{
+				Start: lspPosition{Line: line},
+				End:   lspPosition{Line: line, Character: len(lines[line])},
+			},
+			Severity: severity,
+			Source:   "slate",
+			Message:  msg,
+		})
+	}
+
+	for _, issue := range lintFrontmatter(content) {
+		lineDiagnostic(issue.Line, severityWarning, issue.Msg)
+	}
+
+	fm, _, _ := parseFrontmatter(content)
+	loc, err := s.cfg.pageLocation(fm)
+	if err != nil {
+		lineDiagnostic(frontmatterKeyLine(lines, "timezone"), severityWarning, err.Error())
+		loc = s.cfg.location()
+	}
+	if fm.Date != "" {
+		if _, err := parseDate(fm.Date, loc); err != nil {
+			lineDiagnostic(frontmatterKeyLine(lines, "date"), severityWarning, err.Error())
+		}
+	}
+
+	for _, issue := range auditAltText(file, content) {
+		line := issue.Line - 1
+		diagnostics = append(diagnostics, lspDiagnostic{
+			Range: lspRange{
+				Start: lspPosition{Line: line, Character: issue.Column},
+				End:   lspPosition{Line: line, Character: len(lines[line])},
+			},
+			Severity: severityWarning,
+			Source:   "slate",
+			Message:  issue.Msg,
+		})
+	}
+
+	// Links to markdown files must point at existing content, and shortcodes
+	// must exist
+	for i, line := range lines {
+		for _, m := range shortcodePattern.FindAllStringSubmatchIndex(line, -1) {
+			name := line[m[2]:m[3]]
+			if _, ok := shortcodes[name]; ok {
+				continue
+			}
+			diagnostics = append(diagnostics, lspDiagnostic{
+				Range: lspRange{
+					Start: lspPosition{Line: i, Character: m[0]},
+					End:   lspPosition{Line: i, Character: m[1]},
+				},
+				Severity: severityWarning,
+				Source:   "slate",
+				Message:  fmt.Sprintf("unknown shortcode %q", name),
+			})
+		}
+		for _, m := range markdownLinkPattern.FindAllStringSubmatchIndex(line, -1) {
+			dest := line[m[2]:m[3]]
+			target, _, ok := s.cfg.resolveMarkdownLink(file, dest)
+			if !ok {
+				continue
+			}
+			if _, err := os.Stat(target); err == nil {
+				continue
+			}
+			diagnostics = append(diagnostics, lspDiagnostic{
+				Range: lspRange{
+					Start: lspPosition{Line: i, Character: m[2]},
+					End:   lspPosition{Line: i, Character: m[3]},
+				},
+				Severity: severityError,
+				Source:   "slate",
+				Message:  fmt.Sprintf("broken link: %s does not exist", target),
+			})
+		}
+	}
+	return diagnostics
+}
+
+// frontmatterKeyLine returns the 1-based line a top-level frontmatter key is on
+func frontmatterKeyLine(lines []string, key string) int {
+	for i, line := range lines {
+		if strings.HasPrefix(line, key+":") {
+			return i + 1
+		}
+	}
+	return 1
+}
+
+// uriToPath converts a file:// URI into a local path
+func uriToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return filepath.FromSlash(u.Path)
+}
@@ -2,54 +2,186 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	stdhtml "html"
 	"html/template"
-	"io/fs"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"reflect"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/yuin/goldmark"
+	emoji "github.com/yuin/goldmark-emoji"
 	highlighting "github.com/yuin/goldmark-highlighting/v2"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/util"
 	"gopkg.in/yaml.v3"
 )
 
 type Page struct {
-	Path    string
-	URL     string
-	Title   string
-	Date    time.Time
-	Content template.HTML
+	Path        string
+	URL         string
+	Title       string
+	Description string
+	Summary     string // description, or the first paragraph as plain text
+	Date        time.Time
+	Lastmod     time.Time // last git commit touching the file, with enableGitInfo
+	GitAuthor   string    // author of that commit
+	Tags        []string
+	Categories  []Category
+	Authors     []Author
+	Series      *SeriesInfo // nil unless the page is part of a series
+	Popularity  int         // number of other pages linking here
+	OGImage     string      // social card URL, set when ogImages is enabled
+	Backlinks   []PageLink  // pages linking here, sorted by title
+	TOC         []TOCEntry  // headings, for sections with toc enabled
+	Aliases     []string    // old URLs redirecting here
+	Redirect    string      // URL the page redirects to, instead of rendering its content
+	Split       *SplitInfo  // nil unless the page is split into parts
+	Canonical   string      // URL to index instead of this page, e.g. the first part of a split page
+	Math        bool        // the content has math, so the page loads the math renderer
+	Mermaid     bool        // the content has diagrams for the Mermaid script to draw
+	Weight      int         // orders lists before the date, 0 if unset
+	Featured    bool        // a highlighted post
+	Unlisted    bool        // reachable only by its URL
+	NoIndex     bool        // kept out of search engines and the sitemap
+	NoFollow    bool        // links not followed by search engines
+	Layout      string      // template file rendering the page instead of the default, e.g. landing.html
+	Content     template.HTML
+
+	authorIDs  []string // from frontmatter, resolved into Authors
+	seriesName string
+	seriesPart int
+	split      bool
 }
 
+// listedBefore orders the pages of blog, tag, category and author lists:
+// pages with a weight first, lowest first, then the rest newest first
+func listedBefore(a, b Page) bool {
+	if a.Weight != b.Weight {
+		if a.Weight == 0 || b.Weight == 0 {
+			return b.Weight == 0
+		}
+		return a.Weight < b.Weight
+	}
+	return a.Date.After(b.Date)
+}
+
+// withoutUnlisted returns the pages that appear in lists
+func withoutUnlisted(pages []Page) []Page {
+	return slices.DeleteFunc(slices.Clone(pages), func(p Page) bool { return p.Unlisted })
+}
+
+// featuredPosts holds the blog posts marked featured, in list order, for
+// the featured template function
+var featuredPosts []Page
+
 type Frontmatter struct {
-	Title string `yaml:"title"`
-	Date  string `yaml:"date"`
+	Title       string   `yaml:"title"`
+	Description string   `yaml:"description"`
+	Date        string   `yaml:"date"`
+	Tags        []string `yaml:"tags"`
+	Categories  []string `yaml:"categories"` // nested with slashes, e.g. go/tooling
+	Author      string   `yaml:"author"`
+	Authors     []string `yaml:"authors"`
+	Status      string   `yaml:"status"` // editorial workflow state, e.g. draft or review
+	Series      string   `yaml:"series"`
+	SeriesPart  int      `yaml:"seriesPart"`
+	Aliases     []string `yaml:"aliases"`     // old URLs redirecting here, with hosting platforms
+	Redirect    string   `yaml:"redirect"`    // render as a redirect to this URL
+	Split       bool     `yaml:"split"`       // split into one page per h2 section
+	Timezone    string   `yaml:"timezone"`    // IANA zone of date, overriding the site timezone
+	Typographer *bool    `yaml:"typographer"` // smart punctuation, overriding the site setting
+	Weight      int      `yaml:"weight"`      // position in lists, lowest first, ahead of pages without one
+	Featured    bool     `yaml:"featured"`    // highlight the post, see the featured template function
+	Unlisted    bool     `yaml:"unlisted"`    // rendered, but left out of lists, feeds, the sitemap and search
+	NoIndex     bool     `yaml:"noindex"`     // ask search engines not to index the page
+	NoFollow    bool     `yaml:"nofollow"`    // ask search engines not to follow the page's links
+	Layout      string   `yaml:"layout"`      // template rendering the page, e.g. landing for templates/landing.html
+	Template    string   `yaml:"template"`    // same as layout
+
+	// Cascade sets default frontmatter for the other files in an _index.md's
+	// directory and below, see defaults.go
+	Cascade map[string]any `yaml:"cascade"`
 }
 
 func main() {
-	if len(os.Args) > 1 {
-		switch os.Args[1] {
+	args := parseGlobalFlags(os.Args[1:])
+
+	if len(args) > 0 {
+		switch args[0] {
 		case "init":
 			initProject()
 			return
 		case "build":
-			build()
+			buildCmd(args[1:])
+			return
+		case "check":
+			ok := false
+			if len(args) > 1 && args[1] == "alt" {
+				ok = altCmd(args[2:])
+			} else {
+				ok = runLinkCheck("")
+			}
+			if !ok {
+				os.Exit(1)
+			}
+			return
+		case "lint":
+			if !lintCmd(args[1:]) {
+				os.Exit(1)
+			}
 			return
 		case "serve":
-			serve()
+			serve(args[1:])
 			return
-		default:
-			fmt.Println("Unknown command:", os.Args[1])
-			fmt.Println("Usage: slate [init|build|serve]")
+		case "calendar":
+			calendarCmd(args[1:])
+			return
+		case "lsp":
+			lspCmd()
+			return
+		case "history":
+			historyCmd(args[1:])
+			return
+		case "deploy":
+			deployCmd(args[1:])
+			return
+		case "theme":
+			themeCmd(args[1:])
+			return
+		case "export":
+			exportCmd(args[1:])
 			return
+		case "restore":
+			restoreCmd(args[1:])
+			return
+		case "doctor":
+			doctorCmd(args[1:])
+			return
+		case "new":
+			newCmd(args[1:])
+			return
+		default:
+			log.Errorf("Unknown command: %s", args[0])
+			fmt.Println("Usage: slate [--quiet|--verbose|--log-json|--porcelain] [init|new|build|serve|check|lint|calendar|lsp|history|deploy|theme|export|restore|doctor]")
+			os.Exit(2)
 		}
 	} else {
 		// Default to build
-		build()
+		buildCmd(nil)
 	}
 }
 
@@ -59,15 +191,17 @@ func initProject() {
 		"content/blog",
 		"templates",
 		"static",
+		"archetypes",
 	}
 
 	// Create starter directories
 	for _, dir := range dirs {
 		if err := os.MkdirAll(dir, 0755); err != nil {
-			fmt.Println("Error creating directory:", err)
+			log.Errorf("creating directory: %v", err)
 			return
 		}
-		fmt.Println("Created:", dir+"/")
+		log.Infof("Created: %s/", dir)
+		record("created", dir+"/")
 	}
 
 	// Create starter files
@@ -77,199 +211,1010 @@ func initProject() {
 		"templates/home.html":       starterHomeTemplate,
 		"templates/post.html":       starterPostTemplate,
 		"templates/blog_index.html": starterBlogIndexTemplate,
+		"templates/tag.html":        starterTagTemplate,
+		"templates/category.html":   starterCategoryTemplate,
+		"templates/author.html":     starterAuthorTemplate,
+		"templates/digest.html":     starterDigestTemplate,
+		"templates/series.html":     starterSeriesTemplate,
 		"static/styles.css":         starterCSS,
+		"slate.yaml":                starterConfig,
+		"archetypes/default.md":     defaultArchetype,
 	}
 
 	for path, content := range files {
 		// Don't overwrite existing files
 		if _, err := os.Stat(path); err == nil {
-			fmt.Println("Skipped (exists):", path)
+			log.Infof("Skipped (exists): %s", path)
+			record("skipped", path)
 			continue
 		}
 
 		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
-			fmt.Println("Error creating file:", err)
+			log.Errorf("creating file: %v", err)
 			return
 		}
-		fmt.Println("Created:", path)
+		log.Infof("Created: %s", path)
+		record("created", path)
 	}
 
-	fmt.Println("\nProject initialized! Run `slate build` to generate your site.")
+	log.Infof("\nProject initialized! Run `slate build` to generate your site.")
 }
 
-func serve() {
-	// Check if public directory exists
-	if _, err := os.Stat("public"); os.IsNotExist(err) {
-		fmt.Println("Missing public/ directory. Did you run 'slate build'?")
+func serve(args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	prod := flags.Bool("prod", false, "serve as a production web server with caching, compression and JSON access logs")
+	addr := flags.String("addr", "", "address for --prod to listen on, default :$PORT or :8080")
+	var output string
+	flags.StringVar(&output, "o", "", "serve this directory instead of the output directory")
+	flags.StringVar(&output, "output", "", "same as -o")
+	flags.Parse(args)
+
+	cfg, err := loadConfig()
+	if err != nil {
+		log.Errorf("loading %s: %v", configFile, err)
+		return
+	}
+	if output != "" {
+		cfg.Dirs.Output = output
+	}
+
+	// Check if the output directory exists
+	if _, err := os.Stat(cfg.outputDir()); os.IsNotExist(err) {
+		log.Errorf("Missing %s/ directory. Did you run 'slate build'?", cfg.outputDir())
+		return
+	}
+
+	if *prod {
+		if *addr == "" {
+			*addr = ":8080"
+			if port := os.Getenv("PORT"); port != "" {
+				*addr = ":" + port
+			}
+		}
+		// Container platforms collect structured logs
+		log.json = true
+		if err := serveProd(cfg, *addr); err != nil {
+			log.Errorf("server: %v", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if err := registerMIMETypes(mimeTypes(cfg)); err != nil {
+		log.Errorf("registering MIME types: %v", err)
 		return
 	}
 
 	port := "8080"
-	fmt.Printf("Serving public/ at http://localhost:%s\n", port)
-	fmt.Println("Press Ctrl+C to stop")
+	log.Infof("Serving %s/ at http://localhost:%s", cfg.outputDir(), port)
+	log.Infof("Press Ctrl+C to stop")
 
-	// Serve files from public/
-	http.Handle("/", http.FileServer(http.Dir("public")))
+	// Serve files from the output directory
+	http.Handle("/", http.FileServer(http.Dir(cfg.outputDir())))
 	if err := http.ListenAndServe(":"+port, nil); err != nil {
-		fmt.Println("Server error:", err)
+		log.Errorf("server: %v", err)
+	}
+}
+
+// buildCmd parses the build flags and runs the build, exiting non-zero on failure
+func buildCmd(args []string) {
+	flags := flag.NewFlagSet("build", flag.ExitOnError)
+	checkLinks := flags.Bool("check-links", false, "verify internal links in the output after building")
+	strict := flags.Bool("strict", false, "fail the build on warnings")
+	retryFailed := flags.Bool("retry-failed", false, "only reprocess the files listed in "+failedJournalFile)
+	eventsJSON := flags.Bool("events-json", false, "stream build events to stdout as newline-delimited JSON")
+	diff := flags.Bool("diff", false, "list the output files created, updated and deleted since the previous build")
+	var output string
+	flags.StringVar(&output, "o", "", "write the site to this directory instead of the output directory")
+	flags.StringVar(&output, "output", "", "same as -o")
+	flags.Parse(args)
+
+	// Events own stdout, so human-readable output moves to stderr
+	if *eventsJSON && porcelainOut != nil {
+		log.Errorf("--events-json and --porcelain both write to stdout, use one")
+		os.Exit(2)
+	}
+	if *eventsJSON {
+		buildEvents = newEventStream(os.Stdout)
+		log.out = os.Stderr
+	}
+
+	// Ctrl+C cancels the build between files instead of killing it mid-write
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	start := time.Now()
+	buildEvents.Start()
+	err := build(ctx, buildOptions{Strict: *strict, RetryFailed: *retryFailed, Output: output, Diff: *diff})
+	if errors.Is(err, context.Canceled) {
+		err = errors.New("interrupted")
+	}
+	if err != nil {
+		log.Errorf("build failed: %v", err)
+	}
+	buildEvents.Done(err, log.warnings)
+	recordHistory("build", start, renderCount, err)
+	if err != nil {
+		stop()
+		os.Exit(1)
+	}
+	record("built", renderCount, log.warnings, time.Since(start).Milliseconds())
+
+	if *checkLinks && !runLinkCheck(output) {
+		os.Exit(1)
 	}
 }
 
-func build() {
+// buildOptions controls a single build
+type buildOptions struct {
+	Strict      bool   // treat warnings as errors
+	RetryFailed bool   // only reprocess files from the failure journal
+	Output      string // overrides the output directory, e.g. for a CI artifact
+	Diff        bool   // report the files changed since the previous build
+}
+
+// build generates the site into public/, followed by any variants
+// Cancelling ctx stops the build before the next file is processed
+func build(ctx context.Context, opts buildOptions) error {
+	log.warnings = 0
+	renderCount = 0
+
+	var err error
+	if buildTime, err = buildTimestamp(); err != nil {
+		return err
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("loading %s: %w", configFile, err)
+	}
+	if opts.Output != "" {
+		cfg.Dirs.Output = opts.Output
+	}
+
 	// Check if required directories exist
-	if _, err := os.Stat("content"); os.IsNotExist(err) {
-		fmt.Println("Missing content/ directory. Did you run `slate init`?")
-		return
+	if _, err := os.Stat(cfg.contentDir()); os.IsNotExist(err) {
+		return fmt.Errorf("missing %s/ directory. Did you run `slate init`?", cfg.contentDir())
 	}
-	if _, err := os.Stat("templates"); os.IsNotExist(err) {
-		fmt.Println("Missing templates/ directory. Did you run `slate init`?")
-		return
+	if _, err := os.Stat(cfg.templateDir()); os.IsNotExist(err) {
+		return fmt.Errorf("missing %s/ directory. Did you run `slate init`?", cfg.templateDir())
+	}
+	// The site written into its own sources would be read back next build
+	out, err := filepath.Abs(cfg.outputDir())
+	if err != nil {
+		return err
+	}
+	for _, dir := range []string{cfg.contentDir(), cfg.staticDir(), cfg.templateDir()} {
+		if abs, err := filepath.Abs(dir); err == nil && within(out, abs) {
+			return fmt.Errorf("the output directory %s/ is inside %s/", cfg.outputDir(), dir)
+		}
 	}
 
-	markdownFiles, err := findMarkdownFiles("content")
+	// A deploy waits for the build, and the build for a deploy's snapshot
+	lock, err := acquireOutputLock(ctx, "build")
 	if err != nil {
-		fmt.Println("Error finding markdown files:", err)
-		return
+		return err
+	}
+	defer lock.release()
+
+	if err := runHooks(cfg, "preBuild", cfg.Hooks.PreBuild); err != nil {
+		return err
+	}
+
+	// Modules are mounted once and shared by the variants
+	contentMounts, err = mountModules(cfg, true)
+	if err != nil {
+		return err
+	}
+
+	// Each output is built in a staging directory and synced into place
+	// once all of them built, so a failed build leaves the last site whole
+	outputs := []string{cfg.outputDir()}
+	defer clear(stagedOutputs)
+	if cfg.staging, err = stageOutput(cfg.outputDir(), opts.RetryFailed); err != nil {
+		return err
+	}
+	if err := buildSite(ctx, cfg, opts); err != nil {
+		return err
+	}
+
+	// Variants reuse the content with their own settings and output
+	if !opts.RetryFailed {
+		for _, v := range cfg.Variants {
+			variantCfg, err := cfg.variantConfig(v)
+			if err != nil {
+				return err
+			}
+			log.Infof("Building variant %s into %s/", v.Name, variantCfg.outputDir())
+			outputs = append(outputs, variantCfg.outputDir())
+			if variantCfg.staging, err = stageOutput(variantCfg.outputDir(), false); err != nil {
+				return err
+			}
+			if err := buildSite(ctx, variantCfg, opts); err != nil {
+				return fmt.Errorf("variant %s: %w", v.Name, err)
+			}
+		}
+	}
+
+	if opts.Strict && log.warnings > 0 {
+		return fmt.Errorf("%d warning(s) in strict mode", log.warnings)
+	}
+	// The last completed build tells the files slate wrote from others
+	previous := map[string]*outputManifest{}
+	trash := newTrashBatch(cfg, "build")
+	defer func() {
+		if err := trash.close(); err != nil {
+			log.Warnf("writing the trash: %v", err)
+		}
+	}()
+	for _, dir := range outputs {
+		manifest, err := readOutputManifest(dir)
+		if err != nil && !os.IsNotExist(err) {
+			log.Warnf("%v", err)
+		}
+		previous[dir] = manifest
+		if err := forgetOutputManifest(dir); err != nil {
+			return err
+		}
+		if err := syncOutput(stagingDir(dir), dir, manifest, trash); err != nil {
+			return fmt.Errorf("updating %s/: %w", dir, err)
+		}
+	}
+	if !opts.RetryFailed {
+		if err := pruneDerivedCache(); err != nil {
+			return fmt.Errorf("pruning %s: %w", derivedCacheDir, err)
+		}
+	}
+	if err := runHooks(cfg, "postBuild", cfg.Hooks.PostBuild); err != nil {
+		return err
+	}
+
+	// Deploys only take output recorded as complete
+	for i, dir := range outputs {
+		manifest, err := writeOutputManifest(dir)
+		if err != nil {
+			return fmt.Errorf("recording the build of %s/: %w", dir, err)
+		}
+		if i == 0 {
+			if err := manifest.save(buildManifestFile); err != nil {
+				return fmt.Errorf("writing %s: %w", buildManifestFile, err)
+			}
+		}
+		if opts.Diff {
+			reportOutputDiff(dir, previous[dir], manifest)
+		}
+	}
+	return nil
+}
+
+// buildSite generates one site, the main one or a variant, from cfg
+func buildSite(ctx context.Context, cfg *Config, opts buildOptions) error {
+	out := cfg.buildDir()
+	builtAssets = newAssetPipeline(out, cfg.staticDir(), cfg.Assets)
+	builtA11y = newA11yFixer(cfg.Accessibility)
+	builtOutbound = newOutboundPolicy(cfg)
+	builtPartials = newPartialCache()
+	builtDownloads = map[string]*Download{}
+
+	if _, err := os.Stat(cfg.templateDir()); os.IsNotExist(err) {
+		return fmt.Errorf("missing %s/ directory", cfg.templateDir())
+	}
+
+	markdownFiles, err := findContentFiles(cfg)
+	if err != nil {
+		return fmt.Errorf("finding markdown files: %w", err)
 	}
+	markdownFiles = slices.DeleteFunc(markdownFiles, cfg.excluded)
+
+	// Retrying renders just the pages that failed last time. Files that failed
+	// before rendering were missing from listing pages, so those need a full build.
+	retryOnly := false
+	if opts.RetryFailed && cfg.variant == nil {
+		failed, err := readFailureJournal()
+		if os.IsNotExist(err) {
+			return fmt.Errorf("no %s found, nothing to retry", failedJournalFile)
+		}
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", failedJournalFile, err)
+		}
 
-	fmt.Println("Found markdown files:")
+		retryOnly = true
+		retry := map[string]bool{}
+		for _, f := range failed {
+			retry[f.File] = true
+			if f.Stage != stageRender {
+				retryOnly = false
+			}
+		}
+		if retryOnly {
+			markdownFiles = slices.DeleteFunc(markdownFiles, func(file string) bool {
+				return !retry[file]
+			})
+			log.Infof("Retrying %d failed file(s)", len(markdownFiles))
+		} else {
+			log.Infof("Previous build failed before rendering or in listing pages, rebuilding everything")
+		}
+	}
+
+	log.Infof("Found %d markdown files", len(markdownFiles))
 	for _, file := range markdownFiles {
-		fmt.Println(" -", file)
+		log.Debugf(" - %s", file)
+		buildEvents.Discovered(file)
+	}
+
+	// Large sites show progress instead of a line per generated file
+	threshold := cfg.ProgressThreshold
+	if threshold == 0 {
+		threshold = defaultProgressThreshold
+	}
+	if threshold > 0 && len(markdownFiles) >= threshold {
+		buildProgress = newProgress()
+		defer func() {
+			buildProgress.Done()
+			buildProgress = nil
+		}()
 	}
 
-	pages, err := generateHtml(markdownFiles)
+	profiles, err := loadAuthors()
 	if err != nil {
-		fmt.Println("Error generating HTML:", err)
-		return
+		return fmt.Errorf("loading %s: %w", authorsFile, err)
 	}
 
-	homeTmpl, err := template.ParseFiles("templates/home.html")
+	buildProgress.Phase("convert", len(markdownFiles))
+	journal := &failureJournal{}
+	pages, err := generateHtml(ctx, cfg, profiles, markdownFiles, journal)
 	if err != nil {
-		fmt.Println("Error parsing home.html template:", err)
-		return
+		return fmt.Errorf("generating HTML: %w", err)
 	}
+	pages, redirects := splitRedirects(cfg, pages)
 
-	postTmpl, err := template.ParseFiles("templates/post.html")
+	homeTmpl, err := parseTemplate(cfg, "home.html")
 	if err != nil {
-		fmt.Println("Error parsing post.html template:", err)
-		return
+		return fmt.Errorf("parsing home.html template: %w", err)
 	}
 
-	blogIndexTmpl, err := template.ParseFiles("templates/blog_index.html")
+	postTmpl, err := parseTemplate(cfg, "post.html")
 	if err != nil {
-		fmt.Println("Error parsing blog index template:", err)
-		return
+		return fmt.Errorf("parsing post.html template: %w", err)
+	}
+
+	blogIndexTmpl, err := parseTemplate(cfg, "blog_index.html")
+	if err != nil {
+		return fmt.Errorf("parsing blog index template: %w", err)
+	}
+
+	// Stamp pages with their last commit
+	if cfg.EnableGitInfo {
+		commits, err := gitLastCommits(cfg.contentDir())
+		if err != nil {
+			log.Warnf("enableGitInfo: reading git history: %v", err)
+		}
+		for i := range pages {
+			commit := commits[filepath.ToSlash(pages[i].Path)]
+			pages[i].Lastmod = commit.Date
+			pages[i].GitAuthor = commit.Author
+		}
+	}
+
+	// Attach author profiles
+	for i := range pages {
+		pages[i].Authors = resolveAuthors(pages[i].authorIDs, profiles)
+	}
+
+	// Normalize tags so aliases and case variants share one taxonomy page
+	tags := newTagNormalizer(cfg.Tags)
+	for i := range pages {
+		pages[i].Tags = tags.normalizeAll(pages[i].Tags)
+	}
+
+	// Count inbound links so templates can surface the most referenced pages.
+	// Unlisted pages aren't linked back to, which would give them away.
+	links := buildLinkGraph(withoutUnlisted(pages))
+	titles := map[string]string{}
+	for _, page := range pages {
+		titles[page.URL] = page.Title
+	}
+	for i := range pages {
+		pages[i].Popularity = len(links.inbound[pages[i].URL])
+		for _, from := range links.inbound[pages[i].URL] {
+			pages[i].Backlinks = append(pages[i].Backlinks, PageLink{Title: titles[from], URL: from})
+		}
+		sort.Slice(pages[i].Backlinks, func(a, b int) bool {
+			return pages[i].Backlinks[a].Title < pages[i].Backlinks[b].Title
+		})
+		if pages[i].Popularity == 0 {
+			log.Debugf("%s: no other page links here", pages[i].Path)
+		}
+	}
+	popularPages = rankByPopularity(withoutUnlisted(pages))
+
+	// Link the parts of each series together
+	seriesPages := assignSeries(pages)
+
+	// Draw a social card for each post
+	if cfg.OGImages.Enabled {
+		cards, err := newOGCardRenderer(cfg.OGImages)
+		if err != nil {
+			return err
+		}
+		for i := range pages {
+			if !strings.Contains(pages[i].Path, "/blog/") {
+				continue
+			}
+			image := ogImagePath(pages[i].URL)
+			if err := cards.render(cfg.Title, pages[i], out+image); err != nil {
+				log.Warnf("%s: generating social card: %v", pages[i].Path, err)
+				continue
+			}
+			pages[i].OGImage = image
+		}
 	}
 
 	var blogPosts []Page
 	var homePage *Page
+	// Other pages are only rendered when they choose a layout, e.g. landing
+	// pages, index files at their directory's URL
+	var layoutPages []Page
 
 	for i, page := range pages {
-		if strings.HasSuffix(page.Path, "index.md") {
+		if cfg.isHomePage(page.Path) {
 			homePage = &pages[i]
-		} else if strings.Contains(page.Path, "/blog/") {
+		} else if strings.Contains(page.Path, "/blog/") && !isIndexFile(page.Path) {
 			blogPosts = append(blogPosts, page)
+			if page.Date.IsZero() {
+				log.Warnf("%s: missing date", page.Path)
+			}
+		} else if page.Layout != "" {
+			layoutPages = append(layoutPages, page)
+		}
+	}
+
+	// Two content files mapping to the same URL silently overwrite each other
+	seenURLs := map[string]string{}
+	for _, page := range pages {
+		if other, ok := seenURLs[page.URL]; ok {
+			log.Warnf("%s: duplicate URL %s (also generated by %s)", page.Path, page.URL, other)
+			continue
 		}
+		seenURLs[page.URL] = page.Path
 	}
 
-	// Sort blog posts by date, newest first
+	// Sort blog posts by weight, then newest first
 	sort.Slice(blogPosts, func(i, j int) bool {
-		return blogPosts[i].Date.After(blogPosts[j].Date)
+		return listedBefore(blogPosts[i], blogPosts[j])
 	})
+	// Unlisted posts are rendered but left out of everything listing posts
+	listedPosts := withoutUnlisted(blogPosts)
+	listedPages := withoutUnlisted(pages)
+	featuredPosts = slices.DeleteFunc(slices.Clone(listedPosts), func(p Page) bool { return !p.Featured })
+
+	buildProgress.Phase("render", len(blogPosts)+len(layoutPages)+1)
+	layouts := newPageLayouts(cfg)
+
+	for _, page := range redirects {
+		if err := renderPage(redirectTemplate, page, out+page.URL); err != nil {
+			journal.add(page.Path, stageRender, err)
+		}
+	}
 
 	if homePage != nil {
 		homePage.URL = "/index.html"
-		if err := renderPage(homeTmpl, *homePage, "public/index.html"); err != nil {
-			fmt.Println("Error rendering home page:", err)
-			return
+		tmpl, err := layouts.template(*homePage, homeTmpl)
+		if err == nil {
+			err = renderPage(tmpl, *homePage, out+"/index.html")
+		}
+		if err != nil {
+			journal.add(homePage.Path, stageRender, err)
 		}
 	}
 
 	// Render individual blog posts
+	// Every post URL, parts of split posts included
+	postURLs := postURLSet(blogPosts)
+	// The URLs of unlisted pages, kept out of Pagefind's index
+	var unlistedURLs []string
+	if homePage != nil && homePage.Unlisted {
+		unlistedURLs = append(unlistedURLs, homePage.URL)
+	}
 	for _, post := range blogPosts {
-		outputPath := "public" + post.URL
-		if err := renderPage(postTmpl, post, outputPath); err != nil {
-			fmt.Println("Error rendering blog post:", err)
-			return
+		if err := ctx.Err(); err != nil {
+			return err
 		}
+		tmpl, err := layouts.template(post, postTmpl)
+		if err != nil {
+			journal.add(post.Path, stageRender, err)
+			buildProgress.Step()
+			continue
+		}
+		for _, page := range splitPage(cfg, post) {
+			postURLs[page.URL] = true
+			if post.Unlisted {
+				unlistedURLs = append(unlistedURLs, page.URL)
+			}
+			if err := renderPage(tmpl, page, out+page.URL); err != nil {
+				journal.add(post.Path, stageRender, err)
+			}
+		}
+		buildProgress.Step()
+	}
+
+	for _, page := range layoutPages {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		// Critical CSS and budgets treat them like posts, as content pages
+		postURLs[page.URL] = true
+		if page.Unlisted {
+			unlistedURLs = append(unlistedURLs, page.URL)
+		}
+		tmpl, err := layouts.template(page, nil)
+		if err == nil {
+			err = renderPage(tmpl, page, out+page.URL)
+		}
+		if err != nil {
+			journal.add(page.Path, stageRender, err)
+		}
+		buildProgress.Step()
+	}
+
+	// The journal tracks the main site, variants rebuild from scratch. It's
+	// saved once the content pages rendered and again after the listings.
+	saveJournal := func() error {
+		if cfg.variant != nil {
+			return nil
+		}
+		if err := journal.save(); err != nil {
+			return fmt.Errorf("writing %s: %w", failedJournalFile, err)
+		}
+		return nil
+	}
+	if err := saveJournal(); err != nil {
+		return err
+	}
+	if retryOnly {
+		journal.summarize(true)
+		return journal.err()
+	}
+
+	// A listing page that fails is recorded under its template and the rest
+	// of the site still builds. Retrying it needs a full build.
+	listingFailed := func(template string, err error) {
+		journal.add(filepath.Join(cfg.templateDir(), template), stageListing, err)
 	}
 
 	// Render blog index
-	if err := renderBlogIndex(blogIndexTmpl, blogPosts); err != nil {
-		fmt.Println("Error rendering blog index:", err)
-		return
+	if err := renderBlogIndex(blogIndexTmpl, listedPosts, out+"/blog/index.html"); err != nil {
+		listingFailed("blog_index.html", fmt.Errorf("blog index: %w", err))
 	}
+	buildProgress.Step()
 
-	// Copy static files to public
-	if content, err := os.ReadFile("static/styles.css"); err == nil {
-		os.WriteFile("public/styles.css", content, 0644)
-		fmt.Println("Copied:", "public/styles.css")
+	// The sitemap, search index and taxonomy pages cover the pages that are
+	// rendered: the home page, blog posts and pages with their own layout
+	var listed []Page
+	if homePage != nil && !homePage.Unlisted {
+		listed = append(listed, *homePage)
 	}
-}
+	listed = append(listed, listedPosts...)
+	listed = append(listed, withoutUnlisted(layoutPages)...)
 
-func renderPage(tmpl *template.Template, page Page, outputPath string) error {
-	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
-		return err
+	// Render tag pages when the project has a tag template
+	if _, err := os.Stat(filepath.Join(cfg.templateDir(), "tag.html")); err == nil {
+		tagTmpl, err := parseTemplate(cfg, "tag.html")
+		if err != nil {
+			return fmt.Errorf("parsing tag template: %w", err)
+		}
+		for _, tagPage := range collectTagPages(listed) {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := renderPage(tagTmpl, tagPage, out+tagPage.URL+"index.html"); err != nil {
+				listingFailed("tag.html", fmt.Errorf("tag page %s: %w", tagPage.Name, err))
+			}
+		}
 	}
 
-	file, err := os.Create(outputPath)
-	if err != nil {
+	// Render fallback pages with their own template, or the post template so
+	// they match the rest of the site
+	if len(cfg.FallbackPages) > 0 {
+		fallbackTmpl := postTmpl
+		if _, err := os.Stat(filepath.Join(cfg.templateDir(), "fallback.html")); err == nil {
+			if fallbackTmpl, err = parseTemplate(cfg, "fallback.html"); err != nil {
+				return fmt.Errorf("parsing fallback template: %w", err)
+			}
+		}
+		fallbacks, err := fallbackPages(cfg)
+		if err != nil {
+			return fmt.Errorf("generating fallback pages: %w", err)
+		}
+		for _, page := range fallbacks {
+			if err := renderPage(fallbackTmpl, page, out+page.URL); err != nil {
+				listingFailed(fallbackTmpl.Name(), fmt.Errorf("%s page: %w", page.Path, err))
+			}
+		}
+	}
+
+	if cfg.Graph.Enabled {
+		if err := writeGraph(cfg, collectGraph(listedPages, links), out); err != nil {
+			return fmt.Errorf("writing link graph: %w", err)
+		}
+	}
+
+	if err := writeVerificationFiles(cfg, out); err != nil {
+		return fmt.Errorf("writing verification files: %w", err)
+	}
+
+	if err := writeRobotsTxt(cfg, out); err != nil {
+		return fmt.Errorf("writing robots.txt: %w", err)
+	}
+
+	// The sitemap needs absolute URLs, so it's only written with a baseURL
+	if cfg.BaseURL != "" {
+		if err := writeSitemap(cfg, listed, out+"/sitemap.xml"); err != nil {
+			return fmt.Errorf("writing sitemap: %w", err)
+		}
+	}
+
+	// Render category pages when the project has a category template
+	if _, err := os.Stat(filepath.Join(cfg.templateDir(), "category.html")); err == nil {
+		categoryTmpl, err := parseTemplate(cfg, "category.html")
+		if err != nil {
+			return fmt.Errorf("parsing category template: %w", err)
+		}
+		for _, categoryPage := range collectCategoryPages(listed) {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := renderPage(categoryTmpl, categoryPage, out+categoryPage.URL+"index.html"); err != nil {
+				listingFailed("category.html", fmt.Errorf("category page %s: %w", categoryPage.Path, err))
+			}
+		}
+	}
+
+	// Render author pages when the project has an author template
+	if _, err := os.Stat(filepath.Join(cfg.templateDir(), "author.html")); err == nil {
+		authorTmpl, err := parseTemplate(cfg, "author.html")
+		if err != nil {
+			return fmt.Errorf("parsing author template: %w", err)
+		}
+		for _, authorPage := range collectAuthorPages(listed) {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := renderPage(authorTmpl, authorPage, out+authorPage.URL+"index.html"); err != nil {
+				listingFailed("author.html", fmt.Errorf("author page %s: %w", authorPage.ID, err))
+			}
+		}
+	}
+
+	// Render series index pages when the project has a series template
+	if _, err := os.Stat(filepath.Join(cfg.templateDir(), "series.html")); err == nil {
+		seriesTmpl, err := parseTemplate(cfg, "series.html")
+		if err != nil {
+			return fmt.Errorf("parsing series template: %w", err)
+		}
+		for _, series := range seriesPages {
+			if err := renderPage(seriesTmpl, series, out+series.URL+"index.html"); err != nil {
+				listingFailed("series.html", fmt.Errorf("series %s: %w", series.Name, err))
+			}
+		}
+	}
+
+	// Render a page about each file linked through one
+	if err := renderDownloads(cfg, pages, out); err != nil {
 		return err
 	}
-	defer file.Close()
 
-	if err := tmpl.Execute(file, page); err != nil {
+	// Render periodic digests of blog posts
+	if cfg.Digest.Cadence != "" {
+		digests, err := collectDigests(cfg, listedPosts)
+		if err != nil {
+			return err
+		}
+		digestTmpl, err := parseTemplate(cfg, "digest.html")
+		if err != nil {
+			return fmt.Errorf("parsing digest template: %w", err)
+		}
+		for _, digest := range digests {
+			if err := renderPage(digestTmpl, digest, out+digest.URL+"index.html"); err != nil {
+				listingFailed("digest.html", fmt.Errorf("digest %s: %w", digest.Label, err))
+			}
+		}
+		if cfg.BaseURL != "" {
+			if err := writeDigestFeed(cfg, digests, out+"/digest/feed.xml"); err != nil {
+				return fmt.Errorf("writing digest feed: %w", err)
+			}
+		} else {
+			log.Warnf("digest feed skipped: set baseURL in %s", configFile)
+		}
+	}
+
+	if err := saveJournal(); err != nil {
 		return err
 	}
 
-	fmt.Println("Generated:", outputPath)
-	return nil
+	// Copy static files and page assets to public
+	if err := copyAssets(cfg, out); err != nil {
+		return fmt.Errorf("copying static files: %w", err)
+	}
+
+	if err := writeHostingConfig(cfg.Hosting, slices.Concat(pages, redirects), out); err != nil {
+		return fmt.Errorf("writing hosting config: %w", err)
+	}
+
+	if err := writeEditMetadata(cfg, pages, out); err != nil {
+		return fmt.Errorf("writing %s: %w", editMetadataFile, err)
+	}
+
+	// Subset fonts before stylesheets are inlined into pages
+	if err := subsetFonts(cfg.Fonts, out); err != nil {
+		return fmt.Errorf("subsetting fonts: %w", err)
+	}
+
+	if cfg.CriticalCSS {
+		if err := inlineCriticalCSS(out, postURLs); err != nil {
+			return fmt.Errorf("inlining critical CSS: %w", err)
+		}
+	}
+
+	// Index the finished site last so search covers every generated page
+	if err := buildSearchIndex(cfg, listed, unlistedURLs, out); err != nil {
+		return fmt.Errorf("building search index: %w", err)
+	}
+
+	if err := precompress(cfg.Compress, out); err != nil {
+		return fmt.Errorf("compressing output: %w", err)
+	}
+
+	if err := checkBudgets(cfg.Budgets, out, postURLs); err != nil {
+		return fmt.Errorf("page budgets: %w", err)
+	}
+
+	builtOutbound.report()
+	builtPartials.report()
+
+	journal.summarize(cfg.variant == nil)
+	return journal.err()
 }
 
-func renderBlogIndex(tmpl *template.Template, posts []Page) error {
-	outputPath := "public/blog/index.html"
+// renderCount is the number of files rendered by the running build
+var renderCount int
 
-	if err := os.MkdirAll("public/blog", 0755); err != nil {
+// buildTime is when the running build counts as made, for output that
+// depends on the time. SOURCE_DATE_EPOCH (seconds since 1970) fixes it, so
+// building the same sources twice gives byte-for-byte the same site.
+var buildTime = time.Now()
+
+// buildTimestamp returns the time a build starting now counts as made at
+func buildTimestamp() (time.Time, error) {
+	epoch := os.Getenv("SOURCE_DATE_EPOCH")
+	if epoch == "" {
+		return time.Now(), nil
+	}
+	seconds, err := strconv.ParseInt(epoch, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("SOURCE_DATE_EPOCH must be seconds since 1970, not %q", epoch)
+	}
+	return time.Unix(seconds, 0).UTC(), nil
+}
+
+// renderPage executes tmpl into outputPath
+// The page is written to a temporary file first and renamed into place, so
+// an error or interrupted build never leaves a half-written page behind
+func renderPage(tmpl *template.Template, data any, outputPath string) error {
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
 		return err
 	}
 
-	file, err := os.Create(outputPath)
+	templateDataTypes[tmpl.Name()] = reflect.TypeOf(data)
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return withTemplateContext(err)
+	}
+	html := builtA11y.fix(tmpl.Name(), buf.String())
+	builtOutbound.check(outputPath, html)
+
+	file, err := os.CreateTemp(filepath.Dir(outputPath), ".slate-*.tmp")
 	if err != nil {
 		return err
 	}
-	defer file.Close()
+	defer os.Remove(file.Name())
 
-	if err := tmpl.Execute(file, posts); err != nil {
+	if _, err := file.WriteString(html); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(file.Name(), 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(file.Name(), outputPath); err != nil {
 		return err
 	}
 
-	fmt.Println("Generated:", outputPath)
+	renderCount++
+	logGenerated(outputPath)
+	var source string
+	if page, ok := data.(Page); ok {
+		source = page.Path
+	}
+	buildEvents.Rendered(source, publishedPath(outputPath))
 	return nil
 }
 
-func generateHtml(markdownFiles []string) ([]Page, error) {
+// logGenerated reports a written file, demoted to debug output when the
+// build is large enough to show progress instead
+func logGenerated(outputPath string) {
+	outputPath = publishedPath(outputPath)
+	record("generated", outputPath)
+	if buildProgress != nil {
+		log.Debugf("Generated: %s", outputPath)
+		return
+	}
+	log.Infof("Generated: %s", outputPath)
+}
+
+func renderBlogIndex(tmpl *template.Template, posts []Page, outputPath string) error {
+	return renderPage(tmpl, posts, outputPath)
+}
+
+// generateHtml converts markdownFiles into pages
+// Files that can't be read or converted are recorded in journal and skipped
+func generateHtml(ctx context.Context, cfg *Config, profiles map[string]Author, markdownFiles []string, journal *failureJournal) ([]Page, error) {
+	// Wikilinks can point at any page, not just the ones being rebuilt
+	wiki, err := buildWikiIndex(cfg)
+	if err != nil {
+		return nil, err
+	}
+	attachments, err := buildAttachmentIndex(cfg)
+	if err != nil {
+		return nil, err
+	}
+	defaults, err := loadContentDefaults(cfg, markdownFiles)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create goldmark with syntax highlighting
-	gm := goldmark.New(
-		goldmark.WithExtensions(
-			highlighting.NewHighlighting(
-				highlighting.WithStyle("algol_nu"),
-			),
+	// Pipe tables everywhere, figures only number them in their sections
+	extensions := []goldmark.Extender{
+		highlighting.NewHighlighting(
+			highlighting.WithStyle("algol_nu"),
 		),
-	)
+		extension.Table,
+	}
+	if cfg.Emoji {
+		extensions = append(extensions, emoji.New(emoji.WithRenderingMethod(emoji.Unicode)))
+	}
+	nodeRenderers := []util.PrioritizedValue{
+		util.Prioritized(&figureRenderer{}, 500),
+		util.Prioritized(&rawHTMLRenderer{unsafe: cfg.Markup.UnsafeHTML}, 500),
+	}
+	inlineParsers := []util.PrioritizedValue{
+		// Ahead of the standard link parser, which also triggers on [
+		util.Prioritized(&wikiLinkParser{index: wiki, files: attachments}, 199),
+	}
+	var blockParsers []util.PrioritizedValue
+	var transformers []util.PrioritizedValue
+	if cfg.Downloads.enabled() {
+		files, err := downloadAssetIndex(cfg)
+		if err != nil {
+			return nil, err
+		}
+		// After markdown links are rewritten, so they aren't taken for files
+		transformers = append(transformers, util.Prioritized(&downloadTransformer{cfg: cfg, files: files}, 150))
+	}
+	if cfg.Mermaid.Enabled {
+		nodeRenderers = append(nodeRenderers, util.Prioritized(newMermaidRenderer(cfg.Mermaid), 500))
+		transformers = append(transformers, util.Prioritized(&mermaidTransformer{}, 50))
+	}
+	// $...$ and $$...$$ hold TeX math, otherwise a $ is just text
+	if cfg.Math.Enabled {
+		nodeRenderers = append(nodeRenderers, util.Prioritized(newMathRenderer(cfg.Math), 500))
+		inlineParsers = append(inlineParsers, util.Prioritized(&mathInlineParser{}, 150))
+		blockParsers = append(blockParsers, util.Prioritized(&mathBlockParser{}, 150))
+	}
+	newMarkdown := func(extensions ...goldmark.Extender) goldmark.Markdown {
+		return goldmark.New(
+			goldmark.WithExtensions(extensions...),
+			goldmark.WithRendererOptions(
+				renderer.WithNodeRenderers(nodeRenderers...),
+			),
+			goldmark.WithParserOptions(
+				// Headings get ids so they can be linked to and listed in the TOC
+				parser.WithAutoHeadingID(),
+				parser.WithASTTransformers(
+					util.Prioritized(&mdLinkTransformer{cfg: cfg}, 100),
+					util.Prioritized(&tocTransformer{}, 200),
+					util.Prioritized(&figureTransformer{cfg: cfg}, 300),
+				),
+				parser.WithASTTransformers(transformers...),
+				parser.WithInlineParsers(inlineParsers...),
+				parser.WithBlockParsers(blockParsers...),
+			),
+		)
+	}
+	gm := newMarkdown(extensions...)
+	// Curly quotes, dashes and ellipses, for the site or a page
+	smartGM := newMarkdown(append(slices.Clone(extensions), extension.Typographer)...)
 
 	var pages []Page
 	for _, file := range markdownFiles {
-		content, err := os.ReadFile(file)
-		if err != nil {
+		if err := ctx.Err(); err != nil {
 			return nil, err
 		}
 
-		// Parse frontmatter and get remaining markdown
-		fm, markdown := parseFrontmatter(content)
+		content, err := readContent(file)
+		if err != nil {
+			journal.add(file, stageRead, err)
+			continue
+		}
+
+		// Report frontmatter problems instead of silently dropping metadata
+		for _, issue := range lintFrontmatter(content) {
+			log.Warnf("%s:%d: %s", file, issue.Line, issue.Msg)
+		}
+
+		// Parse frontmatter over the directory defaults and get remaining markdown
+		base, err := defaults.frontmatter(cfg, file)
+		if err != nil {
+			journal.add(file, stageRead, err)
+			continue
+		}
+		// A page's layout replaces its directory's, whether either says layout
+		// or template
+		layout := pageLayout(base)
+		base.Layout, base.Template = "", ""
+		fm, markdown, _ := parseFrontmatterOver(base, content)
+		if fm.Layout != "" && fm.Template != "" && pageLayout(fm) != pageLayout(Frontmatter{Layout: fm.Template}) {
+			log.Warnf("%s: layout %q and template %q disagree, using the layout", file, fm.Layout, fm.Template)
+		}
+		if own := pageLayout(fm); own != "" {
+			layout = own
+		}
+		if cfg.ExcludeDrafts && fm.Status == "draft" {
+			log.Debugf("%s: skipping draft", file)
+			continue
+		}
+
+		// Expose the source path to AST transformers
+		pc := parser.NewContext()
+		pc.Set(sourcePathKey, file)
 
 		var buf bytes.Buffer
-		if err := gm.Convert(markdown, &buf); err != nil {
-			return nil, err
+		if command, ok := cfg.contentFormat(file); ok {
+			// Other formats are converted by their own tools
+			html, err := convertContent(command, markdown)
+			if err != nil {
+				journal.add(file, stageConvert, err)
+				continue
+			}
+			// Hand-written HTML is trusted as much as HTML in markdown
+			if command == nil && !cfg.Markup.UnsafeHTML {
+				html = rawHTMLPolicy.SanitizeBytes(html)
+			}
+			buf.Write(html)
+		} else {
+			// Expand shortcodes and add the section's snippets
+			sc := shortcodeContext{
+				cfg:     cfg,
+				file:    file,
+				authors: resolveAuthors(append([]string{fm.Author}, fm.Authors...), profiles),
+			}
+			markdown = appendSnippets(sc, expandShortcodes(sc, markdown))
+
+			converter := gm
+			typographer := cfg.Typographer
+			if fm.Typographer != nil {
+				typographer = *fm.Typographer
+			}
+			if typographer {
+				converter = smartGM
+			}
+			if err := convertMarkdown(converter, markdown, &buf, parser.WithContext(pc)); err != nil {
+				journal.add(file, stageConvert, err)
+				continue
+			}
 		}
 
 		// Use frontmatter title if present, otherwise extract from filename
@@ -278,55 +1223,70 @@ func generateHtml(markdownFiles []string) ([]Page, error) {
 			title = extractTitle(file)
 		}
 
-		// Parse date from frontmatter
+		// Parse date from frontmatter in the page or site timezone
 		var date time.Time
 		if fm.Date != "" {
-			// Try parsing common date formats
-			date, _ = time.Parse("2006-01-02", fm.Date)
+			date, err = cfg.pageDate(fm)
+			if err != nil {
+				log.Warnf("%s: %v", file, err)
+			}
 		}
 
 		pages = append(pages, Page{
-			Path:    file,
-			URL:     pathToURL(file),
-			Title:   title,
-			Date:    date,
-			Content: template.HTML(buf.String()),
+			Path:        file,
+			URL:         cfg.pathToURL(file),
+			Title:       title,
+			Description: fm.Description,
+			Summary:     summarize(fm.Description, buf.String()),
+			Date:        date,
+			Tags:        fm.Tags,
+			Categories:  parseCategories(fm.Categories),
+			authorIDs:   append([]string{fm.Author}, fm.Authors...),
+			seriesName:  fm.Series,
+			seriesPart:  fm.SeriesPart,
+			split:       fm.Split,
+			TOC:         pageTOC(cfg.TOC, cfg.contentSection(file), tocEntries(pc)),
+			Aliases:     fm.Aliases,
+			Redirect:    fm.Redirect,
+			Math:        hasMath(pc),
+			Mermaid:     needsMermaid(buf.String()),
+			Weight:      fm.Weight,
+			Featured:    fm.Featured,
+			Unlisted:    fm.Unlisted,
+			NoIndex:     fm.NoIndex,
+			NoFollow:    fm.NoFollow,
+			Layout:      layout,
+			Content:     template.HTML(buf.String()),
 		})
+		buildProgress.Step()
 	}
 	return pages, nil
 }
 
-// findMarkdownFiles finds and returns all .md file paths
-func findMarkdownFiles(root string) ([]string, error) {
-	var files []string
-
-	// WalkDir traverses the directory tree rooted at "root"
-	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			fmt.Println("Warning: could not access", path, "-", err)
-			return nil
-		}
-
-		if d.IsDir() {
-			return nil
-		}
-
-		// Check if file ends with .md (case-insensitive)
-		if strings.HasSuffix(strings.ToLower(path), ".md") {
-			files = append(files, path)
+// convertMarkdown converts source with md, turning a panic in one of the
+// extensions into an error so a page it trips over fails on its own
+func convertMarkdown(md goldmark.Markdown, source []byte, buf *bytes.Buffer, opts ...parser.ParseOption) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("converting markdown: %v", r)
 		}
+	}()
+	return md.Convert(source, buf, opts...)
+}
 
-		return nil
+// findMarkdownFiles finds and returns all .md file paths, leaving out the
+// files and directories ignored reports, given their path relative to root
+func findMarkdownFiles(root string, ignored func(rel string, dir bool) bool) ([]string, error) {
+	return findSourceFiles(root, ignored, func(file string) bool {
+		return strings.HasSuffix(strings.ToLower(file), ".md")
 	})
-
-	return files, err
 }
 
 // extractTitle converts a file path to a readable title
 // e.g., "content/blog/my-first-post.md" → "My First Post"
 func extractTitle(path string) string {
 	base := filepath.Base(path)
-	name := strings.TrimSuffix(base, ".md")
+	name := strings.TrimSuffix(base, filepath.Ext(base))
 
 	// Replace underscores and hyphens with spaces
 	name = strings.ReplaceAll(name, "_", " ")
@@ -335,24 +1295,76 @@ func extractTitle(path string) string {
 	return strings.Title(name)
 }
 
+// summarize returns the description if set, otherwise the text of the first
+// paragraph of the rendered HTML
+func summarize(description, html string) string {
+	if description != "" {
+		return description
+	}
+
+	start := strings.Index(html, "<p>")
+	if start == -1 {
+		return ""
+	}
+	paragraph := html[start+len("<p>"):]
+	if end := strings.Index(paragraph, "</p>"); end != -1 {
+		paragraph = paragraph[:end]
+	}
+	return stripTags(paragraph)
+}
+
+// stripTags removes HTML tags and entities, collapsing whitespace
+func stripTags(s string) string {
+	var b strings.Builder
+	inTag := false
+	for _, r := range s {
+		switch {
+		case r == '<':
+			inTag = true
+		case r == '>':
+			inTag = false
+		case !inTag:
+			b.WriteRune(r)
+		}
+	}
+	return strings.Join(strings.Fields(stdhtml.UnescapeString(b.String())), " ")
+}
+
+// isHomePage reports whether path is the home page, content/index.md or
+// index.<ext> of another format
+func (c *Config) isHomePage(path string) bool {
+	return filepath.Clean(filepath.Dir(path)) == filepath.Clean(c.contentDir()) && isIndexFile(path)
+}
+
+// isIndexFile reports whether path is the index of its directory, rendered
+// at the directory's URL
+func isIndexFile(path string) bool {
+	return strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)) == "index"
+}
+
 // pathToURL converts a content path to a web URL
 // e.g., "content/blog/my-post.md" → "/blog/my-post.html"
-func pathToURL(path string) string {
-	// Remove "content" prefix and change extension
-	url := strings.TrimPrefix(path, "content")
-	url = strings.TrimSuffix(url, ".md") + ".html"
+func (c *Config) pathToURL(path string) string {
+	// Remove the content directory and change extension
+	url := strings.TrimPrefix(filepath.ToSlash(path), filepath.ToSlash(c.contentDir()))
+	url = strings.TrimSuffix(url, filepath.Ext(url)) + ".html"
 	return url
 }
 
 // parseFrontmatter extracts YAML frontmatter from markdown content
 // Frontmatter is delimited by --- at the start and end
 // Returns the parsed frontmatter and the remaining markdown content
-func parseFrontmatter(content []byte) (Frontmatter, []byte) {
-	var fm Frontmatter
+// A YAML error still returns the remaining markdown so the page can render
+func parseFrontmatter(content []byte) (Frontmatter, []byte, error) {
+	return parseFrontmatterOver(Frontmatter{}, content)
+}
 
+// parseFrontmatterOver parses content's frontmatter over the fields already
+// set in fm, so directory defaults apply where the page sets nothing
+func parseFrontmatterOver(fm Frontmatter, content []byte) (Frontmatter, []byte, error) {
 	// Check if content starts with ---
 	if !bytes.HasPrefix(content, []byte("---")) {
-		return fm, content
+		return fm, content, nil
 	}
 
 	// Find the closing ---
@@ -360,7 +1372,7 @@ func parseFrontmatter(content []byte) (Frontmatter, []byte) {
 	endIndex := bytes.Index(rest, []byte("\n---"))
 	if endIndex == -1 {
 		// No closing ---, return content as-is
-		return fm, content
+		return fm, content, nil
 	}
 
 	// Extract the YAML
@@ -369,7 +1381,7 @@ func parseFrontmatter(content []byte) (Frontmatter, []byte) {
 		yamlContent = yamlContent[1:]
 	}
 
-	yaml.Unmarshal(yamlContent, &fm)
+	err := yaml.Unmarshal(yamlContent, &fm)
 
 	// Return the content after the closing --- +4 to skip past "\n---" and +1 more to skip the newline after it
 	markdown := rest[endIndex+4:]
@@ -377,7 +1389,7 @@ func parseFrontmatter(content []byte) (Frontmatter, []byte) {
 		markdown = markdown[1:]
 	}
 
-	return fm, markdown
+	return fm, markdown, err
 }
 
 //
@@ -395,18 +1407,32 @@ Check out my [blog](/blog/).
 const starterBlogPost = `---
 title: Hello World
 date: 2025-01-17
+tags: [welcome]
 ---
 
 This is your first blog post. Edit this file or create new .md files in content/blog/.
 `
 
+const starterConfig = `title: My Site
+
+# Elements added to every page by the built-in {{template "head" .}} partial
+head:
+  # favicon: /favicon.ico
+  description: A site built with Slate
+  # meta:
+  #   author: Jane Doe
+  # snippets:
+  #   - <script defer src="https://example.com/analytics.js"></script>
+`
+
 const starterHomeTemplate = `<!DOCTYPE html>
 <html lang="en">
 <head>
     <meta charset="UTF-8">
     <meta name="viewport" content="width=device-width, initial-scale=1.0">
     <title>{{.Title}}</title>
-    <link rel="stylesheet" href="/styles.css">
+    <link rel="stylesheet" href="{{asset "styles.css"}}">
+    {{template "head" .}}
 </head>
 <body>
     <main>
@@ -422,7 +1448,8 @@ const starterPostTemplate = `<!DOCTYPE html>
     <meta charset="UTF-8">
     <meta name="viewport" content="width=device-width, initial-scale=1.0">
     <title>{{.Title}}</title>
-    <link rel="stylesheet" href="/styles.css">
+    <link rel="stylesheet" href="{{asset "styles.css"}}">
+    {{template "head" .}}
 </head>
 <body>
     <header>
@@ -434,7 +1461,22 @@ const starterPostTemplate = `<!DOCTYPE html>
     <main>
         <h1>{{.Title}}</h1>
         {{if not .Date.IsZero}}<p class="post-date">{{.Date.Format "January 2, 2006"}}</p>{{end}}
+        {{with .Series}}<p class="series-info">Part {{.Part}} of {{.Total}} in <a href="{{.URL}}">{{.Name}}</a></p>{{end}}
+        {{if .Authors}}<p class="post-authors">By {{range $i, $a := .Authors}}{{if $i}}, {{end}}<a href="{{$a.URL}}">{{$a.Name}}</a>{{end}}</p>{{end}}
+        {{template "toc" .}}
         {{.Content}}
+        {{template "parts" .}}
+        {{template "suggestEdit" .}}
+        {{with .Series}}{{if or .Prev .Next}}<nav class="series-nav">
+            {{with .Prev}}<a href="{{.URL}}">&larr; {{.Title}}</a>{{end}}
+            {{with .Next}}<a href="{{.URL}}">{{.Title}} &rarr;</a>{{end}}
+        </nav>{{end}}{{end}}
+        {{range .Categories}}<p class="post-category">{{range $i, $c := .Breadcrumbs}}{{if $i}} / {{end}}<a href="{{$c.URL}}">{{$c.Title}}</a>{{end}}</p>{{end}}
+        {{if .Tags}}<p class="post-tags">{{range .Tags}}<a href="{{tagURL .}}">#{{.}}</a> {{end}}</p>{{end}}
+        {{if .Backlinks}}<aside class="backlinks">
+            <h2>Linked from</h2>
+            <ul>{{range .Backlinks}}<li><a href="{{.URL}}">{{.Title}}</a></li>{{end}}</ul>
+        </aside>{{end}}
     </main>
 </body>
 </html>
@@ -446,7 +1488,8 @@ const starterBlogIndexTemplate = `<!DOCTYPE html>
     <meta charset="UTF-8">
     <meta name="viewport" content="width=device-width, initial-scale=1.0">
     <title>Posts</title>
-    <link rel="stylesheet" href="/styles.css">
+    <link rel="stylesheet" href="{{asset "styles.css"}}">
+    {{template "head" .}}
 </head>
 <body>
     <header>
@@ -469,6 +1512,165 @@ const starterBlogIndexTemplate = `<!DOCTYPE html>
 </html>
 `
 
+const starterTagTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Tagged: {{.Name}}</title>
+    <link rel="stylesheet" href="{{asset "styles.css"}}">
+    {{template "head" .}}
+</head>
+<body>
+    <header>
+        <nav>
+            <a href="/">Home</a>
+            <a href="/blog/">Blog</a>
+        </nav>
+    </header>
+    <main>
+        <h1>Tagged: {{.Name}}</h1>
+        <ul class="post-list">
+            {{range .Pages}}
+            <li>
+                <a href="{{.URL}}">{{.Title}}</a>
+                {{if not .Date.IsZero}}<span class="post-date">{{.Date.Format "Jan 2, 2006"}}</span>{{end}}
+            </li>
+            {{end}}
+        </ul>
+    </main>
+</body>
+</html>
+`
+
+const starterCategoryTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>{{.Name}}</title>
+    <link rel="stylesheet" href="{{asset "styles.css"}}">
+    {{template "head" .}}
+</head>
+<body>
+    <header>
+        <nav>
+            <a href="/">Home</a>
+            <a href="/blog/">Blog</a>
+        </nav>
+    </header>
+    <main>
+        <p class="breadcrumbs">{{range $i, $c := .Breadcrumbs}}{{if $i}} / {{end}}<a href="{{$c.URL}}">{{$c.Title}}</a>{{end}}</p>
+        <h1>{{.Name}}</h1>
+        {{if .Children}}
+        <ul>
+            {{range .Children}}<li><a href="{{.URL}}">{{.Name}}</a></li>{{end}}
+        </ul>
+        {{end}}
+        <ul class="post-list">
+            {{range .Pages}}
+            <li>
+                <a href="{{.URL}}">{{.Title}}</a>
+                {{if not .Date.IsZero}}<span class="post-date">{{.Date.Format "Jan 2, 2006"}}</span>{{end}}
+            </li>
+            {{end}}
+        </ul>
+    </main>
+</body>
+</html>
+`
+
+const starterAuthorTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>{{.Name}}</title>
+    <link rel="stylesheet" href="{{asset "styles.css"}}">
+    {{template "head" .}}
+</head>
+<body>
+    <header>
+        <nav>
+            <a href="/">Home</a>
+            <a href="/blog/">Blog</a>
+        </nav>
+    </header>
+    <main>
+        <h1>{{.Name}}</h1>
+        {{if .Bio}}<p>{{.Bio}}</p>{{end}}
+        {{if .Website}}<p><a href="{{.Website}}">{{.Website}}</a></p>{{end}}
+        <ul class="post-list">
+            {{range .Pages}}
+            <li>
+                <a href="{{.URL}}">{{.Title}}</a>
+                {{if not .Date.IsZero}}<span class="post-date">{{.Date.Format "Jan 2, 2006"}}</span>{{end}}
+            </li>
+            {{end}}
+        </ul>
+    </main>
+</body>
+</html>
+`
+
+const starterDigestTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>{{.Title}}</title>
+    <link rel="stylesheet" href="{{asset "styles.css"}}">
+    {{template "head" .}}
+</head>
+<body>
+    <header>
+        <nav>
+            <a href="/">Home</a>
+            <a href="/blog/">Blog</a>
+        </nav>
+    </header>
+    <main>
+        <h1>{{.Title}}</h1>
+        {{range .Pages}}
+        <article>
+            <h2><a href="{{.URL}}">{{.Title}}</a></h2>
+            <p class="post-date">{{.Date.Format "January 2, 2006"}}</p>
+            {{with .Summary}}<p>{{.}}</p>{{end}}
+        </article>
+        {{end}}
+    </main>
+</body>
+</html>
+`
+
+const starterSeriesTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>{{.Name}}</title>
+    <link rel="stylesheet" href="{{asset "styles.css"}}">
+    {{template "head" .}}
+</head>
+<body>
+    <header>
+        <nav>
+            <a href="/">Home</a>
+            <a href="/blog/">Blog</a>
+        </nav>
+    </header>
+    <main>
+        <h1>{{.Name}}</h1>
+        <ol class="series-list">
+            {{range .Pages}}
+            <li><a href="{{.URL}}">{{.Title}}</a></li>
+            {{end}}
+        </ol>
+    </main>
+</body>
+</html>
+`
+
 const starterCSS = `
 @import url('https://fonts.googleapis.com/css2?family=Inter:wght@400;500;600;700&display=swap');
 @import url('https://fonts.googleapis.com/css2?family=JetBrains+Mono:wght@400;500&display=swap');
@@ -516,12 +1718,109 @@ nav a:hover {
     color: #0066cc;
 }
 
+.skip-link {
+    position: absolute;
+    left: -9999px;
+}
+
+.skip-link:focus {
+    left: 1rem;
+    top: 1rem;
+    padding: 0.5rem 1rem;
+    background: #fff;
+    color: #0066cc;
+}
+
+.toc {
+    font-size: 0.9rem;
+    border-left: 2px solid #eee;
+    padding-left: 1rem;
+    margin-bottom: 2rem;
+}
+
+.toc ul {
+    list-style: none;
+    padding: 0;
+}
+
+.toc .toc-h3 {
+    padding-left: 1rem;
+}
+
+.toc a[aria-current] {
+    color: #0066cc;
+    font-weight: bold;
+}
+
+figure {
+    margin: 1.5rem 0;
+}
+
+figcaption, caption {
+    color: #666;
+    font-size: 0.9rem;
+    margin-top: 0.5rem;
+}
+
+.figure-label {
+    font-weight: bold;
+}
+
 .post-date {
     color: #666;
     font-size: 0.9rem;
     margin-bottom: 1.5rem;
 }
 
+.post-authors {
+    color: #666;
+    font-size: 0.9rem;
+}
+
+.series-info {
+    color: #666;
+    font-size: 0.9rem;
+}
+
+.series-nav {
+    display: flex;
+    justify-content: space-between;
+    margin: 2rem 0 1rem;
+}
+
+.page-parts {
+    border-top: 1px solid #eee;
+    margin: 2rem 0 1rem;
+    padding-top: 1rem;
+    font-size: 0.9rem;
+}
+
+.page-parts a[aria-current] {
+    font-weight: bold;
+}
+
+.offline-banner {
+    position: fixed;
+    bottom: 1rem;
+    left: 50%;
+    transform: translateX(-50%);
+    background: #333;
+    color: #fff;
+    padding: 0.5rem 1rem;
+    border-radius: 5px;
+    font-size: 0.9rem;
+}
+
+.breadcrumbs, .post-category {
+    color: #666;
+    font-size: 0.9rem;
+}
+
+.post-tags a {
+    margin-right: 0.5rem;
+    font-size: 0.9rem;
+}
+
 .post-list .post-date {
     margin-left: 0.5rem;
     margin-bottom: 0;
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestListedBefore(t *testing.T) {
+	older := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newer := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		a, b Page
+		want bool
+	}{
+		{"newer first", Page{Date: newer}, Page{Date: older}, true},
+		{"older after", Page{Date: older}, Page{Date: newer}, false},
+		{"same date", Page{Date: older}, Page{Date: older}, false},
+		{"weight before none", Page{Weight: 5, Date: older}, Page{Date: newer}, true},
+		{"none after weight", Page{Date: newer}, Page{Weight: 5, Date: older}, false},
+		{"lower weight first", Page{Weight: 1}, Page{Weight: 2}, true},
+		{"higher weight after", Page{Weight: 2}, Page{Weight: 1}, false},
+		{"same weight newer first", Page{Weight: 1, Date: newer}, Page{Weight: 1, Date: older}, true},
+		{"negative weight first", Page{Weight: -1}, Page{Weight: 1}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := listedBefore(tt.a, tt.b); got != tt.want {
+				t.Errorf("listedBefore() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsHomePage(t *testing.T) {
+	cfg := &Config{}
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"content/index.md", true},
+		{"content/index.html", true},
+		{"content/blog/index.md", false},
+		{"content/docs/index.md", false},
+		{"content/about.md", false},
+		{"content/reindex.md", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := cfg.isHomePage(tt.path); got != tt.want {
+				t.Errorf("isHomePage(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
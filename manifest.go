@@ -0,0 +1,59 @@
+package main
+
+import (
+	"path/filepath"
+	"time"
+)
+
+// buildManifestFile is the manifest of the main output's last completed
+// build, at a fixed path for tools purging CDN caches or diffing deploys
+var buildManifestFile = filepath.Join(".slate", "manifest.json")
+
+// outputChange is a file that differs between two builds of an output
+type outputChange struct {
+	Kind string // created, updated or deleted
+	Name string // slash-separated path in the output
+}
+
+// diffOutputManifests returns the files created, updated and deleted from
+// before to after, by name. A nil before is an empty output.
+func diffOutputManifests(before, after *outputManifest) []outputChange {
+	if before == nil {
+		before = &outputManifest{}
+	}
+	var changes []outputChange
+	for _, name := range sortedKeys(after.Files) {
+		old, ok := before.Files[name]
+		switch {
+		case !ok:
+			changes = append(changes, outputChange{Kind: "created", Name: name})
+		case old != after.Files[name]:
+			changes = append(changes, outputChange{Kind: "updated", Name: name})
+		}
+	}
+	for _, name := range sortedKeys(before.Files) {
+		if _, ok := after.Files[name]; !ok {
+			changes = append(changes, outputChange{Kind: "deleted", Name: name})
+		}
+	}
+	return changes
+}
+
+// reportOutputDiff lists how a build changed dir since the build before
+func reportOutputDiff(dir string, before, after *outputManifest) {
+	changes := diffOutputManifests(before, after)
+	counts := map[string]int{}
+	for _, c := range changes {
+		counts[c.Kind]++
+	}
+	since := "with no earlier build"
+	if before != nil {
+		since = "since the build of " + before.Built.Local().Format(time.DateTime)
+	}
+	log.Infof("%s/ %s: %d created, %d updated, %d deleted, %d unchanged", dir, since,
+		counts["created"], counts["updated"], counts["deleted"], len(after.Files)-counts["created"]-counts["updated"])
+	for _, c := range changes {
+		log.Infof("  %-7s %s", c.Kind, c.Name)
+		record("changed", c.Kind, dir, c.Name)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDiffOutputManifests(t *testing.T) {
+	tests := []struct {
+		name          string
+		before, after *outputManifest
+		want          []outputChange
+	}{
+		{"no earlier build", nil, &outputManifest{Files: map[string]string{"b.html": "1", "a.html": "2"}}, []outputChange{
+			{Kind: "created", Name: "a.html"},
+			{Kind: "created", Name: "b.html"},
+		}},
+		{"unchanged", &outputManifest{Files: map[string]string{"a.html": "1"}}, &outputManifest{Files: map[string]string{"a.html": "1"}}, nil},
+		{"updated", &outputManifest{Files: map[string]string{"a.html": "1"}}, &outputManifest{Files: map[string]string{"a.html": "2"}}, []outputChange{
+			{Kind: "updated", Name: "a.html"},
+		}},
+		{"deleted", &outputManifest{Files: map[string]string{"a.html": "1", "blog/old.html": "2"}}, &outputManifest{Files: map[string]string{"a.html": "1"}}, []outputChange{
+			{Kind: "deleted", Name: "blog/old.html"},
+		}},
+		{"all kinds", &outputManifest{Files: map[string]string{"a.html": "1", "b.html": "2"}}, &outputManifest{Files: map[string]string{"b.html": "3", "c.html": "4"}}, []outputChange{
+			{Kind: "updated", Name: "b.html"},
+			{Kind: "created", Name: "c.html"},
+			{Kind: "deleted", Name: "a.html"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := diffOutputManifests(tt.before, tt.after); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffOutputManifests() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// A post removed between two builds is reported as deleted by --diff and
+// leaves the output
+func TestBuildDiffDeletedPost(t *testing.T) {
+	t.Chdir(t.TempDir())
+	logOut, logErrOut := log.out, log.errOut
+	log.out, log.errOut = io.Discard, io.Discard
+	t.Cleanup(func() { log.out, log.errOut = logOut, logErrOut })
+	initProject()
+
+	post := filepath.Join("content", "blog", "second.md")
+	if err := os.WriteFile(post, []byte("---\ntitle: Second\ndate: 2025-01-02\n---\nBye\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := build(context.Background(), buildOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join("public", "blog", "second.html")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Remove(post); err != nil {
+		t.Fatal(err)
+	}
+	var records bytes.Buffer
+	porcelainOut = &records
+	t.Cleanup(func() { porcelainOut = nil })
+	if err := build(context.Background(), buildOptions{Diff: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(filepath.Join("public", "blog", "second.html")); !os.IsNotExist(err) {
+		t.Errorf("public/blog/second.html still exists after its post was removed (err %v)", err)
+	}
+	tests := []struct {
+		record string
+		want   bool
+	}{
+		{"changed\tdeleted\tpublic\tblog/second.html", true},
+		{"changed\tdeleted\tpublic\tblog/hello.html", false},
+		{"changed\tcreated\tpublic\tblog/second.html", false},
+	}
+	lines := strings.Split(records.String(), "\n")
+	for _, tt := range tests {
+		if got := slices.Contains(lines, tt.record); got != tt.want {
+			t.Errorf("record %q reported: %v, want %v\n%s", tt.record, got, tt.want, records.String())
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/util"
+)
+
+// MarkupConfig controls how markdown is rendered
+type MarkupConfig struct {
+	// UnsafeHTML renders HTML written in markdown as is. Otherwise it is
+	// sanitized: formatting stays, scripts, styles, event handlers and
+	// javascript: URLs go.
+	UnsafeHTML bool `yaml:"unsafeHTML"`
+}
+
+// rawHTMLPolicy is what sanitized HTML in markdown may keep: the elements
+// and attributes of user-generated content, the phrase elements writers use
+// inline, and class and id for styling and linking
+var rawHTMLPolicy = func() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowElements("kbd", "mark", "samp", "var", "abbr")
+	p.AllowAttrs("class", "id").Globally()
+	// The site's own links, unlike comments', don't need nofollow
+	p.RequireNoFollowOnLinks(false)
+	return p
+}()
+
+// closingTagPattern matches a closing tag, e.g. </a>
+var closingTagPattern = regexp.MustCompile(`^</\s*([a-zA-Z][a-zA-Z0-9-]*)\s*>$`)
+
+// openingTagPattern matches the name of an opening tag
+var openingTagPattern = regexp.MustCompile(`^<([a-zA-Z][a-zA-Z0-9-]*)`)
+
+// rawHTMLRenderer renders HTML blocks and inline HTML in markdown, as is or
+// sanitized. Inline HTML is sanitized a tag at a time, as goldmark parses it.
+type rawHTMLRenderer struct {
+	unsafe  bool
+	dropped map[string]int // inline tags dropped whose closing tag is still to come
+}
+
+func (r *rawHTMLRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindHTMLBlock, r.renderBlock)
+	reg.Register(ast.KindRawHTML, r.renderInline)
+}
+
+func (r *rawHTMLRenderer) renderBlock(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	n := node.(*ast.HTMLBlock)
+	var block bytes.Buffer
+	lines := n.Lines()
+	for i := range lines.Len() {
+		segment := lines.At(i)
+		block.Write(segment.Value(source))
+	}
+	if n.HasClosure() {
+		block.Write(n.ClosureLine.Value(source))
+	}
+	w.Write(r.sanitize(block.Bytes()))
+	return ast.WalkContinue, nil
+}
+
+func (r *rawHTMLRenderer) renderInline(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkSkipChildren, nil
+	}
+	n := node.(*ast.RawHTML)
+	var html bytes.Buffer
+	for i := range n.Segments.Len() {
+		segment := n.Segments.At(i)
+		html.Write(segment.Value(source))
+	}
+	if r.unsafe {
+		w.Write(html.Bytes())
+		return ast.WalkSkipChildren, nil
+	}
+
+	// A tag dropped on its own leaves its closing tag behind, which goes too
+	tag := bytes.TrimSpace(html.Bytes())
+	if m := closingTagPattern.FindSubmatch(tag); m != nil {
+		name := strings.ToLower(string(m[1]))
+		if r.dropped[name] > 0 {
+			r.dropped[name]--
+			return ast.WalkSkipChildren, nil
+		}
+	}
+	sanitized := r.sanitize(tag)
+	if m := openingTagPattern.FindSubmatch(tag); m != nil && len(sanitized) == 0 {
+		if r.dropped == nil {
+			r.dropped = map[string]int{}
+		}
+		r.dropped[strings.ToLower(string(m[1]))]++
+	}
+	w.Write(sanitized)
+	return ast.WalkSkipChildren, nil
+}
+
+func (r *rawHTMLRenderer) sanitize(html []byte) []byte {
+	if r.unsafe {
+		return html
+	}
+	return rawHTMLPolicy.SanitizeBytes(html)
+}
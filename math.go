@@ -0,0 +1,281 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"html/template"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// MathConfig renders $...$ and $$...$$ TeX math
+type MathConfig struct {
+	Enabled  bool   `yaml:"enabled"`
+	Renderer string `yaml:"renderer"` // katex (default) or mathjax, loaded by pages with math
+	// Server renders math to HTML during the build with the katex command,
+	// so pages only load KaTeX's stylesheet
+	Server bool `yaml:"server"`
+}
+
+// Versions of the math libraries loaded from jsDelivr
+const (
+	katexURL   = "https://cdn.jsdelivr.net/npm/katex@0.16.11/dist/"
+	mathjaxURL = "https://cdn.jsdelivr.net/npm/mathjax@3.2.2/es5/tex-chtml.js"
+)
+
+// mathKey records that the document being converted has math
+var mathKey = parser.NewContextKey()
+
+var (
+	kindMathInline = ast.NewNodeKind("MathInline")
+	kindMathBlock  = ast.NewNodeKind("MathBlock")
+)
+
+// mathInlineNode is $tex$, or $$tex$$ displayed within a paragraph
+type mathInlineNode struct {
+	ast.BaseInline
+	TeX     []byte
+	Display bool
+}
+
+func (n *mathInlineNode) Kind() ast.NodeKind { return kindMathInline }
+
+func (n *mathInlineNode) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"TeX": string(n.TeX)}, nil)
+}
+
+// mathBlockNode is a $$ block, its lines holding the TeX
+type mathBlockNode struct {
+	ast.BaseBlock
+	oneLine bool // $$ tex $$, complete once opened
+}
+
+func (n *mathBlockNode) Kind() ast.NodeKind { return kindMathBlock }
+
+func (n *mathBlockNode) IsRaw() bool { return true }
+
+func (n *mathBlockNode) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+// mathInlineParser parses $...$ and $$...$$ within a line. As in pandoc,
+// the opening $ can't be followed by a space and the closing one can't
+// follow a space or precede a digit, so "$5 and $10" stays text.
+type mathInlineParser struct{}
+
+func (p *mathInlineParser) Trigger() []byte {
+	return []byte{'$'}
+}
+
+func (p *mathInlineParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, _ := block.PeekLine()
+	delim := []byte("$")
+	if bytes.HasPrefix(line, []byte("$$")) {
+		delim = []byte("$$")
+	}
+	rest := line[len(delim):]
+	if len(rest) == 0 || rest[0] == ' ' || rest[0] == '\t' {
+		return nil
+	}
+	for i := 0; i+len(delim) <= len(rest); i++ {
+		if rest[i] == '\\' {
+			i++
+			continue
+		}
+		if !bytes.HasPrefix(rest[i:], delim) || i == 0 {
+			continue
+		}
+		if rest[i-1] == ' ' || rest[i-1] == '\t' || len(delim) == 1 && rest[i-1] == '$' {
+			continue
+		}
+		// A lone $ doesn't close before a digit or within $$
+		if after := rest[i+len(delim):]; len(delim) == 1 && len(after) > 0 && (after[0] == '$' || after[0] >= '0' && after[0] <= '9') {
+			i++
+			continue
+		}
+		block.Advance(len(delim) + i + len(delim))
+		pc.Set(mathKey, true)
+		return &mathInlineNode{TeX: append([]byte(nil), rest[:i]...), Display: len(delim) == 2}
+	}
+	return nil
+}
+
+// mathBlockParser parses math between lines holding only $$, or on one
+// line: $$ tex $$
+type mathBlockParser struct{}
+
+func (p *mathBlockParser) Trigger() []byte {
+	return []byte{'$'}
+}
+
+func (p *mathBlockParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	line, segment := reader.PeekLine()
+	trimmed := bytes.TrimSpace(line)
+	if !bytes.HasPrefix(trimmed, []byte("$$")) {
+		return nil, parser.NoChildren
+	}
+	node := &mathBlockNode{}
+	inner := bytes.TrimPrefix(trimmed, []byte("$$"))
+	if len(inner) > 0 {
+		// $$ tex $$ on one line, anything else after $$ is inline math
+		if !bytes.HasSuffix(inner, []byte("$$")) || len(inner) < 3 {
+			return nil, parser.NoChildren
+		}
+		start := segment.Start + bytes.Index(line, []byte("$$")) + 2
+		node.Lines().Append(text.NewSegment(start, start+len(inner)-2))
+		node.oneLine = true
+	}
+	pc.Set(mathKey, true)
+	reader.Advance(segment.Len() - 1)
+	return node, parser.NoChildren
+}
+
+func (p *mathBlockParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	if node.(*mathBlockNode).oneLine {
+		return parser.Close
+	}
+	line, segment := reader.PeekLine()
+	if bytes.Equal(bytes.TrimSpace(line), []byte("$$")) {
+		reader.Advance(segment.Len())
+		return parser.Close
+	}
+	node.Lines().Append(segment)
+	reader.Advance(segment.Len() - 1)
+	return parser.Continue | parser.NoChildren
+}
+
+func (p *mathBlockParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {}
+
+func (p *mathBlockParser) CanInterruptParagraph() bool { return true }
+
+func (p *mathBlockParser) CanAcceptIndentedLine() bool { return false }
+
+// mathRenderer writes math for KaTeX or MathJax to find in the page, with
+// the \( \) and \[ \] delimiters both read, or renders it with katex
+type mathRenderer struct {
+	cfg MathConfig
+
+	mu     sync.Mutex
+	cache  map[string]string // display flag and TeX → katex output
+	warned bool
+}
+
+func newMathRenderer(cfg MathConfig) *mathRenderer {
+	return &mathRenderer{cfg: cfg, cache: map[string]string{}}
+}
+
+func (r *mathRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(kindMathInline, r.renderInline)
+	reg.Register(kindMathBlock, r.renderBlock)
+}
+
+func (r *mathRenderer) renderInline(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		n := node.(*mathInlineNode)
+		class := "math math-inline"
+		if n.Display {
+			class = "math math-display"
+		}
+		fmt.Fprintf(w, "<span class=\"%s\">", class)
+		r.write(w, string(n.TeX), n.Display)
+		w.WriteString("</span>")
+	}
+	return ast.WalkSkipChildren, nil
+}
+
+func (r *mathRenderer) renderBlock(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		var tex strings.Builder
+		lines := node.Lines()
+		for i := range lines.Len() {
+			segment := lines.At(i)
+			tex.Write(segment.Value(source))
+		}
+		w.WriteString("<div class=\"math math-display\">")
+		r.write(w, strings.TrimSpace(tex.String()), true)
+		w.WriteString("</div>\n")
+	}
+	return ast.WalkSkipChildren, nil
+}
+
+func (r *mathRenderer) write(w util.BufWriter, tex string, display bool) {
+	if r.cfg.Server {
+		if out, ok := r.katex(tex, display); ok {
+			w.WriteString(out)
+			return
+		}
+	}
+	if display {
+		fmt.Fprintf(w, "\\[%s\\]", html.EscapeString(tex))
+	} else {
+		fmt.Fprintf(w, "\\(%s\\)", html.EscapeString(tex))
+	}
+}
+
+// katex renders tex to HTML with the katex command, reporting false to
+// leave it to the browser when the command is missing or fails
+func (r *mathRenderer) katex(tex string, display bool) (string, bool) {
+	key := fmt.Sprint(display, tex)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if out, ok := r.cache[key]; ok {
+		return out, true
+	}
+	if !katexInstalled() {
+		if !r.warned {
+			log.Warnf("math: the katex command isn't installed (npm install -g katex), rendering math in the browser")
+			r.warned = true
+		}
+		return "", false
+	}
+	args := []string{"--no-throw-on-error"}
+	if display {
+		args = append(args, "--display-mode")
+	}
+	cmd := exec.Command("katex", args...)
+	cmd.Stdin = strings.NewReader(tex)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		log.Warnf("math: katex failed on %q: %v %s", tex, err, strings.TrimSpace(stderr.String()))
+		return "", false
+	}
+	r.cache[key] = strings.TrimSpace(string(out))
+	return r.cache[key], true
+}
+
+// hasMath reports whether a document converted with pc has math
+func hasMath(pc parser.Context) bool {
+	found, _ := pc.Get(mathKey).(bool)
+	return found
+}
+
+func katexInstalled() bool {
+	_, err := exec.LookPath("katex")
+	return err == nil
+}
+
+// mathHead returns the stylesheet and scripts a page with math needs.
+// Math rendered during the build only needs KaTeX's stylesheet.
+func mathHead(cfg MathConfig) template.HTML {
+	server := cfg.Server && katexInstalled()
+	if cfg.Renderer == "mathjax" && !server {
+		return template.HTML(`<script defer src="` + mathjaxURL + `"></script>`)
+	}
+	head := `<link rel="stylesheet" href="` + katexURL + `katex.min.css">`
+	if !server {
+		head += `
+<script defer src="` + katexURL + `katex.min.js"></script>
+<script defer src="` + katexURL + `contrib/auto-render.min.js" onload="renderMathInElement(document.body, {delimiters: [{left: '\\[', right: '\\]', display: true}, {left: '\\(', right: '\\)', display: false}]})"></script>`
+	}
+	return template.HTML(head)
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"html/template"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// MermaidConfig renders ```mermaid code blocks as diagrams
+type MermaidConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Render is "client" (default), drawing diagrams in the browser with the
+	// Mermaid script, or "svg", rendering them to inline SVG during the build
+	Render string `yaml:"render"`
+	// Command renders a diagram to SVG, given the input and output files,
+	// "mmdc" from @mermaid-js/mermaid-cli by default
+	Command string `yaml:"command"`
+}
+
+const mermaidURL = "https://cdn.jsdelivr.net/npm/mermaid@11.4.1/dist/mermaid.esm.min.mjs"
+
+var kindMermaid = ast.NewNodeKind("Mermaid")
+
+// mermaidNode is a diagram, replacing its code block
+type mermaidNode struct {
+	ast.BaseBlock
+	Source []byte
+}
+
+func (n *mermaidNode) Kind() ast.NodeKind { return kindMermaid }
+
+func (n *mermaidNode) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Source": string(n.Source)}, nil)
+}
+
+// mermaidTransformer replaces ```mermaid code blocks with diagrams, ahead
+// of syntax highlighting
+type mermaidTransformer struct{}
+
+func (t *mermaidTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	source := reader.Source()
+
+	// Collect first, the tree can't change while it's walked
+	var blocks []*ast.FencedCodeBlock
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if block, ok := n.(*ast.FencedCodeBlock); ok && entering && string(block.Language(source)) == "mermaid" {
+			blocks = append(blocks, block)
+		}
+		return ast.WalkContinue, nil
+	})
+
+	for _, block := range blocks {
+		var diagram bytes.Buffer
+		lines := block.Lines()
+		for i := range lines.Len() {
+			segment := lines.At(i)
+			diagram.Write(segment.Value(source))
+		}
+		block.Parent().ReplaceChild(block.Parent(), block, &mermaidNode{Source: diagram.Bytes()})
+	}
+}
+
+// mermaidRenderer writes diagrams for the Mermaid script to draw, or as SVG
+type mermaidRenderer struct {
+	cfg MermaidConfig
+
+	svgs   map[string]string // diagram hash → SVG
+	warned bool
+}
+
+func newMermaidRenderer(cfg MermaidConfig) *mermaidRenderer {
+	return &mermaidRenderer{cfg: cfg, svgs: map[string]string{}}
+}
+
+func (r *mermaidRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(kindMermaid, r.render)
+}
+
+func (r *mermaidRenderer) render(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkSkipChildren, nil
+	}
+	diagram := node.(*mermaidNode).Source
+	if r.cfg.Render == "svg" {
+		if svg, ok := r.svg(diagram); ok {
+			fmt.Fprintf(w, "<figure class=\"mermaid\">%s</figure>\n", svg)
+			return ast.WalkSkipChildren, nil
+		}
+	}
+	fmt.Fprintf(w, "<pre class=\"mermaid\">%s</pre>\n", html.EscapeString(string(diagram)))
+	return ast.WalkSkipChildren, nil
+}
+
+// svg renders a diagram with the configured command, reporting false to
+// leave it to the browser when the command is missing or fails
+func (r *mermaidRenderer) svg(diagram []byte) (string, bool) {
+	sum := sha256.Sum256(diagram)
+	hash := hex.EncodeToString(sum[:4])
+	if svg, ok := r.svgs[hash]; ok {
+		return svg, true
+	}
+	command := r.cfg.command()
+	if _, err := exec.LookPath(command); err != nil {
+		if !r.warned {
+			log.Warnf("mermaid: %s isn't installed (npm install -g @mermaid-js/mermaid-cli), drawing diagrams in the browser", command)
+			r.warned = true
+		}
+		return "", false
+	}
+
+	dir, err := os.MkdirTemp("", "slate-mermaid-")
+	if err != nil {
+		log.Warnf("mermaid: %v", err)
+		return "", false
+	}
+	defer os.RemoveAll(dir)
+	input, output := filepath.Join(dir, "diagram.mmd"), filepath.Join(dir, "diagram.svg")
+	if err := os.WriteFile(input, diagram, 0644); err != nil {
+		log.Warnf("mermaid: %v", err)
+		return "", false
+	}
+	// A page can hold several diagrams, each needs its own id
+	cmd := exec.Command(command, "--input", input, "--output", output, "--svgId", "mermaid-"+hash)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		log.Warnf("mermaid: %s failed: %v %s", command, err, strings.TrimSpace(string(out)))
+		return "", false
+	}
+	svg, err := os.ReadFile(output)
+	if err != nil {
+		log.Warnf("mermaid: %v", err)
+		return "", false
+	}
+	r.svgs[hash] = strings.TrimSpace(string(svg))
+	return r.svgs[hash], true
+}
+
+func (c MermaidConfig) command() string {
+	if c.Command == "" {
+		return "mmdc"
+	}
+	return c.Command
+}
+
+// needsMermaid reports whether converted content has diagrams for the
+// Mermaid script to draw
+func needsMermaid(content string) bool {
+	return strings.Contains(content, `<pre class="mermaid">`)
+}
+
+// mermaidHead returns the script drawing a page's diagrams in the browser
+func mermaidHead() template.HTML {
+	return template.HTML(`<script type="module">
+import mermaid from "` + mermaidURL + `";
+mermaid.initialize({startOnLoad: true});
+</script>`)
+}
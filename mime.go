@@ -0,0 +1,95 @@
+package main
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// defaultMIMETypes maps file extensions to the Content-Type they are served
+// and uploaded with. Go's mime package falls back to the operating system's
+// tables, which differ between machines and miss newer types.
+var defaultMIMETypes = map[string]string{
+	".html":        "text/html; charset=utf-8",
+	".htm":         "text/html; charset=utf-8",
+	".css":         "text/css; charset=utf-8",
+	".js":          "text/javascript; charset=utf-8",
+	".mjs":         "text/javascript; charset=utf-8",
+	".json":        "application/json",
+	".map":         "application/json",
+	".xml":         "application/xml",
+	".rss":         "application/rss+xml",
+	".atom":        "application/atom+xml",
+	".txt":         "text/plain; charset=utf-8",
+	".md":          "text/markdown; charset=utf-8",
+	".csv":         "text/csv; charset=utf-8",
+	".ics":         "text/calendar; charset=utf-8",
+	".webmanifest": "application/manifest+json",
+	".wasm":        "application/wasm",
+	".pdf":         "application/pdf",
+	".zip":         "application/zip",
+	".svg":         "image/svg+xml",
+	".png":         "image/png",
+	".jpg":         "image/jpeg",
+	".jpeg":        "image/jpeg",
+	".gif":         "image/gif",
+	".webp":        "image/webp",
+	".avif":        "image/avif",
+	".ico":         "image/x-icon",
+	".woff":        "font/woff",
+	".woff2":       "font/woff2",
+	".ttf":         "font/ttf",
+	".otf":         "font/otf",
+	".mp3":         "audio/mpeg",
+	".ogg":         "audio/ogg",
+	".mp4":         "video/mp4",
+	".webm":        "video/webm",
+}
+
+// mimeTypes returns the built-in extension map with the overrides from the
+// mimeTypes block in slate.yaml applied
+func mimeTypes(cfg *Config) map[string]string {
+	types := make(map[string]string, len(defaultMIMETypes)+len(cfg.MIMETypes))
+	for ext, t := range defaultMIMETypes {
+		types[ext] = t
+	}
+	for ext, t := range cfg.MIMETypes {
+		ext = strings.ToLower(ext)
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		types[ext] = t
+	}
+	return types
+}
+
+// registerMIMETypes makes types visible to net/http's file server
+func registerMIMETypes(types map[string]string) error {
+	for ext, t := range types {
+		if err := mime.AddExtensionType(ext, t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// contentType returns the Content-Type for file, sniffing its first bytes
+// when the extension isn't in types
+func contentType(types map[string]string, file string) string {
+	if t, ok := types[strings.ToLower(filepath.Ext(file))]; ok {
+		return t
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return "application/octet-stream"
+	}
+	defer f.Close()
+
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(f, head)
+	return http.DetectContentType(head[:n])
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ContentModule mounts markdown from outside the project into content/,
+// e.g. legal pages shared by several sites
+type ContentModule struct {
+	Name  string `yaml:"name"`
+	Path  string `yaml:"path"`  // local directory, or
+	Git   string `yaml:"git"`   // repository URL, cloned into .slate/modules
+	Ref   string `yaml:"ref"`   // branch or tag, defaults to the remote's HEAD
+	Dir   string `yaml:"dir"`   // subdirectory of the source holding the content
+	Mount string `yaml:"mount"` // directory under content/, e.g. legal
+
+	// Frontmatter is applied over every mounted file's frontmatter
+	Frontmatter map[string]any `yaml:"frontmatter"`
+}
+
+// moduleCacheDir holds clones of git modules between builds
+var moduleCacheDir = filepath.Join(".slate", "modules")
+
+// mountedFile is a module file as seen under content/
+type mountedFile struct {
+	source  string
+	overlay map[string]any
+}
+
+// contentMounts maps the virtual content paths of module files, e.g.
+// content/legal/privacy.md, to their sources. Nil when no modules are used.
+var contentMounts map[string]mountedFile
+
+// mountModules resolves every module and CMS source and returns its files
+// by content path. Git modules are cloned or updated and CMS entries fetched
+// first when fetch is set; otherwise, or when the update fails, the last
+// copy is used.
+func mountModules(cfg *Config, fetch bool) (map[string]mountedFile, error) {
+	sources := cfg.contentSources()
+	if len(sources) == 0 {
+		return nil, nil
+	}
+
+	contentDir := filepath.ToSlash(cfg.contentDir())
+	mounts := map[string]mountedFile{}
+	for _, src := range sources {
+		root, err := src.root(fetch)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", src.label(), err)
+		}
+		files, err := findSourceFiles(root, cfg.ignored, cfg.isContentFile)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", src.label(), err)
+		}
+		for _, file := range files {
+			rel, err := filepath.Rel(root, file)
+			if err != nil {
+				return nil, err
+			}
+			virtual := path.Join(contentDir, strings.Trim(src.mountPoint(), "/"), filepath.ToSlash(rel))
+			if other, ok := mounts[virtual]; ok {
+				log.Warnf("%s: %s is also provided by %s, keeping the first", src.label(), virtual, other.source)
+				continue
+			}
+			mounts[virtual] = mountedFile{source: file, overlay: src.overlay()}
+		}
+		log.Debugf("Mounted %s: %d file(s) at %s", src.label(), len(files), path.Join(contentDir, src.mountPoint()))
+	}
+	return mounts, nil
+}
+
+func (m ContentModule) name() string {
+	switch {
+	case m.Name != "":
+		return m.Name
+	case m.Git != "":
+		return m.Git
+	default:
+		return m.Path
+	}
+}
+
+// root returns the local directory holding the module's content
+func (m ContentModule) root(fetch bool) (string, error) {
+	switch {
+	case m.Path != "" && m.Git != "":
+		return "", fmt.Errorf("set either path or git, not both")
+	case m.Path != "":
+		return filepath.Join(m.Path, m.Dir), nil
+	case m.Git != "":
+		dir := filepath.Join(moduleCacheDir, slugify(m.name()))
+		if err := syncGitModule(m, dir, fetch); err != nil {
+			return "", err
+		}
+		return filepath.Join(dir, m.Dir), nil
+	default:
+		return "", fmt.Errorf("missing path or git")
+	}
+}
+
+// syncGitModule makes dir a shallow clone of the module's ref
+func syncGitModule(m ContentModule, dir string, fetch bool) error {
+	_, err := os.Stat(filepath.Join(dir, ".git"))
+	cloned := err == nil
+	if cloned && !fetch {
+		return nil
+	}
+
+	var cmds [][]string
+	if cloned {
+		ref := m.Ref
+		if ref == "" {
+			ref = "HEAD"
+		}
+		cmds = [][]string{
+			{"git", "-C", dir, "fetch", "--depth", "1", "origin", ref},
+			{"git", "-C", dir, "reset", "--hard", "FETCH_HEAD"},
+		}
+	} else {
+		clone := []string{"git", "clone", "--depth", "1"}
+		if m.Ref != "" {
+			clone = append(clone, "--branch", m.Ref)
+		}
+		cmds = [][]string{append(clone, m.Git, dir)}
+	}
+
+	for _, args := range cmds {
+		out, err := exec.Command(args[0], args[1:]...).CombinedOutput()
+		if err == nil {
+			continue
+		}
+		msg := strings.TrimSpace(string(out))
+		if cloned {
+			log.Warnf("module %s: updating failed, using the last fetched copy: %s", m.name(), msg)
+			return nil
+		}
+		return fmt.Errorf("cloning %s: %s", m.Git, msg)
+	}
+	return nil
+}
+
+// findContentFiles returns the markdown files under content/ followed by
+// the mounted module files. A file in content/ takes precedence over a
+// module file at the same path, so sites can override single pages.
+// Section _index.md files aren't pages and are left out.
+func findContentFiles(cfg *Config) ([]string, error) {
+	files, err := findSourceFiles(cfg.contentDir(), cfg.ignored, func(file string) bool {
+		return cfg.isContentFile(file) && !isSectionIndex(file)
+	})
+	if err != nil {
+		return nil, err
+	}
+	local := map[string]bool{}
+	for _, file := range files {
+		local[filepath.ToSlash(file)] = true
+	}
+	for _, virtual := range sortedKeys(contentMounts) {
+		if isSectionIndex(virtual) {
+			continue
+		}
+		if local[virtual] {
+			log.Debugf("%s overrides the module file %s", virtual, contentMounts[virtual].source)
+			continue
+		}
+		files = append(files, filepath.FromSlash(virtual))
+	}
+	return files, nil
+}
+
+// readContent reads a content file, mounted or not, notebooks converted to
+// markdown, with its module's frontmatter overlay applied
+func readContent(file string) ([]byte, error) {
+	source := file
+	mounted, ok := contentMounts[filepath.ToSlash(file)]
+	if ok {
+		source = mounted.source
+	}
+	content, err := os.ReadFile(source)
+	if err != nil {
+		return nil, err
+	}
+	// Notebooks are read as the markdown they convert to
+	if isNotebook(file) {
+		if content, err = notebookMarkdown(content); err != nil {
+			return nil, err
+		}
+	}
+	if !ok || len(mounted.overlay) == 0 {
+		return content, nil
+	}
+	return overlayFrontmatter(content, mounted.overlay)
+}
+
+// overlayFrontmatter sets the overlay's keys in content's frontmatter,
+// adding a frontmatter block when there is none
+func overlayFrontmatter(content []byte, overlay map[string]any) ([]byte, error) {
+	fields := map[string]any{}
+	body := content
+	if bytes.HasPrefix(content, []byte("---")) {
+		if end := bytes.Index(content[3:], []byte("\n---")); end != -1 {
+			if err := yaml.Unmarshal(content[3:3+end], &fields); err != nil {
+				return nil, fmt.Errorf("frontmatter: %w", err)
+			}
+			body = bytes.TrimPrefix(content[3+end+4:], []byte("\n"))
+		}
+	}
+	if fields == nil {
+		fields = map[string]any{}
+	}
+	for _, key := range sortedKeys(overlay) {
+		fields[key] = overlay[key]
+	}
+
+	data, err := yaml.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	out := append([]byte("---\n"), data...)
+	out = append(out, "---\n"...)
+	return append(out, body...), nil
+}
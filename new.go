@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// archetypesDir holds the templates new content starts from
+const archetypesDir = "archetypes"
+
+// defaultArchetype is used when the project has no archetype for the
+// section nor archetypes/default.md
+const defaultArchetype = `---
+title: {{printf "%q" .Title}}
+date: {{.Date}}
+status: draft
+---
+
+`
+
+// archetypeData is what an archetype can use
+type archetypeData struct {
+	Title   string    // from the file name, e.g. My First Post
+	Slug    string    // e.g. my-first-post
+	Section string    // first directory under content/, e.g. blog
+	Date    string    // now, in the site timezone, as RFC 3339
+	Now     time.Time // for other formats, e.g. {{.Now.Format "January 2006"}}
+}
+
+// newCmd creates a content file from the archetype for its section
+// e.g., slate new blog/my-first-post → content/blog/my-first-post.md
+func newCmd(args []string) {
+	flags := flag.NewFlagSet("new", flag.ExitOnError)
+	kind := flags.String("kind", "", "archetype to use instead of the section's, e.g. recipe for archetypes/recipe.md")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		log.Errorf("Usage: slate new [--kind archetype] <section/name>")
+		os.Exit(2)
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		log.Errorf("loading %s: %v", configFile, err)
+		os.Exit(1)
+	}
+
+	file, content, err := newContent(cfg, flags.Arg(0), *kind, time.Now())
+	if err != nil {
+		log.Errorf("%v", err)
+		os.Exit(1)
+	}
+	if _, err := os.Stat(file); err == nil {
+		log.Errorf("%s already exists", file)
+		os.Exit(1)
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		log.Errorf("%v", err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(file, content, 0644); err != nil {
+		log.Errorf("%v", err)
+		os.Exit(1)
+	}
+	log.Infof("Created: %s", file)
+	record("created", file)
+}
+
+// newContent returns the path and content of a new file named by name,
+// relative to content/. A name without an extension gets .md, and its file
+// name is slugified, so "blog/My First Post" works too.
+func newContent(cfg *Config, name, kind string, now time.Time) (string, []byte, error) {
+	name = strings.Trim(filepath.ToSlash(name), "/")
+	dir, base := path.Split(name)
+	ext := path.Ext(base)
+	if ext == "" {
+		ext = ".md"
+	}
+	slug := slugify(strings.TrimSuffix(base, path.Ext(base)))
+	if slug == "" {
+		return "", nil, fmt.Errorf("%q has no file name", name)
+	}
+	file := filepath.Join(cfg.contentDir(), filepath.FromSlash(dir), slug+ext)
+
+	data := archetypeData{
+		Title:   extractTitle(base),
+		Slug:    slug,
+		Section: cfg.contentSection(file),
+		Now:     now.In(cfg.location()),
+	}
+	data.Date = data.Now.Format(time.RFC3339)
+
+	source, archetype, err := findArchetype(kind, data.Section, ext)
+	if err != nil {
+		return "", nil, err
+	}
+	tmpl, err := template.New(archetype).Parse(source)
+	if err != nil {
+		return "", nil, err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", nil, err
+	}
+	return file, buf.Bytes(), nil
+}
+
+// findArchetype returns the archetype named kind, or else the one for the
+// section, archetypes/default<ext> or the built-in one, with its name
+func findArchetype(kind, section, ext string) (string, string, error) {
+	if kind != "" {
+		name := kind
+		if path.Ext(name) == "" {
+			name += ext
+		}
+		data, err := os.ReadFile(filepath.Join(archetypesDir, name))
+		if err != nil {
+			return "", "", fmt.Errorf("archetype %s: %w", kind, err)
+		}
+		return string(data), name, nil
+	}
+	var names []string
+	if section != "" {
+		names = append(names, section+ext)
+	}
+	names = append(names, "default"+ext)
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(archetypesDir, name))
+		if err == nil {
+			return string(data), name, nil
+		}
+		if !os.IsNotExist(err) {
+			return "", "", err
+		}
+	}
+	return defaultArchetype, "default", nil
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// notebook is the part of a Jupyter notebook (nbformat 4) a page shows
+type notebook struct {
+	Cells    []notebookCell `json:"cells"`
+	Metadata struct {
+		Kernelspec struct {
+			Language string `json:"language"`
+		} `json:"kernelspec"`
+		LanguageInfo struct {
+			Name string `json:"name"`
+		} `json:"language_info"`
+	} `json:"metadata"`
+}
+
+type notebookCell struct {
+	Type        string                       `json:"cell_type"` // markdown, code or raw
+	Source      notebookText                 `json:"source"`
+	Outputs     []notebookOutput             `json:"outputs"`
+	Attachments map[string]map[string]string `json:"attachments"` // name → MIME type → base64
+}
+
+type notebookOutput struct {
+	Type      string                  `json:"output_type"` // stream, execute_result, display_data or error
+	Name      string                  `json:"name"`        // stdout or stderr, for streams
+	Text      notebookText            `json:"text"`
+	Data      map[string]notebookText `json:"data"` // MIME type → content
+	Traceback []string                `json:"traceback"`
+}
+
+// notebookText is multi-line text, stored as a string or a list of lines
+type notebookText string
+
+func (t *notebookText) UnmarshalJSON(data []byte) error {
+	var lines []string
+	if err := json.Unmarshal(data, &lines); err == nil {
+		*t = notebookText(strings.Join(lines, ""))
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	*t = notebookText(s)
+	return nil
+}
+
+// notebookImageTypes are the image outputs shown, as data URLs markdown
+// allows in images
+var notebookImageTypes = []string{"image/png", "image/jpeg", "image/gif", "image/webp"}
+
+// ansiPattern matches the terminal colors of tracebacks
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// attachmentPattern matches a markdown cell's reference to an attachment
+var attachmentPattern = regexp.MustCompile(`\(attachment:([^)\s]+)`)
+
+func isNotebook(file string) bool {
+	return strings.EqualFold(filepath.Ext(file), ".ipynb")
+}
+
+// notebookMarkdown converts a notebook to the markdown of its page: markdown
+// cells as they are, code cells as code blocks in the kernel's language,
+// followed by their outputs. A first cell holding only frontmatter, as
+// Quarto writes it, becomes the page's frontmatter.
+func notebookMarkdown(data []byte) ([]byte, error) {
+	var nb notebook
+	if err := json.Unmarshal(data, &nb); err != nil {
+		return nil, fmt.Errorf("reading notebook: %w", err)
+	}
+	language := nb.Metadata.LanguageInfo.Name
+	if language == "" {
+		language = nb.Metadata.Kernelspec.Language
+	}
+
+	var md bytes.Buffer
+	for i, cell := range nb.Cells {
+		source := string(cell.Source)
+		if i == 0 && cell.Type != "code" && isFrontmatterCell(source) {
+			md.WriteString(strings.TrimSpace(source) + "\n")
+			continue
+		}
+		switch cell.Type {
+		case "markdown":
+			source = attachmentPattern.ReplaceAllStringFunc(source, func(ref string) string {
+				name := attachmentPattern.FindStringSubmatch(ref)[1]
+				for _, typ := range notebookImageTypes {
+					if data, ok := cell.Attachments[name][typ]; ok {
+						return "(data:" + typ + ";base64," + strings.Join(strings.Fields(data), "")
+					}
+				}
+				return ref
+			})
+			md.WriteString(source + "\n\n")
+		case "code":
+			if strings.TrimSpace(source) == "" {
+				continue
+			}
+			fence := codeFence(source)
+			fmt.Fprintf(&md, "%s%s\n%s\n%s\n\n", fence, language, strings.TrimRight(source, "\n"), fence)
+			for _, output := range cell.Outputs {
+				writeNotebookOutput(&md, output)
+			}
+		}
+	}
+	return md.Bytes(), nil
+}
+
+// isFrontmatterCell reports whether a cell holds nothing but frontmatter
+func isFrontmatterCell(source string) bool {
+	source = strings.TrimSpace(source)
+	return strings.HasPrefix(source, "---\n") && strings.HasSuffix(source, "\n---")
+}
+
+// codeFence returns a fence longer than any run of backticks in source
+func codeFence(source string) string {
+	longest, run := 0, 0
+	for _, r := range source {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
+
+// writeNotebookOutput writes one output of a code cell, its richest
+// representation a page can show
+func writeNotebookOutput(md *bytes.Buffer, output notebookOutput) {
+	switch output.Type {
+	case "stream":
+		writeOutputText(md, "output "+output.Name, string(output.Text))
+	case "error":
+		writeOutputText(md, "output error", ansiPattern.ReplaceAllString(strings.Join(output.Traceback, "\n"), ""))
+	case "execute_result", "display_data":
+		if h, ok := output.Data["text/html"]; ok {
+			// An HTML block ends at a blank line
+			var lines []string
+			for _, line := range strings.Split(string(h), "\n") {
+				if strings.TrimSpace(line) != "" {
+					lines = append(lines, line)
+				}
+			}
+			fmt.Fprintf(md, "<div class=\"output\">\n%s\n</div>\n\n", strings.Join(lines, "\n"))
+			return
+		}
+		for _, typ := range notebookImageTypes {
+			if data, ok := output.Data[typ]; ok {
+				fmt.Fprintf(md, "![output](data:%s;base64,%s)\n\n", typ, strings.Join(strings.Fields(string(data)), ""))
+				return
+			}
+		}
+		if text, ok := output.Data["text/markdown"]; ok {
+			md.WriteString(string(text) + "\n\n")
+			return
+		}
+		if text, ok := output.Data["text/plain"]; ok {
+			writeOutputText(md, "output", string(text))
+		}
+	}
+}
+
+// writeOutputText writes text output as a preformatted block
+func writeOutputText(md *bytes.Buffer, class, text string) {
+	text = strings.TrimRight(text, "\n")
+	if text == "" {
+		return
+	}
+	fmt.Fprintf(md, "<pre class=\"%s\">%s</pre>\n\n", class, html.EscapeString(text))
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// Social card size recommended by the major platforms
+const (
+	ogImageWidth  = 1200
+	ogImageHeight = 630
+	ogImagePad    = 80
+)
+
+// OGImagesConfig generates a social card per post for og:image
+type OGImagesConfig struct {
+	Enabled    bool   `yaml:"enabled"`
+	Background string `yaml:"background"` // hex color, e.g. "#1a1a2e"
+	Color      string `yaml:"color"`      // text color
+}
+
+// ogImagePath returns where a page's social card is published
+// e.g., "/blog/hello.html" → "/og/blog/hello.png"
+func ogImagePath(pageURL string) string {
+	return "/og" + strings.TrimSuffix(pageURL, ".html") + ".png"
+}
+
+// ogCardRenderer draws social cards with the Go fonts
+type ogCardRenderer struct {
+	background color.Color
+	color      color.Color
+	title      font.Face
+	meta       font.Face
+}
+
+func newOGCardRenderer(cfg OGImagesConfig) (*ogCardRenderer, error) {
+	r := &ogCardRenderer{}
+	var err error
+	if r.background, err = parseHexColor(cfg.Background, color.RGBA{0x1a, 0x1a, 0x2e, 0xff}); err != nil {
+		return nil, fmt.Errorf("ogImages.background: %w", err)
+	}
+	if r.color, err = parseHexColor(cfg.Color, color.White); err != nil {
+		return nil, fmt.Errorf("ogImages.color: %w", err)
+	}
+	if r.title, err = loadFace(gobold.TTF, 64); err != nil {
+		return nil, err
+	}
+	if r.meta, err = loadFace(goregular.TTF, 32); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func loadFace(ttf []byte, size float64) (font.Face, error) {
+	f, err := opentype.Parse(ttf)
+	if err != nil {
+		return nil, err
+	}
+	return opentype.NewFace(f, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+}
+
+// render writes a card with the page title, site name and date as a PNG
+func (r *ogCardRenderer) render(site string, page Page, outputPath string) error {
+	img := image.NewRGBA(image.Rect(0, 0, ogImageWidth, ogImageHeight))
+	draw.Draw(img, img.Bounds(), image.NewUniform(r.background), image.Point{}, draw.Src)
+
+	d := &font.Drawer{Dst: img, Src: image.NewUniform(r.color)}
+
+	// Title from the top, wrapped to at most four lines
+	d.Face = r.title
+	lineHeight := r.title.Metrics().Height.Ceil() + 12
+	y := ogImagePad + r.title.Metrics().Ascent.Ceil()
+	for _, line := range wrapText(r.title, page.Title, ogImageWidth-2*ogImagePad, 4) {
+		d.Dot = fixed.P(ogImagePad, y)
+		d.DrawString(line)
+		y += lineHeight
+	}
+
+	// Site name and date along the bottom
+	d.Face = r.meta
+	meta := site
+	if !page.Date.IsZero() {
+		if meta != "" {
+			meta += " · "
+		}
+		meta += page.Date.Format("January 2, 2006")
+	}
+	d.Dot = fixed.P(ogImagePad, ogImageHeight-ogImagePad)
+	d.DrawString(meta)
+
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		return err
+	}
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(file, img); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	logGenerated(outputPath)
+	return nil
+}
+
+// wrapText breaks s into lines no wider than width, ending the last line
+// with an ellipsis when the text doesn't fit in maxLines
+func wrapText(face font.Face, s string, width, maxLines int) []string {
+	limit := fixed.I(width)
+	var lines []string
+	var line string
+	words := strings.Fields(s)
+	for i, word := range words {
+		candidate := strings.TrimSpace(line + " " + word)
+		if line == "" || font.MeasureString(face, candidate) <= limit {
+			line = candidate
+			continue
+		}
+		lines = append(lines, line)
+		line = word
+		if len(lines) == maxLines-1 {
+			// Everything left goes on the last line, trimmed to fit
+			rest := words[i:]
+			if font.MeasureString(face, strings.Join(rest, " ")) <= limit {
+				return append(lines, strings.Join(rest, " "))
+			}
+			for len(rest) > 1 && font.MeasureString(face, strings.Join(rest, " ")+"…") > limit {
+				rest = rest[:len(rest)-1]
+			}
+			return append(lines, strings.Join(rest, " ")+"…")
+		}
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// parseHexColor parses "#rgb" or "#rrggbb", returning def for an empty value
+func parseHexColor(s string, def color.Color) (color.Color, error) {
+	if s == "" {
+		return def, nil
+	}
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) != 6 {
+		return nil, fmt.Errorf("invalid color %q", s)
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid color %q", s)
+	}
+	return color.RGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 0xff}, nil
+}
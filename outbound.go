@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// OutboundConfig restricts the external domains pages may reference
+type OutboundConfig struct {
+	// Allow lists the domains links, embeds and assets may point at
+	// e.g. github.com, or *.example.com for example.com and its subdomains
+	// Empty allows everything.
+	Allow []string `yaml:"allow"`
+}
+
+// builtOutbound checks the pages of the running build, nil without a policy
+var builtOutbound *outboundPolicy
+
+// outboundPolicy collects references to domains outside the allowlist
+type outboundPolicy struct {
+	allow []string
+
+	mu   sync.Mutex
+	refs map[string][]string // domain → pages referencing it
+}
+
+// newOutboundPolicy returns nil when no allowlist is configured
+// The site's own domain, from baseURL, is always allowed
+func newOutboundPolicy(cfg *Config) *outboundPolicy {
+	if len(cfg.Outbound.Allow) == 0 {
+		return nil
+	}
+	var allow []string
+	for _, domain := range cfg.Outbound.Allow {
+		allow = append(allow, strings.ToLower(domain))
+	}
+	if host := externalHost(cfg.BaseURL); host != "" {
+		allow = append(allow, host)
+	}
+	return &outboundPolicy{allow: allow, refs: map[string][]string{}}
+}
+
+// check records the disallowed domains referenced by a generated page
+func (p *outboundPolicy) check(page, html string) {
+	if p == nil {
+		return
+	}
+	seen := map[string]bool{}
+	for _, m := range linkAttrPattern.FindAllStringSubmatch(html, -1) {
+		host := externalHost(m[1])
+		if host == "" || seen[host] || p.allowed(host) {
+			continue
+		}
+		seen[host] = true
+		p.mu.Lock()
+		p.refs[host] = append(p.refs[host], page)
+		p.mu.Unlock()
+	}
+}
+
+// allowed reports whether host matches an allowlist entry
+func (p *outboundPolicy) allowed(host string) bool {
+	for _, domain := range p.allow {
+		if parent, ok := strings.CutPrefix(domain, "*."); ok {
+			if host == parent || strings.HasSuffix(host, "."+parent) {
+				return true
+			}
+		} else if host == domain {
+			return true
+		}
+	}
+	return false
+}
+
+// report warns once per disallowed domain, so strict builds fail
+func (p *outboundPolicy) report() {
+	if p == nil {
+		return
+	}
+	for _, host := range sortedKeys(p.refs) {
+		pages := p.refs[host]
+		example := pages[0]
+		if len(pages) > 1 {
+			example += fmt.Sprintf(" and %d other page(s)", len(pages)-1)
+		}
+		log.Warnf("%s: references %s, which is not in outbound.allow", example, host)
+	}
+}
+
+// externalHost returns the lowercased host of an absolute http(s) or
+// protocol-relative URL, empty for anything else
+func externalHost(ref string) string {
+	lower := strings.ToLower(strings.TrimSpace(ref))
+	if !strings.HasPrefix(lower, "http://") && !strings.HasPrefix(lower, "https://") && !strings.HasPrefix(lower, "//") {
+		return ""
+	}
+	u, err := url.Parse(lower)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
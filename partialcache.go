@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"html/template"
+)
+
+// builtPartials holds the output of partials rendered with cached during the
+// current build
+var builtPartials = newPartialCache()
+
+// partialCache memoizes partials by name and arguments, so a partial that
+// renders the same for every page, like a tag cloud or the navigation,
+// runs once per build instead of once per page
+type partialCache struct {
+	output       map[string]template.HTML
+	hits, misses int
+}
+
+func newPartialCache() *partialCache {
+	return &partialCache{output: map[string]template.HTML{}}
+}
+
+// render returns the output of the partial name in tmpl for args, running
+// it the first time. The partial gets the first argument as its data, or
+// the list of them when there are several.
+func (c *partialCache) render(tmpl *template.Template, name string, args []any) (template.HTML, error) {
+	sum := sha256.Sum256(fmt.Appendf(nil, "%q %#v", name, args))
+	key := string(sum[:])
+	if out, ok := c.output[key]; ok {
+		c.hits++
+		return out, nil
+	}
+
+	var data any
+	switch len(args) {
+	case 0:
+	case 1:
+		data = args[0]
+	default:
+		data = args
+	}
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, name, data); err != nil {
+		return "", fmt.Errorf("cached %q: %w", name, err)
+	}
+	// Partials are escaped as they run, so the output is safe as it is
+	out := template.HTML(buf.String())
+	c.output[key] = out
+	c.misses++
+	return out, nil
+}
+
+// cachedFunc returns the cached template function running partials of tmpl
+func cachedFunc(tmpl *template.Template) func(name string, args ...any) (template.HTML, error) {
+	return func(name string, args ...any) (template.HTML, error) {
+		return builtPartials.render(tmpl, name, args)
+	}
+}
+
+// report logs how often cached partials were reused
+func (c *partialCache) report() {
+	if c.misses > 0 {
+		log.Debugf("cached partials: rendered %d, reused %d times", c.misses, c.hits)
+	}
+}
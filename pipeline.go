@@ -0,0 +1,321 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// builtAssets is the asset pipeline of the running build
+var builtAssets = newAssetPipeline("public", "static", AssetsConfig{})
+
+// AssetsConfig configures the asset pipeline
+type AssetsConfig struct {
+	Transforms []AssetTransform `yaml:"transforms"`
+	NoMinify   bool             `yaml:"noMinify"` // keep CSS and JS readable, e.g. in development
+}
+
+// AssetTransform pipes matching assets through an external command, such as
+// tailwindcss or postcss, before they are fingerprinted
+type AssetTransform struct {
+	Match string `yaml:"match"` // file name pattern, e.g. *.css
+	// Command reads the asset on stdin, or from the path that replaces
+	// {input}, and writes the result to stdout
+	// e.g., npx tailwindcss --input {input} --minify
+	Command string `yaml:"command"`
+}
+
+// assetPipeline writes fingerprinted copies of static files referenced from
+// templates with {{asset "styles.css"}}
+type assetPipeline struct {
+	publicDir  string
+	staticDir  string
+	transforms []AssetTransform
+	minify     bool
+
+	mu   sync.Mutex
+	urls map[string]string // static file name → fingerprinted URL
+}
+
+func newAssetPipeline(publicDir, staticDir string, cfg AssetsConfig) *assetPipeline {
+	return &assetPipeline{
+		publicDir:  publicDir,
+		staticDir:  staticDir,
+		transforms: cfg.Transforms,
+		minify:     !cfg.NoMinify,
+		urls:       map[string]string{},
+	}
+}
+
+// url minifies static/<name>, writes it with a content hash in its file name
+// and returns its URL
+// e.g., "styles.css" → "/styles.3f2a9c1b.css"
+func (p *assetPipeline) url(name string) (string, error) {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if url, ok := p.urls[name]; ok {
+		return url, nil
+	}
+
+	source := filepath.Join(p.staticDir, name)
+	data, err := os.ReadFile(source)
+	if err != nil {
+		return "", fmt.Errorf("asset %q: %w", name, err)
+	}
+	for _, t := range p.transforms {
+		if ok, _ := path.Match(t.Match, path.Base(name)); !ok {
+			continue
+		}
+		if data, err = runAssetTransform(t.Command, source, data); err != nil {
+			return "", fmt.Errorf("asset %q: %w", name, err)
+		}
+	}
+	if p.minify {
+		switch path.Ext(name) {
+		case ".css":
+			data = minifyCSS(data)
+		case ".js", ".mjs":
+			data = minifyJS(data)
+		}
+	}
+
+	sum := sha256.Sum256(data)
+	ext := path.Ext(name)
+	url := "/" + strings.TrimSuffix(name, ext) + "." + hex.EncodeToString(sum[:4]) + ext
+
+	outputPath := p.publicDir + url
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(outputPath, data, 0644); err != nil {
+		return "", err
+	}
+	logGenerated(outputPath)
+
+	p.urls[name] = url
+	return url, nil
+}
+
+// runAssetTransform runs command over an asset and returns its output
+func runAssetTransform(command, source string, data []byte) ([]byte, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return data, nil
+	}
+	stdin := true
+	for i, arg := range args {
+		if strings.Contains(arg, "{input}") {
+			args[i] = strings.ReplaceAll(arg, "{input}", source)
+			stdin = false
+		}
+	}
+
+	cmd := exec.Command(args[0], args[1:]...)
+	if stdin {
+		cmd.Stdin = bytes.NewReader(data)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w\n%s", command, err, stderr.String())
+	}
+	log.Debugf("Transformed %s with %s", source, args[0])
+	return out, nil
+}
+
+// refingerprint writes new content for the fingerprinted file at url under
+// a fingerprint of that content and returns its URL, or false if url isn't
+// one of the pipeline's files. The old file is left for the caller.
+func (p *assetPipeline) refingerprint(url string, data []byte) (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, name := range sortedKeys(p.urls) {
+		if p.urls[name] != url {
+			continue
+		}
+		sum := sha256.Sum256(data)
+		ext := path.Ext(name)
+		newURL := "/" + strings.TrimSuffix(name, ext) + "." + hex.EncodeToString(sum[:4]) + ext
+		outputPath := p.publicDir + newURL
+		if err := os.WriteFile(outputPath, data, 0644); err != nil {
+			log.Warnf("rewriting %s: %v", url, err)
+			return "", false
+		}
+		logGenerated(outputPath)
+		p.urls[name] = newURL
+		return newURL, true
+	}
+	return "", false
+}
+
+// fingerprinted reports whether the static file at source went through the
+// pipeline, so it isn't copied again under its original name
+func (p *assetPipeline) fingerprinted(source string) bool {
+	rel, err := filepath.Rel(p.staticDir, source)
+	if err != nil {
+		return false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, ok := p.urls[filepath.ToSlash(rel)]
+	return ok
+}
+
+// minifyCSS removes comments and unneeded whitespace, leaving strings intact
+func minifyCSS(css []byte) []byte {
+	var out []byte
+	space := false
+	// Drop whitespace around braces, semicolons, commas and child
+	// combinators, and after colons
+	flushSpace := func(next byte) {
+		if space && !strings.ContainsRune("{};,>", rune(next)) && !strings.ContainsRune("{}:;,>", rune(out[len(out)-1])) {
+			out = append(out, ' ')
+		}
+		space = false
+	}
+	for i := 0; i < len(css); i++ {
+		c := css[i]
+		switch {
+		case c == '/' && i+1 < len(css) && css[i+1] == '*':
+			end := strings.Index(string(css[i+2:]), "*/")
+			if end == -1 {
+				return out
+			}
+			i += end + 3
+		case c == '"' || c == '\'':
+			flushSpace(c)
+			start := i
+			for i++; i < len(css) && css[i] != c; i++ {
+				if css[i] == '\\' {
+					i++
+				}
+			}
+			out = append(out, css[start:min(i+1, len(css))]...)
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f':
+			space = len(out) > 0
+		default:
+			flushSpace(c)
+			if c == '}' && len(out) > 0 && out[len(out)-1] == ';' {
+				out = out[:len(out)-1]
+			}
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// jsRegexpKeywords are the keywords a regular expression literal can follow,
+// where a slash can't be a division
+var jsRegexpKeywords = []string{"return", "typeof", "case", "do", "else", "in", "of", "void", "delete", "throw", "new", "yield", "await"}
+
+// minifyJS removes comments and indentation and collapses whitespace, leaving
+// strings, template literals and regular expressions intact. Line breaks are
+// kept, as automatic semicolon insertion may depend on them, and /*! license
+// comments too.
+func minifyJS(js []byte) []byte {
+	var out []byte
+	space, newline := false, false
+	isWord := func(c byte) bool {
+		return c == '_' || c == '$' || c >= 0x80 || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+	}
+	flush := func(next byte) {
+		if len(out) > 0 {
+			last := out[len(out)-1]
+			switch {
+			case newline:
+				out = append(out, '\n')
+			// a b, a + +b and a - -b need their space
+			case space && (isWord(last) && isWord(next) || (last == '+' || last == '-') && last == next):
+				out = append(out, ' ')
+			}
+		}
+		space, newline = false, false
+	}
+	// A slash starts a regular expression where a value is expected
+	regexpAllowed := func() bool {
+		if len(out) == 0 {
+			return true
+		}
+		if strings.ContainsRune("(,=:[!&|?{};+-*%<>~^\n", rune(out[len(out)-1])) {
+			return true
+		}
+		for _, keyword := range jsRegexpKeywords {
+			if rest, ok := bytes.CutSuffix(out, []byte(keyword)); ok && (len(rest) == 0 || !isWord(rest[len(rest)-1])) {
+				return true
+			}
+		}
+		return false
+	}
+	// copyQuoted copies from js[i] through the unescaped closing quote
+	copyQuoted := func(i int, quote byte) int {
+		start := i
+		for i++; i < len(js) && js[i] != quote; i++ {
+			if js[i] == '\\' {
+				i++
+			}
+		}
+		out = append(out, js[start:min(i+1, len(js))]...)
+		return i
+	}
+
+	for i := 0; i < len(js); i++ {
+		c := js[i]
+		switch {
+		case c == '/' && i+1 < len(js) && js[i+1] == '/':
+			for i+1 < len(js) && js[i+1] != '\n' {
+				i++
+			}
+		case c == '/' && i+1 < len(js) && js[i+1] == '*':
+			end := bytes.Index(js[i+2:], []byte("*/"))
+			if end == -1 {
+				return out
+			}
+			comment := js[i : i+2+end+2]
+			if bytes.HasPrefix(comment, []byte("/*!")) {
+				flush(c)
+				out = append(out, comment...)
+			} else if bytes.ContainsRune(comment, '\n') {
+				newline = len(out) > 0
+			} else {
+				space = len(out) > 0
+			}
+			i += end + 3
+		case c == '"' || c == '\'' || c == '`':
+			flush(c)
+			i = copyQuoted(i, c)
+		case c == '/' && regexpAllowed():
+			flush(c)
+			start := i
+			class := false
+			for i++; i < len(js) && (js[i] != '/' || class) && js[i] != '\n'; i++ {
+				switch js[i] {
+				case '\\':
+					i++
+				case '[':
+					class = true
+				case ']':
+					class = false
+				}
+			}
+			out = append(out, js[start:min(i+1, len(js))]...)
+		case c == '\n' || c == '\r':
+			newline = len(out) > 0
+		case c == ' ' || c == '\t' || c == '\f' || c == '\v':
+			space = len(out) > 0
+		default:
+			flush(c)
+			out = append(out, c)
+		}
+	}
+	return out
+}
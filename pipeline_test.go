@@ -0,0 +1,47 @@
+package main
+
+import "testing"
+
+func TestMinifyCSS(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"whitespace", "body {\n  color: red;\n  margin: 0 auto;\n}\n", "body{color:red;margin:0 auto}"},
+		{"comments", "/* header */\na { color: blue; } /* end */", "a{color:blue}"},
+		{"strings", `a::before { content: "  /* kept */  "; }`, `a::before{content:"  /* kept */  "}`},
+		{"selectors", "ul > li,\nol  li { x: y }", "ul>li,ol li{x:y}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(minifyCSS([]byte(tt.in))); got != tt.want {
+				t.Errorf("minifyCSS(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMinifyJS(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"indentation", "function f(a, b) {\n    return a + b;\n}\n", "function f(a,b){\nreturn a+b;\n}"},
+		{"line comments", "let a = 1; // one\n// whole line\nlet b = 2;", "let a=1;\nlet b=2;"},
+		{"block comments", "let /* inline */ a = /* multi\nline */ 1;", "let a=\n1;"},
+		{"license comment", "/*! MIT */\nlet a;", "/*! MIT */\nlet a;"},
+		{"strings", `let s = "a // b", t = 'c /* d */ e';`, `let s="a // b",t='c /* d */ e';`},
+		{"template literal", "let s = `  ${a}  // x`;", "let s=`  ${a}  // x`;"},
+		{"escaped quote", `let s = "say \"hi\"  there";`, `let s="say \"hi\"  there";`},
+		{"regexp", "let re = /\\/\\/ [/]x/g; // c", "let re=/\\/\\/ [/]x/g;"},
+		{"regexp after return", "return /a b/.test(s)", "return/a b/.test(s)"},
+		{"division", "let x = a / b / 2;", "let x=a/b/2;"},
+		{"unary operators", "a + +b - -c", "a+ +b- -c"},
+		{"keeps line breaks", "let a = 1\nlet b = 2", "let a=1\nlet b=2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(minifyJS([]byte(tt.in))); got != tt.want {
+				t.Errorf("minifyJS(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
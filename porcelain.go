@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// porcelainOut receives records with --porcelain, nil otherwise. Records are
+// for scripts and keep their format across releases: one per line, a kind
+// followed by tab-separated fields, fields holding a tab, newline, quote or
+// backslash quoted as Go strings.
+var porcelainOut io.Writer
+
+// enablePorcelain gives stdout to records and moves human-readable output
+// to stderr, where its wording may change freely
+func enablePorcelain() {
+	porcelainOut = os.Stdout
+	log.out = os.Stderr
+}
+
+// record writes a porcelain record, e.g. record("generated", path)
+func record(kind string, fields ...any) {
+	if porcelainOut == nil {
+		return
+	}
+	var line strings.Builder
+	line.WriteString(kind)
+	for _, field := range fields {
+		value := fmt.Sprint(field)
+		if strings.ContainsAny(value, "\t\n\r\"\\") {
+			value = strconv.Quote(value)
+		}
+		line.WriteString("\t")
+		line.WriteString(value)
+	}
+	fmt.Fprintln(porcelainOut, line.String())
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// prodServer serves the built site as a production web server: with cache
+// headers, ETags, compression, the configured custom headers and an access
+// log, e.g. as the process of a container
+type prodServer struct {
+	cfg   *Config
+	root  string
+	types map[string]string
+	etags map[string]string // slash-separated file name → quoted ETag
+}
+
+// newProdServer indexes the files under root, which must not change while
+// the server runs
+func newProdServer(cfg *Config, root string) (*prodServer, error) {
+	files, err := listOutput(root)
+	if err != nil {
+		return nil, err
+	}
+	s := &prodServer{cfg: cfg, root: root, types: mimeTypes(cfg), etags: map[string]string{}}
+	for name, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(data)
+		s.etags[name] = `"` + hex.EncodeToString(sum[:8]) + `"`
+	}
+	return s, nil
+}
+
+// serveProd runs the production server on addr until SIGINT or SIGTERM,
+// then lets in-flight requests finish
+func serveProd(cfg *Config, addr string) error {
+	s, err := newProdServer(cfg, cfg.outputDir())
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	srv := &http.Server{Addr: addr, Handler: s, ReadHeaderTimeout: 10 * time.Second}
+	errs := make(chan error, 1)
+	go func() { errs <- srv.ListenAndServe() }()
+	log.Infof("Serving %s/ (%d files) on %s", s.root, len(s.etags), addr)
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+	log.Infof("Shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return srv.Shutdown(shutdownCtx)
+}
+
+func (s *prodServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+	defer func() { s.logRequest(r, rec, time.Since(start)) }()
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		rec.Header().Set("Allow", "GET, HEAD")
+		http.Error(rec, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name, ok := s.resolve(r.URL.Path)
+	status := http.StatusOK
+	if !ok {
+		if _, has404 := s.etags["404.html"]; !has404 {
+			http.NotFound(rec, r)
+			return
+		}
+		name, status = "404.html", http.StatusNotFound
+	}
+	s.serveFile(rec, r, name, status)
+}
+
+// resolve maps a request path to a file, trying the path itself, its
+// index.html and the path with .html appended
+func (s *prodServer) resolve(urlPath string) (string, bool) {
+	name := strings.TrimPrefix(path.Clean("/"+urlPath), "/")
+	for _, candidate := range []string{name, path.Join(name, "index.html"), name + ".html"} {
+		if _, ok := s.etags[candidate]; ok && candidate != "" {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
+// serveFile writes a file with its headers, preferring a precompressed
+// .br or .gz copy and otherwise gzipping compressible types on the fly
+func (s *prodServer) serveFile(w http.ResponseWriter, r *http.Request, name string, status int) {
+	h := w.Header()
+	ctype := contentType(s.types, filepath.Join(s.root, filepath.FromSlash(name)))
+	h.Set("Content-Type", ctype)
+	h.Set("Cache-Control", cacheControl(s.cfg.Deploy.CacheControl, name))
+	for _, rule := range s.cfg.Hosting.Headers {
+		if headerRuleMatches(rule.Path, "/"+name) {
+			for key, value := range rule.Values {
+				h.Set(key, value)
+			}
+		}
+	}
+
+	compressible := isCompressible(ctype)
+	if compressible {
+		h.Add("Vary", "Accept-Encoding")
+	}
+	file, etag, encoding := name, s.etags[name], ""
+	for _, enc := range []struct{ name, ext string }{{"br", ".br"}, {"gzip", ".gz"}} {
+		if tag, ok := s.etags[name+enc.ext]; ok && acceptsEncoding(r, enc.name) {
+			file, etag, encoding = name+enc.ext, tag, enc.name
+			break
+		}
+	}
+	if encoding == "" && compressible && acceptsEncoding(r, "gzip") {
+		encoding = "gzip"
+		etag = strings.TrimSuffix(etag, `"`) + `-gzip"`
+	}
+	h.Set("ETag", etag)
+	if encoding != "" {
+		h.Set("Content-Encoding", encoding)
+	}
+
+	if status == http.StatusOK && r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	f, err := os.Open(filepath.Join(s.root, filepath.FromSlash(file)))
+	if err != nil {
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	// Precompressed and uncompressed files support ranges, on the fly
+	// compression doesn't
+	if file == name && encoding == "gzip" {
+		w.WriteHeader(status)
+		if r.Method == http.MethodHead {
+			return
+		}
+		gz := gzip.NewWriter(w)
+		defer gz.Close()
+		io.Copy(gz, f)
+		return
+	}
+	if status != http.StatusOK {
+		w.WriteHeader(status)
+		if r.Method != http.MethodHead {
+			f.WriteTo(w)
+		}
+		return
+	}
+	http.ServeContent(w, r, name, time.Time{}, f)
+}
+
+// headerRuleMatches reports whether a header rule's path, e.g. /assets/*,
+// matches the request path
+func headerRuleMatches(pattern, urlPath string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(urlPath, prefix)
+	}
+	return pattern == urlPath
+}
+
+// isCompressible reports whether a content type benefits from compression
+func isCompressible(ctype string) bool {
+	return strings.HasPrefix(ctype, "text/") ||
+		strings.Contains(ctype, "json") || strings.Contains(ctype, "xml") ||
+		strings.Contains(ctype, "javascript") || strings.Contains(ctype, "svg")
+}
+
+// acceptsEncoding reports whether the request's Accept-Encoding allows enc
+func acceptsEncoding(r *http.Request, enc string) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if strings.EqualFold(strings.TrimSpace(name), enc) {
+			return strings.ReplaceAll(params, " ", "") != "q=0"
+		}
+	}
+	return false
+}
+
+// responseRecorder captures the status and size of a response for logging
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(p []byte) (int, error) {
+	n, err := r.ResponseWriter.Write(p)
+	r.bytes += n
+	return n, err
+}
+
+// logRequest writes a JSON access log line
+func (s *prodServer) logRequest(r *http.Request, rec *responseRecorder, elapsed time.Duration) {
+	if log.level > levelInfo {
+		return
+	}
+	line, _ := json.Marshal(struct {
+		Time       string  `json:"time"`
+		Level      string  `json:"level"`
+		Msg        string  `json:"msg"`
+		Method     string  `json:"method"`
+		Path       string  `json:"path"`
+		Status     int     `json:"status"`
+		Bytes      int     `json:"bytes"`
+		DurationMs float64 `json:"durationMs"`
+		Remote     string  `json:"remote"`
+		UserAgent  string  `json:"userAgent,omitempty"`
+	}{
+		time.Now().Format(time.RFC3339), levelInfo.String(), "request", r.Method, r.URL.RequestURI(),
+		rec.status, rec.bytes, float64(elapsed.Microseconds()) / 1000, r.RemoteAddr, r.UserAgent(),
+	})
+	fmt.Fprintln(log.out, string(line))
+}
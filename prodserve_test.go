@@ -0,0 +1,100 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProdServer(t *testing.T) {
+	logOut := log.out
+	log.out = io.Discard
+	t.Cleanup(func() { log.out = logOut })
+
+	root := t.TempDir()
+	for name, content := range map[string]string{
+		"index.html":     "home",
+		"blog/post.html": "post",
+		"styles.css":     "body{}",
+		"styles.css.br":  "brotli bytes",
+		"app.js":         "let a=1",
+		"404.html":       "not found",
+	} {
+		file := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s, err := newProdServer(&Config{}, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		method, url  string
+		header       map[string]string
+		wantStatus   int
+		wantBody     string
+		wantEncoding string
+		wantETag     string
+	}{
+		{name: "home", url: "/", wantStatus: 200, wantBody: "home", wantETag: s.etags["index.html"]},
+		{name: "pretty URL", url: "/blog/post", wantStatus: 200, wantBody: "post", wantETag: s.etags["blog/post.html"]},
+		{name: "not modified", url: "/", header: map[string]string{"If-None-Match": s.etags["index.html"]}, wantStatus: 304, wantETag: s.etags["index.html"]},
+		{name: "stale ETag", url: "/", header: map[string]string{"If-None-Match": `"old"`}, wantStatus: 200, wantBody: "home", wantETag: s.etags["index.html"]},
+		{name: "precompressed", url: "/styles.css", header: map[string]string{"Accept-Encoding": "gzip, br"},
+			wantStatus: 200, wantBody: "brotli bytes", wantEncoding: "br", wantETag: s.etags["styles.css.br"]},
+		{name: "precompressed not modified", url: "/styles.css", header: map[string]string{"Accept-Encoding": "br", "If-None-Match": s.etags["styles.css.br"]},
+			wantStatus: 304, wantEncoding: "br", wantETag: s.etags["styles.css.br"]},
+		{name: "brotli refused", url: "/styles.css", header: map[string]string{"Accept-Encoding": "br;q=0, gzip"},
+			wantStatus: 200, wantBody: "body{}", wantEncoding: "gzip", wantETag: strings.TrimSuffix(s.etags["styles.css"], `"`) + `-gzip"`},
+		{name: "gzipped on the fly", url: "/app.js", header: map[string]string{"Accept-Encoding": "gzip"},
+			wantStatus: 200, wantBody: "let a=1", wantEncoding: "gzip", wantETag: strings.TrimSuffix(s.etags["app.js"], `"`) + `-gzip"`},
+		{name: "uncompressed", url: "/app.js", wantStatus: 200, wantBody: "let a=1", wantETag: s.etags["app.js"]},
+		{name: "not found", url: "/nope", wantStatus: 404, wantBody: "not found", wantETag: s.etags["404.html"]},
+		{name: "method not allowed", method: http.MethodPost, url: "/", wantStatus: 405, wantBody: "method not allowed\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			req := httptest.NewRequest(method, tt.url, nil)
+			for key, value := range tt.header {
+				req.Header.Set(key, value)
+			}
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, req)
+
+			resp := w.Result()
+			var body io.Reader = resp.Body
+			if resp.Header.Get("Content-Encoding") == "gzip" {
+				gz, err := gzip.NewReader(resp.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				body = gz
+			}
+			got, _ := io.ReadAll(body)
+			if resp.StatusCode != tt.wantStatus || string(got) != tt.wantBody {
+				t.Errorf("%s %s = %d %q, want %d %q", method, tt.url, resp.StatusCode, got, tt.wantStatus, tt.wantBody)
+			}
+			if enc := resp.Header.Get("Content-Encoding"); enc != tt.wantEncoding {
+				t.Errorf("Content-Encoding = %q, want %q", enc, tt.wantEncoding)
+			}
+			if etag := resp.Header.Get("ETag"); etag != tt.wantETag {
+				t.Errorf("ETag = %q, want %q", etag, tt.wantETag)
+			}
+		})
+	}
+}
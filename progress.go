@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// defaultProgressThreshold is the number of content files above which
+// builds show progress instead of a line per generated file
+const defaultProgressThreshold = 200
+
+// buildProgress is the progress display of the running build, nil when the
+// site is below the threshold
+var buildProgress *progress
+
+// progress reports counters and an ETA for one build phase at a time
+// On a terminal it redraws a single bar, otherwise it prints a line every 10%
+type progress struct {
+	out     io.Writer
+	tty     bool
+	phase   string
+	total   int
+	done    int
+	start   time.Time
+	lastPct int
+	drawn   bool
+}
+
+func newProgress() *progress {
+	return &progress{out: os.Stderr, tty: isTerminal(os.Stderr)}
+}
+
+// isTerminal reports whether f is attached to a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// The methods below are no-ops on a nil *progress so callers don't need to
+// check whether the build is large enough to show progress
+
+// Phase starts counting a new phase of total steps
+func (p *progress) Phase(name string, total int) {
+	if p == nil {
+		return
+	}
+	p.finishLine()
+	p.phase = name
+	p.total = total
+	p.done = 0
+	p.lastPct = -1
+	p.start = time.Now()
+}
+
+// Step marks one unit of work in the current phase as done
+func (p *progress) Step() {
+	if p == nil {
+		return
+	}
+	p.done++
+	if p.total == 0 || log.level > levelInfo || log.json {
+		return
+	}
+
+	pct := p.done * 100 / p.total
+	if p.tty {
+		p.draw(pct)
+		return
+	}
+	if pct/10 != p.lastPct/10 || p.done == p.total {
+		fmt.Fprintf(p.out, "%s: %d/%d (%d%%)%s\n", p.phase, p.done, p.total, pct, p.eta())
+	}
+	p.lastPct = pct
+}
+
+// Clear erases the bar so another message can be printed on its line
+func (p *progress) Clear() {
+	if p != nil && p.tty && p.drawn {
+		fmt.Fprint(p.out, "\r\033[K")
+		p.drawn = false
+	}
+}
+
+// Redraw puts the bar back after Clear
+func (p *progress) Redraw() {
+	if p != nil && p.tty && p.total > 0 && p.done < p.total && p.done > 0 {
+		p.draw(p.done * 100 / p.total)
+	}
+}
+
+// Done ends the last phase
+func (p *progress) Done() {
+	if p == nil {
+		return
+	}
+	p.finishLine()
+}
+
+func (p *progress) draw(pct int) {
+	const width = 30
+	filled := pct * width / 100
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", width-filled)
+	fmt.Fprintf(p.out, "\r\033[K%-8s [%s] %d/%d%s", p.phase, bar, p.done, p.total, p.eta())
+	p.drawn = true
+}
+
+func (p *progress) finishLine() {
+	if p.tty && p.drawn {
+		fmt.Fprintln(p.out)
+		p.drawn = false
+	}
+}
+
+// eta estimates the time left in the current phase from the average so far
+func (p *progress) eta() string {
+	if p.done == 0 || p.done >= p.total {
+		return ""
+	}
+	elapsed := time.Since(p.start)
+	left := elapsed / time.Duration(p.done) * time.Duration(p.total-p.done)
+	return fmt.Sprintf(", ETA %s", left.Round(time.Second))
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"html/template"
+	"strings"
+)
+
+// redirectTemplate renders pages with a redirect in their frontmatter:
+// browsers follow the refresh, search engines the canonical link, and
+// hosting platforms answer with the redirect written to their config
+var redirectTemplate = template.Must(template.New("redirect.html").Parse(`<!DOCTYPE html>
+<html>
+<head>
+	<meta charset="utf-8">
+	<title>{{.Title}}</title>
+	<meta http-equiv="refresh" content="0; url={{.Redirect}}">
+	<link rel="canonical" href="{{.Redirect}}">
+	<meta name="robots" content="noindex">
+</head>
+<body>
+	<p>This page has moved to <a href="{{.Redirect}}">{{.Redirect}}</a>.</p>
+</body>
+</html>
+`))
+
+// splitRedirects separates pages with a redirect from the rest, so they
+// stay out of listings, feeds and the sitemap. Site-relative targets are
+// made absolute when the site has a baseURL.
+func splitRedirects(cfg *Config, pages []Page) (content, redirects []Page) {
+	for _, page := range pages {
+		if page.Redirect == "" {
+			content = append(content, page)
+			continue
+		}
+		if strings.HasPrefix(page.Redirect, "/") && !strings.HasPrefix(page.Redirect, "//") && cfg.BaseURL != "" {
+			page.Redirect = absURL(cfg, page.Redirect)
+		}
+		redirects = append(redirects, page)
+	}
+	return content, redirects
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// RobotsConfig writes public/robots.txt, telling crawlers which paths to skip
+type RobotsConfig struct {
+	Enabled bool         `yaml:"enabled"`
+	Rules   []RobotsRule `yaml:"rules"` // none allows every crawler everywhere
+}
+
+// RobotsRule is a group of robots.txt lines for the crawlers it names
+type RobotsRule struct {
+	UserAgent string   `yaml:"userAgent"` // e.g. Googlebot, * by default
+	Allow     []string `yaml:"allow"`
+	Disallow  []string `yaml:"disallow"` // path prefixes, e.g. /drafts/
+}
+
+// robotsTxt returns the contents of robots.txt, pointing at the sitemap when
+// there is one
+func robotsTxt(cfg *Config) string {
+	rules := cfg.Robots.Rules
+	if len(rules) == 0 {
+		rules = []RobotsRule{{}}
+	}
+	var b strings.Builder
+	for i, rule := range rules {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		agent := rule.UserAgent
+		if agent == "" {
+			agent = "*"
+		}
+		fmt.Fprintf(&b, "User-agent: %s\n", agent)
+		for _, path := range rule.Allow {
+			fmt.Fprintf(&b, "Allow: %s\n", path)
+		}
+		for _, path := range rule.Disallow {
+			fmt.Fprintf(&b, "Disallow: %s\n", path)
+		}
+		// An empty Disallow allows everything, a group needs at least one line
+		if len(rule.Allow) == 0 && len(rule.Disallow) == 0 {
+			b.WriteString("Disallow:\n")
+		}
+	}
+	if cfg.BaseURL != "" {
+		fmt.Fprintf(&b, "\nSitemap: %s\n", absURL(cfg, "/sitemap.xml"))
+	}
+	return b.String()
+}
+
+// writeRobotsTxt writes robots.txt to publicDir when enabled, unless the
+// site has its own in static/
+func writeRobotsTxt(cfg *Config, publicDir string) error {
+	if !cfg.Robots.Enabled {
+		return nil
+	}
+	if _, err := os.Stat(filepath.Join(cfg.staticDir(), "robots.txt")); err == nil {
+		log.Warnf("robots.enabled: %s/robots.txt is copied instead, remove one of them", cfg.staticDir())
+		return nil
+	}
+	file := filepath.Join(publicDir, "robots.txt")
+	if err := os.WriteFile(file, []byte(robotsTxt(cfg)), 0644); err != nil {
+		return err
+	}
+	logGenerated(file)
+	return nil
+}
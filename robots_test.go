@@ -0,0 +1,26 @@
+package main
+
+import "testing"
+
+func TestRobotsTxt(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		want string
+	}{
+		{"allow all", Config{}, "User-agent: *\nDisallow:\n"},
+		{"sitemap", Config{BaseURL: "https://example.com/"}, "User-agent: *\nDisallow:\n\nSitemap: https://example.com/sitemap.xml\n"},
+		{"rules", Config{Robots: RobotsConfig{Rules: []RobotsRule{
+			{Disallow: []string{"/drafts/"}},
+			{UserAgent: "Googlebot", Allow: []string{"/drafts/public/"}, Disallow: []string{"/drafts/", "/tmp/"}},
+		}}}, "User-agent: *\nDisallow: /drafts/\n\nUser-agent: Googlebot\nAllow: /drafts/public/\nDisallow: /drafts/\nDisallow: /tmp/\n"},
+		{"named agent allowed everywhere", Config{Robots: RobotsConfig{Rules: []RobotsRule{{UserAgent: "Bingbot"}}}}, "User-agent: Bingbot\nDisallow:\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := robotsTxt(&tt.cfg); got != tt.want {
+				t.Errorf("robotsTxt() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
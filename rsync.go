@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// rsyncDeployer mirrors the output to a directory on a server over SSH
+// using the rsync command, deleting files no longer in the output
+type rsyncDeployer struct {
+	name string
+	dest string   // user@host:path/
+	ssh  []string // ssh command and options
+}
+
+func newRsyncDeployer(t DeployTarget) (deployer, error) {
+	if t.Host == "" || t.Path == "" {
+		return nil, fmt.Errorf("missing host or path")
+	}
+	if _, err := exec.LookPath("rsync"); err != nil {
+		return nil, fmt.Errorf("rsync not found in PATH")
+	}
+
+	dest := t.Host + ":" + strings.TrimSuffix(t.Path, "/") + "/"
+	if t.User != "" {
+		dest = t.User + "@" + dest
+	}
+	ssh := []string{"ssh"}
+	if t.Port != 0 {
+		ssh = append(ssh, "-p", strconv.Itoa(t.Port))
+	}
+	if t.Key != "" {
+		ssh = append(ssh, "-i", t.Key)
+	}
+	return &rsyncDeployer{name: t.Name, dest: dest, ssh: ssh}, nil
+}
+
+func (d *rsyncDeployer) deploy(ctx context.Context, dir string, opts deployOptions) (deployStats, error) {
+	if opts.Trash != nil && !opts.DryRun {
+		if err := d.keepDeletions(ctx, dir, opts); err != nil {
+			return deployStats{}, fmt.Errorf("keeping deleted files: %w", err)
+		}
+	}
+	out, err := d.sync(ctx, dir, opts.Force, opts.DryRun)
+	if err != nil {
+		return deployStats{}, err
+	}
+	return countRsyncChanges(out), nil
+}
+
+// sync mirrors dir to the server and returns rsync's itemized changes
+func (d *rsyncDeployer) sync(ctx context.Context, dir string, force, dryRun bool) ([]byte, error) {
+	// Itemized changes are parsed for the summary, and also shown on a dry run
+	args := []string{"--recursive", "--links", "--times", "--compress", "--delete", "--itemize-changes"}
+	if dryRun {
+		args = append(args, "--dry-run")
+	}
+	if force {
+		args = append(args, "--ignore-times")
+	} else {
+		args = append(args, "--checksum")
+	}
+	return d.rsync(ctx, "", nil, append(args, strings.TrimSuffix(dir, "/")+"/", d.dest)...)
+}
+
+// keepDeletions copies the files the deploy is about to delete from the
+// server into the trash
+func (d *rsyncDeployer) keepDeletions(ctx context.Context, dir string, opts deployOptions) error {
+	out, err := d.sync(ctx, dir, opts.Force, true)
+	if err != nil {
+		return err
+	}
+	names := rsyncDeletions(out)
+	if len(names) == 0 {
+		return nil
+	}
+	tmp, err := os.MkdirTemp("", "slate-rsync")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	// --files-from names the files relative to the directory on the server
+	list := strings.NewReader(strings.Join(names, "\n") + "\n")
+	if _, err := d.rsync(ctx, "", list, "--files-from=-", "--times", d.dest, tmp+"/"); err != nil {
+		return err
+	}
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(tmp, filepath.FromSlash(name)))
+		if err != nil {
+			return err
+		}
+		if err := opts.Trash.keepRemote(d.name, name, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restore uploads a file kept in the trash back to name on the server
+func (d *rsyncDeployer) restore(ctx context.Context, name, file string, opts deployOptions) error {
+	// --relative recreates name's directories, from the directory name is in
+	base, ok := strings.CutSuffix(filepath.ToSlash(file), "/"+name)
+	if !ok {
+		return fmt.Errorf("%s isn't stored as %s", file, name)
+	}
+	_, err := d.rsync(ctx, filepath.FromSlash(base), nil, "--relative", "--times", name, d.dest)
+	return err
+}
+
+// rsync runs rsync over the deployer's SSH command in dir, with stdin as
+// its input, and returns its output
+func (d *rsyncDeployer) rsync(ctx context.Context, dir string, stdin *strings.Reader, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "rsync", append([]string{"-e", rsyncRemoteShell(d.ssh)}, args...)...)
+	cmd.Dir = dir
+	if stdin != nil {
+		cmd.Stdin = stdin
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("rsync: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// rsyncRemoteShell joins the ssh command for rsync's -e, which rsync splits
+// on spaces again. Arguments holding spaces or quotes are single-quoted, a
+// quote inside doubled, as rsync reads them.
+// e.g., ["ssh", "-i", "/keys/my key"] → "ssh -i '/keys/my key'"
+func rsyncRemoteShell(ssh []string) string {
+	quoted := make([]string, len(ssh))
+	for i, arg := range ssh {
+		if arg != "" && !strings.ContainsAny(arg, " \t\n'\"") {
+			quoted[i] = arg
+			continue
+		}
+		quoted[i] = "'" + strings.ReplaceAll(arg, "'", "''") + "'"
+	}
+	return strings.Join(quoted, " ")
+}
+
+// rsyncDeletions returns the files rsync's --itemize-changes output deletes,
+// leaving out directories
+func rsyncDeletions(out []byte) []string {
+	var names []string
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		item, name, ok := strings.Cut(scanner.Text(), " ")
+		name = strings.TrimSpace(name)
+		if ok && item == "*deleting" && !strings.HasSuffix(name, "/") {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// countRsyncChanges counts the files in rsync's --itemize-changes output,
+// e.g. "<f+++++++++ blog/new.html" or "*deleting   old.html"
+func countRsyncChanges(out []byte) deployStats {
+	var stats deployStats
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		item, name, ok := strings.Cut(scanner.Text(), " ")
+		name = strings.TrimSpace(name)
+		switch {
+		case !ok:
+		case item == "*deleting":
+			log.Infof("Deleting: %s", name)
+			stats.Deleted++
+		case strings.HasPrefix(item, "<f"):
+			log.Infof("Uploading: %s", name)
+			stats.Uploaded++
+		}
+	}
+	return stats
+}
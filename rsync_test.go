@@ -0,0 +1,47 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRsyncDeletions(t *testing.T) {
+	tests := []struct {
+		name string
+		out  string
+		want []string
+	}{
+		{"none", "<f+++++++++ blog/new.html\n", nil},
+		{"files", "*deleting   old.html\n<f.st...... index.html\n*deleting   blog/gone.html\n", []string{"old.html", "blog/gone.html"}},
+		{"directories left out", "*deleting   tags/old/index.html\n*deleting   tags/old/\n", []string{"tags/old/index.html"}},
+		{"spaces in names", "*deleting   my post.html\n", []string{"my post.html"}},
+		{"empty", "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rsyncDeletions([]byte(tt.out)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("rsyncDeletions() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRsyncRemoteShell(t *testing.T) {
+	tests := []struct {
+		name string
+		ssh  []string
+		want string
+	}{
+		{"plain", []string{"ssh", "-p", "2222"}, "ssh -p 2222"},
+		{"key with spaces", []string{"ssh", "-i", "/home/me/My Keys/deploy key"}, "ssh -i '/home/me/My Keys/deploy key'"},
+		{"quote", []string{"ssh", "-i", "/keys/o'brien"}, "ssh -i '/keys/o''brien'"},
+		{"double quote", []string{"ssh", "-i", `/keys/"x"`}, `ssh -i '/keys/"x"'`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rsyncRemoteShell(tt.ssh); got != tt.want {
+				t.Errorf("rsyncRemoteShell(%q) = %q, want %q", tt.ssh, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,369 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// s3Deployer syncs the output with an S3-compatible bucket: changed files
+// are uploaded and files no longer in the output are deleted
+// Credentials come from AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and
+// optionally AWS_SESSION_TOKEN
+type s3Deployer struct {
+	name     string
+	client   *s3Client
+	prefix   string
+	transfer transferOptions
+}
+
+func newS3Deployer(t DeployTarget) (deployer, error) {
+	if t.Bucket == "" {
+		return nil, fmt.Errorf("missing bucket")
+	}
+	region := t.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+	endpoint := t.Endpoint
+	if endpoint == "" {
+		endpoint = "https://s3." + region + ".amazonaws.com"
+	}
+	accessKey, secretKey := os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY")
+	if accessKey == "" || secretKey == "" {
+		return nil, fmt.Errorf("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set")
+	}
+
+	prefix := strings.Trim(t.Prefix, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	return &s3Deployer{
+		client: &s3Client{
+			endpoint:     strings.TrimSuffix(endpoint, "/"),
+			bucket:       t.Bucket,
+			region:       region,
+			accessKey:    accessKey,
+			secretKey:    secretKey,
+			sessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+			http:         &http.Client{Timeout: 5 * time.Minute},
+		},
+		name:     t.Name,
+		prefix:   prefix,
+		transfer: t.transfer(),
+	}, nil
+}
+
+func (d *s3Deployer) deploy(ctx context.Context, dir string, opts deployOptions) (deployStats, error) {
+	var stats deployStats
+
+	local, err := listOutput(dir)
+	if err != nil {
+		return stats, err
+	}
+	remote, err := d.client.list(ctx, d.prefix)
+	if err != nil {
+		return stats, fmt.Errorf("listing bucket: %w", err)
+	}
+
+	var state *deployState
+	if !opts.DryRun {
+		// --force uploads everything again, even what an interrupted
+		// deploy already uploaded
+		if state, err = openDeployState(d.name, opts.Force); err != nil {
+			return stats, err
+		}
+		if len(state.done) > 0 {
+			log.Infof("Resuming an interrupted deploy, %d file(s) were already uploaded", len(state.done))
+		}
+	}
+
+	// Files are only hashed here and read again when uploaded, so no more
+	// than the uploads in flight are held in memory
+	var tasks []deployTask
+	for _, name := range sortedKeys(local) {
+		md5Hex, err := fileMD5(local[name])
+		if err != nil {
+			state.close(false)
+			return stats, err
+		}
+		etag, exists := remote[d.prefix+name]
+		if (exists && !opts.Force && etag == md5Hex) || state.uploaded(name, md5Hex) {
+			stats.Unchanged++
+			continue
+		}
+
+		log.Infof("Uploading: %s", name)
+		stats.Uploaded++
+		headers := http.Header{
+			"Content-Type":  {contentType(opts.Types, local[name])},
+			"Cache-Control": {cacheControl(opts.Cache, name)},
+		}
+		tasks = append(tasks, deployTask{
+			name: "uploading " + name,
+			run: func(ctx context.Context) error {
+				data, err := os.ReadFile(local[name])
+				if err != nil {
+					return permanentError{err}
+				}
+				return d.client.put(ctx, d.prefix+name, data, headers)
+			},
+			done: func() { state.record(name, md5Hex) },
+		})
+	}
+
+	for _, key := range sortedKeys(remote) {
+		if _, ok := local[strings.TrimPrefix(key, d.prefix)]; ok {
+			continue
+		}
+		name := strings.TrimPrefix(key, d.prefix)
+		log.Infof("Deleting: %s", name)
+		stats.Deleted++
+		tasks = append(tasks, deployTask{
+			name: "deleting " + key,
+			run: func(ctx context.Context) error {
+				if opts.Trash != nil {
+					data, err := d.client.get(ctx, key)
+					if err != nil {
+						return err
+					}
+					if err := opts.Trash.keepRemote(d.name, name, data); err != nil {
+						return permanentError{err}
+					}
+				}
+				return d.client.delete(ctx, key)
+			},
+		})
+	}
+
+	if opts.DryRun {
+		return stats, nil
+	}
+	err = runDeployTasks(ctx, d.transfer, tasks)
+	state.close(err == nil)
+	return stats, err
+}
+
+// fileMD5 returns the hex MD5 of a file's content, which single-part
+// uploads have as their ETag
+func fileMD5(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := md5.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// restore uploads a file kept in the trash back to name
+func (d *s3Deployer) restore(ctx context.Context, name, file string, opts deployOptions) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	headers := http.Header{
+		"Content-Type":  {contentType(opts.Types, file)},
+		"Cache-Control": {cacheControl(opts.Cache, name)},
+	}
+	return d.client.put(ctx, d.prefix+name, data, headers)
+}
+
+// s3Client is a minimal S3 API client using path-style requests signed
+// with Signature Version 4
+type s3Client struct {
+	endpoint, bucket, region string
+	accessKey, secretKey     string
+	sessionToken             string
+	http                     *http.Client
+}
+
+// list returns the ETag of every object under prefix, keyed by object key
+func (c *s3Client) list(ctx context.Context, prefix string) (map[string]string, error) {
+	objects := map[string]string{}
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}}
+		if prefix != "" {
+			query.Set("prefix", prefix)
+		}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		body, err := c.do(ctx, http.MethodGet, "", query, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var result struct {
+			Contents []struct {
+				Key  string
+				ETag string
+			}
+			IsTruncated           bool
+			NextContinuationToken string
+		}
+		if err := xml.Unmarshal(body, &result); err != nil {
+			return nil, err
+		}
+		for _, object := range result.Contents {
+			objects[object.Key] = strings.Trim(object.ETag, `"`)
+		}
+		if !result.IsTruncated {
+			return objects, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+func (c *s3Client) get(ctx context.Context, key string) ([]byte, error) {
+	return c.do(ctx, http.MethodGet, key, nil, nil, nil)
+}
+
+func (c *s3Client) put(ctx context.Context, key string, data []byte, headers http.Header) error {
+	_, err := c.do(ctx, http.MethodPut, key, nil, data, headers)
+	return err
+}
+
+func (c *s3Client) delete(ctx context.Context, key string) error {
+	_, err := c.do(ctx, http.MethodDelete, key, nil, nil, nil)
+	return err
+}
+
+// do sends a signed request for key (empty for the bucket itself) and
+// returns the response body, or an error for non-2xx responses
+func (c *s3Client) do(ctx context.Context, method, key string, query url.Values, body []byte, headers http.Header) ([]byte, error) {
+	uri := "/" + s3Escape(c.bucket, false) + "/" + s3Escape(key, true)
+	rawQuery := s3Query(query)
+	target := c.endpoint + uri
+	if rawQuery != "" {
+		target += "?" + rawQuery
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range headers {
+		req.Header[name] = values
+	}
+	c.sign(req, uri, rawQuery, body, time.Now().UTC())
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		var s3Err struct {
+			Code    string
+			Message string
+		}
+		err := fmt.Errorf("%s %s: %s", method, uri, resp.Status)
+		if xml.Unmarshal(respBody, &s3Err) == nil && s3Err.Code != "" {
+			err = fmt.Errorf("%s: %s", s3Err.Code, s3Err.Message)
+		}
+		// Client errors other than throttling and timeouts won't go away
+		if resp.StatusCode/100 == 4 && resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusRequestTimeout {
+			return nil, permanentError{err}
+		}
+		return nil, err
+	}
+	return respBody, nil
+}
+
+// sign adds a Signature Version 4 Authorization header to req
+func (c *s3Client) sign(req *http.Request, uri, rawQuery string, body []byte, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if c.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", c.sessionToken)
+	}
+
+	signed := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		if lower := strings.ToLower(name); strings.HasPrefix(lower, "x-amz-") {
+			signed[lower] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := sortedKeys(signed)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + signed[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method, uri, rawQuery, canonicalHeaders.String(), signedHeaders, payloadHash,
+	}, "\n")
+	scope := day + "/" + c.region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := []byte("AWS4" + c.secretKey)
+	for _, part := range []string{day, c.region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		c.accessKey, scope, signedHeaders, signature))
+}
+
+// s3Escape percent-encodes everything but unreserved characters, and
+// slashes too unless keepSlash is set
+func s3Escape(s string, keepSlash bool) string {
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/' && keepSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// s3Query encodes query sorted by key, as the canonical request requires
+func s3Query(query url.Values) string {
+	var parts []string
+	for _, key := range sortedKeys(query) {
+		for _, value := range query[key] {
+			parts = append(parts, s3Escape(key, false)+"="+s3Escape(value, false))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
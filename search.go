@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// defaultPagefindCommand runs Pagefind through npm when no command is configured
+const defaultPagefindCommand = "npx -y pagefind"
+
+// SearchConfig builds a full-text search index once the site is written
+type SearchConfig struct {
+	Engine  string `yaml:"engine"`  // pagefind or lunr, empty disables search
+	Command string `yaml:"command"` // pagefind command, e.g. /usr/local/bin/pagefind
+}
+
+// lunrDocument is one entry of public/search-index.json, ready to be added
+// to a Lunr index in the browser
+type lunrDocument struct {
+	URL     string   `json:"url"`
+	Title   string   `json:"title"`
+	Summary string   `json:"summary,omitempty"`
+	Tags    []string `json:"tags,omitempty"`
+	Body    string   `json:"body"`
+}
+
+// buildSearchIndex indexes publicDir with the configured search engine,
+// pages for Lunr, every HTML file but the unlisted ones for Pagefind
+func buildSearchIndex(cfg *Config, pages []Page, unlisted []string, publicDir string) error {
+	switch cfg.Search.Engine {
+	case "":
+		return nil
+	case "pagefind":
+		return runPagefind(cfg.Search.Command, publicDir, unlisted)
+	case "lunr":
+		return writeLunrIndex(pages, publicDir+"/search-index.json")
+	default:
+		return fmt.Errorf("unknown search engine %q, use pagefind or lunr", cfg.Search.Engine)
+	}
+}
+
+// runPagefind runs Pagefind over publicDir, which writes its index and UI
+// bundle to publicDir/pagefind/. Pagefind indexes every HTML file it finds,
+// so the unlisted pages are moved aside while it runs.
+func runPagefind(command, publicDir string, unlisted []string) error {
+	if command == "" {
+		command = defaultPagefindCommand
+	}
+	aside, err := os.MkdirTemp(filepath.Dir(publicDir), ".slate-unlisted-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(aside)
+	var moved []string
+	putBack := func() error {
+		for i, file := range moved {
+			if err := os.Rename(filepath.Join(aside, strconv.Itoa(i)), file); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, url := range unlisted {
+		file := filepath.Join(publicDir, filepath.FromSlash(url))
+		if err := os.Rename(file, filepath.Join(aside, strconv.Itoa(len(moved)))); err != nil {
+			return errors.Join(err, putBack())
+		}
+		moved = append(moved, file)
+	}
+
+	args := append(strings.Fields(command), "--site", publicDir)
+	cmd := exec.Command(args[0], args[1:]...)
+	out, err := cmd.CombinedOutput()
+	if err := putBack(); err != nil {
+		return fmt.Errorf("putting back unlisted pages: %w", err)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w\n%s", command, err, out)
+	}
+	log.Debugf("%s", out)
+	log.Infof("Indexed: %s", publishedPath(filepath.Join(publicDir, "pagefind"))+"/")
+	return nil
+}
+
+// writeLunrIndex writes the text of every page as JSON for Lunr
+func writeLunrIndex(pages []Page, outputPath string) error {
+	docs := make([]lunrDocument, 0, len(pages))
+	for _, page := range pages {
+		docs = append(docs, lunrDocument{
+			URL:     page.URL,
+			Title:   page.Title,
+			Summary: page.Summary,
+			Tags:    page.Tags,
+			Body:    strings.Join(strings.Fields(stripTags(string(page.Content))), " "),
+		})
+	}
+
+	data, err := json.Marshal(docs)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(outputPath, data, 0644); err != nil {
+		return err
+	}
+	log.Infof("Generated: %s", publishedPath(outputPath))
+	return nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"sort"
+)
+
+// PageLink is a lightweight reference to another page
+type PageLink struct {
+	Title string
+	URL   string
+}
+
+// SeriesInfo places a page within its series, for "part N of M" navigation
+type SeriesInfo struct {
+	Name  string
+	URL   string // series index page
+	Part  int
+	Total int
+	Prev  *PageLink
+	Next  *PageLink
+}
+
+// SeriesPage is the data passed to templates/series.html
+type SeriesPage struct {
+	Name  string
+	URL   string
+	Pages []Page
+}
+
+// seriesURL returns the index page URL for a series
+func seriesURL(name string) string {
+	return "/series/" + slugify(name) + "/"
+}
+
+// assignSeries orders the pages of each series and sets their Series info
+// Pages are ordered by seriesPart when given, then by date
+func assignSeries(pages []Page) []SeriesPage {
+	members := map[string][]int{}
+	for i, page := range pages {
+		if page.seriesName != "" && !page.Unlisted {
+			members[page.seriesName] = append(members[page.seriesName], i)
+		}
+	}
+
+	var seriesPages []SeriesPage
+	for name, indexes := range members {
+		sort.SliceStable(indexes, func(a, b int) bool {
+			pa, pb := pages[indexes[a]], pages[indexes[b]]
+			if pa.seriesPart != pb.seriesPart {
+				// Explicit parts come before pages without one
+				if pa.seriesPart == 0 || pb.seriesPart == 0 {
+					return pb.seriesPart == 0
+				}
+				return pa.seriesPart < pb.seriesPart
+			}
+			return pa.Date.Before(pb.Date)
+		})
+
+		series := SeriesPage{Name: name, URL: seriesURL(name)}
+		for n, i := range indexes {
+			info := &SeriesInfo{Name: name, URL: series.URL, Part: n + 1, Total: len(indexes)}
+			if n > 0 {
+				prev := pages[indexes[n-1]]
+				info.Prev = &PageLink{Title: prev.Title, URL: prev.URL}
+			}
+			if n < len(indexes)-1 {
+				next := pages[indexes[n+1]]
+				info.Next = &PageLink{Title: next.Title, URL: next.URL}
+			}
+			pages[i].Series = info
+		}
+		for _, i := range indexes {
+			series.Pages = append(series.Pages, pages[i])
+		}
+		seriesPages = append(seriesPages, series)
+	}
+
+	sort.Slice(seriesPages, func(i, j int) bool {
+		return seriesPages[i].Name < seriesPages[j].Name
+	})
+	return seriesPages
+}
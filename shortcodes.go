@@ -0,0 +1,209 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// shortcodePattern matches {{< name "arg" ... >}} in markdown
+var shortcodePattern = regexp.MustCompile(`\{\{<\s*([A-Za-z][\w-]*)((?:\s+"(?:[^"\\]|\\.)*")*)\s*>\}\}`)
+
+// shortcodeArgPattern matches one quoted shortcode argument
+var shortcodeArgPattern = regexp.MustCompile(`"(?:[^"\\]|\\.)*"`)
+
+// shortcodeContext is what a shortcode knows about the page it's used in
+type shortcodeContext struct {
+	cfg     *Config
+	file    string
+	authors []Author
+}
+
+// shortcode returns the markdown that replaces a shortcode
+type shortcode func(ctx shortcodeContext, args []string) (string, error)
+
+// shortcodes are the shortcodes available in content files
+var shortcodes = map[string]shortcode{
+	"snippet": snippetShortcode,
+	"figure":  figureShortcode,
+	"ref":     refShortcode,
+	"table":   tableShortcode,
+}
+
+// expandShortcodes replaces the shortcodes in markdown, except in code
+// blocks and code spans, where they're shown as written
+// Unknown shortcodes and shortcode errors are reported and left in place
+func expandShortcodes(ctx shortcodeContext, markdown []byte) []byte {
+	code := markdownCodeRanges(markdown)
+	var out []byte
+	last := 0
+	for _, m := range shortcodePattern.FindAllSubmatchIndex(markdown, -1) {
+		if slices.ContainsFunc(code, func(r [2]int) bool { return r[0] <= m[0] && m[0] < r[1] }) {
+			continue
+		}
+		out = append(out, markdown[last:m[0]]...)
+		out = append(out, expandShortcode(ctx, markdown[m[0]:m[1]], string(markdown[m[2]:m[3]]), markdown[m[4]:m[5]])...)
+		last = m[1]
+	}
+	if last == 0 {
+		return markdown
+	}
+	return append(out, markdown[last:]...)
+}
+
+// expandShortcode returns the expansion of the shortcode match, or match
+// itself when it can't be expanded
+func expandShortcode(ctx shortcodeContext, match []byte, name string, quotedArgs []byte) []byte {
+	fn, ok := shortcodes[name]
+	if !ok {
+		log.Warnf("%s: unknown shortcode %q", ctx.file, name)
+		return match
+	}
+
+	var args []string
+	for _, quoted := range shortcodeArgPattern.FindAll(quotedArgs, -1) {
+		arg, err := strconv.Unquote(string(quoted))
+		if err != nil {
+			arg = string(quoted[1 : len(quoted)-1])
+		}
+		args = append(args, arg)
+	}
+
+	expanded, err := fn(ctx, args)
+	if err != nil {
+		log.Warnf("%s: shortcode %s: %v", ctx.file, name, err)
+		return match
+	}
+	return []byte(expanded)
+}
+
+// markdownCodeRanges returns the start and end offsets of the fenced code
+// blocks and inline code spans in markdown
+func markdownCodeRanges(markdown []byte) [][2]int {
+	var ranges [][2]int
+	text := string(markdown)
+	proseStart := 0
+	fence, fenceStart := "", 0
+	for offset := 0; offset < len(text); {
+		end := strings.IndexByte(text[offset:], '\n') + offset + 1
+		if end == offset {
+			end = len(text)
+		}
+		line := strings.TrimRight(text[offset:end], "\r\n")
+		indented := strings.TrimLeft(line, " ")
+		switch {
+		case fence != "":
+			// A closing fence is at least as long as the opening one
+			if strings.HasPrefix(indented, fence) && strings.Trim(indented, fence[:1]+" \t") == "" {
+				ranges = append(ranges, [2]int{fenceStart, end})
+				fence, proseStart = "", end
+			}
+		case len(line)-len(indented) <= 3 && (strings.HasPrefix(indented, "```") || strings.HasPrefix(indented, "~~~")):
+			ranges = append(ranges, codeSpanRanges(text, proseStart, offset)...)
+			fence = indented[:len(indented)-len(strings.TrimLeft(indented, indented[:1]))]
+			fenceStart = offset
+		}
+		offset = end
+	}
+	if fence != "" {
+		// An unclosed fence runs to the end of the document
+		return append(ranges, [2]int{fenceStart, len(text)})
+	}
+	return append(ranges, codeSpanRanges(text, proseStart, len(text))...)
+}
+
+// codeSpanRanges returns the inline code spans in text[start:end]: a run of
+// backticks up to the next run of the same length
+func codeSpanRanges(text string, start, end int) [][2]int {
+	var ranges [][2]int
+	for i := start; i < end; {
+		if text[i] != '`' {
+			i++
+			continue
+		}
+		n := len(text[i:end]) - len(strings.TrimLeft(text[i:end], "`"))
+		closing := -1
+		for j := i + n; j < end; {
+			if text[j] != '`' {
+				j++
+				continue
+			}
+			run := len(text[j:end]) - len(strings.TrimLeft(text[j:end], "`"))
+			if run == n {
+				closing = j
+				break
+			}
+			j += run
+		}
+		if closing == -1 {
+			// Unmatched backticks are literal
+			i += n
+			continue
+		}
+		ranges = append(ranges, [2]int{i, closing + n})
+		i = closing + n
+	}
+	return ranges
+}
+
+// SnippetsConfig defines reusable text for the snippet shortcode
+type SnippetsConfig struct {
+	// Text maps snippet names to markdown
+	// e.g., disclaimer: Opinions are my own.
+	Text map[string]string `yaml:"text"`
+
+	// Append adds snippets to the end of every page in a section, keyed by
+	// the first directory under content/
+	// e.g., blog: [signature, disclaimer]
+	Append map[string][]string `yaml:"append"`
+}
+
+// snippetShortcode inserts a named snippet: {{< snippet "signature" >}}
+func snippetShortcode(ctx shortcodeContext, args []string) (string, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("want one snippet name, got %d arguments", len(args))
+	}
+	return lookupSnippet(ctx, args[0])
+}
+
+// lookupSnippet returns a snippet from the page's first author profile,
+// falling back to the site snippets
+func lookupSnippet(ctx shortcodeContext, name string) (string, error) {
+	if len(ctx.authors) > 0 {
+		if text, ok := ctx.authors[0].Snippets[name]; ok {
+			return text, nil
+		}
+	}
+	if text, ok := ctx.cfg.Snippets.Text[name]; ok {
+		return text, nil
+	}
+	return "", fmt.Errorf("no snippet named %q", name)
+}
+
+// appendSnippets adds the snippets configured for the page's section
+func appendSnippets(ctx shortcodeContext, markdown []byte) []byte {
+	for _, name := range ctx.cfg.Snippets.Append[ctx.cfg.contentSection(ctx.file)] {
+		text, err := lookupSnippet(ctx, name)
+		if err != nil {
+			log.Warnf("%s: appending snippets: %v", ctx.file, err)
+			continue
+		}
+		markdown = append(markdown, "\n\n"+text+"\n"...)
+	}
+	return markdown
+}
+
+// contentSection returns the first directory under the content directory
+// for a file, empty for files directly in it
+// e.g., "content/blog/2025/hello.md" → "blog"
+func (c *Config) contentSection(file string) string {
+	rel := strings.TrimPrefix(filepath.ToSlash(file), filepath.ToSlash(c.contentDir())+"/")
+	section, _, ok := strings.Cut(rel, "/")
+	if !ok {
+		return ""
+	}
+	return section
+}
@@ -0,0 +1,30 @@
+package main
+
+import "testing"
+
+func TestExpandShortcodes(t *testing.T) {
+	ctx := shortcodeContext{
+		cfg:  &Config{Snippets: SnippetsConfig{Text: map[string]string{"sig": "-- Ada"}}},
+		file: "content/blog/post.md",
+	}
+	tests := []struct {
+		name, in, want string
+	}{
+		{"prose", `Bye {{< snippet "sig" >}}`, "Bye -- Ada"},
+		{"code span", "Write `{{< snippet \"sig\" >}}` to sign", "Write `{{< snippet \"sig\" >}}` to sign"},
+		{"double backtick span", "``{{< snippet \"sig\" >}}`` and {{< snippet \"sig\" >}}", "``{{< snippet \"sig\" >}}`` and -- Ada"},
+		{"unmatched backtick", "a ` b {{< snippet \"sig\" >}}", "a ` b -- Ada"},
+		{"fenced block", "```md\n{{< snippet \"sig\" >}}\n```\n{{< snippet \"sig\" >}}", "```md\n{{< snippet \"sig\" >}}\n```\n-- Ada"},
+		{"tilde fence", "~~~\n{{< snippet \"sig\" >}}\n~~~", "~~~\n{{< snippet \"sig\" >}}\n~~~"},
+		{"longer fence", "````\n```\n{{< snippet \"sig\" >}}\n````\n{{< snippet \"sig\" >}}", "````\n```\n{{< snippet \"sig\" >}}\n````\n-- Ada"},
+		{"unclosed fence", "```\n{{< snippet \"sig\" >}}", "```\n{{< snippet \"sig\" >}}"},
+		{"unknown left in place", `{{< nope >}}`, `{{< nope >}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(expandShortcodes(ctx, []byte(tt.in))); got != tt.want {
+				t.Errorf("expandShortcodes(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
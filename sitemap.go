@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/xml"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc      string `xml:"loc"`
+	Lastmod  string `xml:"lastmod,omitempty"`
+	Priority string `xml:"priority,omitempty"`
+}
+
+// writeSitemap writes a sitemap.xml listing pages, except those marked noindex
+// lastmod comes from git history when enabled, otherwise the page date, and
+// priority from how many other pages link to each page
+func writeSitemap(cfg *Config, pages []Page, outputPath string) error {
+	maxPopularity := 0
+	for _, page := range pages {
+		maxPopularity = max(maxPopularity, page.Popularity)
+	}
+
+	set := sitemapURLSet{Xmlns: "http://www.sitemaps.org/schemas/sitemap/0.9"}
+	for _, page := range pages {
+		if page.NoIndex {
+			continue
+		}
+		entry := sitemapURL{
+			Loc:      absURL(cfg, page.URL),
+			Priority: sitemapPriority(page.Popularity, maxPopularity),
+		}
+		lastmod := page.Lastmod
+		if lastmod.IsZero() {
+			lastmod = page.Date
+		}
+		if !lastmod.IsZero() {
+			entry.Lastmod = lastmod.Format(time.RFC3339)
+		}
+		set.URLs = append(set.URLs, entry)
+	}
+
+	data, err := xml.MarshalIndent(set, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append([]byte(xml.Header), data...)
+	if err := os.WriteFile(outputPath, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	logGenerated(outputPath)
+	return nil
+}
+
+// absURL joins the site baseURL and a root-relative URL
+func absURL(cfg *Config, url string) string {
+	return strings.TrimSuffix(cfg.BaseURL, "/") + url
+}
+
+// sitemapPriority weights a page by its inbound links relative to the most
+// linked page, between 0.5 and 1.0
+func sitemapPriority(popularity, maxPopularity int) string {
+	if maxPopularity == 0 {
+		return "0.5"
+	}
+	return strconv.FormatFloat(0.5+0.5*float64(popularity)/float64(maxPopularity), 'f', 1, 64)
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// outputLockFile is held while a build writes the output directories and
+// while a deploy snapshots one, so neither sees the other half done
+var outputLockFile = filepath.Join(".slate", "output.lock")
+
+// outputManifestDir holds the manifest of the last completed build of
+// each output directory
+var outputManifestDir = filepath.Join(".slate", "builds")
+
+// outputManifest lists the files of a completed build with their SHA-256
+type outputManifest struct {
+	Built  time.Time         `json:"built"`
+	Output string            `json:"output"` // the output directory, e.g. public
+	Files  map[string]string `json:"files"`  // slash-separated path → hash
+}
+
+// outputLock is a held outputLockFile
+type outputLock struct {
+	file string
+}
+
+// acquireOutputLock takes outputLockFile, waiting while another slate
+// process holds it. A lock left by a process that no longer runs is taken
+// over.
+func acquireOutputLock(ctx context.Context, purpose string) (*outputLock, error) {
+	if err := os.MkdirAll(filepath.Dir(outputLockFile), 0755); err != nil {
+		return nil, err
+	}
+	waiting := false
+	for {
+		file, err := os.OpenFile(outputLockFile, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			fmt.Fprintf(file, "%d %s\n", os.Getpid(), purpose)
+			file.Close()
+			return &outputLock{file: outputLockFile}, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+
+		pid, holder := readOutputLock()
+		if pid == 0 || !processRunning(pid) {
+			log.Debugf("removing stale %s", outputLockFile)
+			os.Remove(outputLockFile)
+			continue
+		}
+		if !waiting {
+			log.Infof("Waiting for the %s in process %d to finish", holder, pid)
+			waiting = true
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+}
+
+func (l *outputLock) release() {
+	if err := os.Remove(l.file); err != nil && !os.IsNotExist(err) {
+		log.Warnf("removing %s: %v", l.file, err)
+	}
+}
+
+// readOutputLock returns the process holding outputLockFile and what for
+func readOutputLock() (int, string) {
+	data, err := os.ReadFile(outputLockFile)
+	if err != nil {
+		return 0, ""
+	}
+	pid, holder, _ := strings.Cut(strings.TrimSpace(string(data)), " ")
+	n, _ := strconv.Atoi(pid)
+	return n, holder
+}
+
+// processRunning reports whether the process pid exists
+func processRunning(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	err = process.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// outputManifestFile returns where the manifest of an output directory is
+// kept, e.g. .slate/builds/public.json
+func outputManifestFile(dir string) string {
+	return filepath.Join(outputManifestDir, outputKey(dir)+".json")
+}
+
+// forgetOutputManifest removes the manifest of dir before a build writes
+// to it, so until the build completes dir counts as unfinished
+func forgetOutputManifest(dir string) error {
+	if err := os.Remove(outputManifestFile(dir)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// writeOutputManifest records the files of dir after a completed build
+func writeOutputManifest(dir string) (*outputManifest, error) {
+	files, err := listOutput(dir)
+	if err != nil {
+		return nil, err
+	}
+	manifest := &outputManifest{Built: time.Now().UTC(), Output: filepath.ToSlash(dir), Files: map[string]string{}}
+	for name, file := range files {
+		if manifest.Files[name], err = hashFile(file); err != nil {
+			return nil, err
+		}
+	}
+	return manifest, manifest.save(outputManifestFile(dir))
+}
+
+func (m *outputManifest) save(file string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(file, append(data, '\n'), 0644)
+}
+
+// readOutputManifest returns the manifest of the last completed build of
+// dir, an error satisfying os.IsNotExist if there's none
+func readOutputManifest(dir string) (*outputManifest, error) {
+	data, err := os.ReadFile(outputManifestFile(dir))
+	if err != nil {
+		return nil, err
+	}
+	var manifest outputManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("%s: %w", outputManifestFile(dir), err)
+	}
+	return &manifest, nil
+}
+
+// snapshotOutput copies the files of the last completed build of dir into a
+// temporary directory, checking each against the build's manifest, and
+// returns the directory and a function removing it. It holds the output
+// lock while copying, waiting for a running build to finish first.
+func snapshotOutput(ctx context.Context, dir string) (string, func(), error) {
+	lock, err := acquireOutputLock(ctx, "deploy")
+	if err != nil {
+		return "", nil, err
+	}
+	defer lock.release()
+
+	manifest, err := readOutputManifest(dir)
+	if os.IsNotExist(err) {
+		return "", nil, fmt.Errorf("%s/ isn't from a completed build, run `slate build` first", dir)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	snapshot, err := os.MkdirTemp(filepath.Dir(outputLockFile), "snapshot-")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() { os.RemoveAll(snapshot) }
+	for _, name := range sortedKeys(manifest.Files) {
+		source := filepath.Join(dir, filepath.FromSlash(name))
+		sum, err := copyHashed(source, filepath.Join(snapshot, filepath.FromSlash(name)))
+		if err == nil && sum != manifest.Files[name] {
+			err = errors.New("changed since the build")
+		}
+		if err != nil {
+			cleanup()
+			return "", nil, fmt.Errorf("%s differs from the build of %s (%v), run `slate build` again",
+				source, manifest.Built.Local().Format(time.DateTime), err)
+		}
+	}
+	log.Debugf("Snapshot of %d file(s) from the build of %s", len(manifest.Files), manifest.Built.Local().Format(time.DateTime))
+	return snapshot, cleanup, nil
+}
+
+// copyHashed copies source to dest, keeping its modification time for
+// deployers comparing times, and returns the SHA-256 of what it copied
+func copyHashed(source, dest string) (string, error) {
+	in, err := os.Open(source)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return "", err
+	}
+	out, err := os.Create(dest)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(out, h), in); err != nil {
+		out.Close()
+		return "", err
+	}
+	if err := out.Close(); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), os.Chtimes(dest, info.ModTime(), info.ModTime())
+}
+
+func hashFile(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// contentSource provides content mounted into content/ from elsewhere: a
+// module's directory or repository, or the entries of a headless CMS
+type contentSource interface {
+	label() string // e.g. module legal, for messages
+	// root returns the local directory holding the content, refreshed
+	// first when fetch is set
+	root(fetch bool) (string, error)
+	mountPoint() string      // directory under content/
+	overlay() map[string]any // frontmatter set on every page
+}
+
+func (m ContentModule) label() string           { return "module " + m.name() }
+func (m ContentModule) mountPoint() string      { return m.Mount }
+func (m ContentModule) overlay() map[string]any { return m.Frontmatter }
+
+// contentSources returns the modules, then the CMS sources, in the order
+// they're configured
+func (c *Config) contentSources() []contentSource {
+	var sources []contentSource
+	for _, m := range c.Modules {
+		sources = append(sources, m)
+	}
+	for _, s := range c.Sources {
+		sources = append(sources, s)
+	}
+	return sources
+}
+
+// CMSSource pulls the entries of a headless CMS from its JSON API and
+// writes each as a content file, so editors can work in the CMS while the
+// site stays static
+type CMSSource struct {
+	Name string `yaml:"name"`
+	URL  string `yaml:"url"` // endpoint listing the entries
+	// TokenEnv is the environment variable holding the API token, sent as
+	// a bearer token; tokens don't belong in slate.yaml
+	TokenEnv string `yaml:"tokenEnv"`
+	Items    string `yaml:"items"` // dotted path to the entries in the response, e.g. data; the response itself if empty
+	Next     string `yaml:"next"`  // dotted path to the URL of the next page of entries, e.g. links.next
+	// Fields maps frontmatter keys to dotted paths in an entry, e.g.
+	// title: attributes.title. The special keys slug, naming the file,
+	// and body, its content, aren't frontmatter.
+	Fields map[string]string `yaml:"fields"`
+	Format string            `yaml:"format"` // extension of the body's format, md by default, e.g. html
+	Mount  string            `yaml:"mount"`  // directory under content/, e.g. blog
+
+	// Frontmatter is applied over every entry's frontmatter
+	Frontmatter map[string]any `yaml:"frontmatter"`
+}
+
+// sourceCacheDir holds the entries of CMS sources between builds
+var sourceCacheDir = filepath.Join(".slate", "sources")
+
+func (s CMSSource) label() string           { return "source " + s.Name }
+func (s CMSSource) mountPoint() string      { return s.Mount }
+func (s CMSSource) overlay() map[string]any { return s.Frontmatter }
+
+// root writes the entries into .slate/sources/<name>, or keeps the last
+// fetched copy when fetch isn't set or fetching fails
+func (s CMSSource) root(fetch bool) (string, error) {
+	if s.Name == "" || s.URL == "" {
+		return "", fmt.Errorf("missing name or url")
+	}
+	dir := filepath.Join(sourceCacheDir, slugify(s.Name))
+	_, err := os.Stat(dir)
+	fetched := err == nil
+	if fetched && !fetch {
+		return dir, nil
+	}
+
+	if err := s.sync(dir); err != nil {
+		if fetched {
+			log.Warnf("source %s: updating failed, using the last fetched copy: %v", s.Name, err)
+			return dir, nil
+		}
+		return "", err
+	}
+	return dir, nil
+}
+
+// sync fetches every entry and replaces dir with their files at once, so
+// a failure halfway leaves the last copy whole
+func (s CMSSource) sync(dir string) error {
+	entries, err := s.fetchEntries()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(sourceCacheDir, 0755); err != nil {
+		return err
+	}
+	tmp, err := os.MkdirTemp(sourceCacheDir, ".fetch-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	ext := "." + strings.TrimPrefix(s.Format, ".")
+	if s.Format == "" {
+		ext = ".md"
+	}
+	written := map[string]bool{}
+	for i, entry := range entries {
+		content, slug, err := s.entryFile(entry)
+		if err != nil {
+			log.Warnf("source %s: entry %d: %v", s.Name, i+1, err)
+			continue
+		}
+		if written[slug] {
+			log.Warnf("source %s: entry %d: %s is also the slug of an earlier entry, keeping the first", s.Name, i+1, slug)
+			continue
+		}
+		written[slug] = true
+		if err := os.WriteFile(filepath.Join(tmp, slug+ext), content, 0644); err != nil {
+			return err
+		}
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, dir); err != nil {
+		return err
+	}
+	log.Infof("Fetched %d entries from %s", len(written), s.Name)
+	return nil
+}
+
+// fetchEntries requests every page of entries from the API
+func (s CMSSource) fetchEntries() ([]any, error) {
+	client := &http.Client{Timeout: 30 * time.Second}
+	var entries []any
+	seen := map[string]bool{}
+	for url := s.URL; url != "" && !seen[url]; {
+		seen[url] = true
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/json")
+		if s.TokenEnv != "" {
+			token := os.Getenv(s.TokenEnv)
+			if token == "" {
+				return nil, fmt.Errorf("$%s isn't set", s.TokenEnv)
+			}
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("%s: %s %s", url, resp.Status, strings.TrimSpace(string(body[:min(len(body), 200)])))
+		}
+
+		var page any
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, fmt.Errorf("%s: %w", url, err)
+		}
+		items, ok := lookupPath(page, s.Items).([]any)
+		if !ok {
+			return nil, fmt.Errorf("%s: no list of entries at %q", url, s.Items)
+		}
+		entries = append(entries, items...)
+
+		url = ""
+		if s.Next != "" {
+			url, _ = lookupPath(page, s.Next).(string)
+		}
+	}
+	return entries, nil
+}
+
+// entryFile returns the content file of an entry and its slug
+func (s CMSSource) entryFile(entry any) ([]byte, string, error) {
+	fm := map[string]any{}
+	for _, key := range sortedKeys(s.Fields) {
+		if key == "slug" || key == "body" {
+			continue
+		}
+		if value := lookupPath(entry, s.Fields[key]); value != nil {
+			fm[key] = value
+		}
+	}
+
+	var slug string
+	if path := s.Fields["slug"]; path != "" {
+		if value := lookupPath(entry, path); value != nil {
+			slug = fmt.Sprint(value)
+		}
+	}
+	if slug == "" {
+		slug, _ = fm["title"].(string)
+	}
+	slug = slugify(slug)
+	if slug == "" {
+		return nil, "", fmt.Errorf("no slug or title")
+	}
+	var body string
+	if path := s.Fields["body"]; path != "" {
+		body, _ = lookupPath(entry, path).(string)
+	}
+
+	var buf bytes.Buffer
+	if len(fm) > 0 {
+		data, err := yaml.Marshal(fm)
+		if err != nil {
+			return nil, "", err
+		}
+		buf.WriteString("---\n")
+		buf.Write(data)
+		buf.WriteString("---\n")
+	}
+	buf.WriteString(body)
+	return buf.Bytes(), slug, nil
+}
+
+// lookupPath returns the value at a dotted path in decoded JSON, e.g.
+// attributes.tags.0, or nil if there's none. An empty path is the value.
+func lookupPath(value any, path string) any {
+	if path == "" {
+		return value
+	}
+	for _, key := range strings.Split(path, ".") {
+		switch v := value.(type) {
+		case map[string]any:
+			value = v[key]
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil
+			}
+			value = v[i]
+		default:
+			return nil
+		}
+	}
+	return value
+}
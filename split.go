@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"regexp"
+	"strings"
+)
+
+// SplitInfo places one part of a page split at its h2 headings, for
+// "part N of M" navigation
+type SplitInfo struct {
+	Part    int // 1-based, 0 on the page showing every part
+	Parts   []PageLink
+	Prev    *PageLink
+	Next    *PageLink
+	AllURL  string            // every part on one page
+	Anchors map[string]string // heading and other ids → URL of the part holding them
+}
+
+var (
+	// splitPattern matches the start of a top-level h2 in rendered content
+	splitPattern = regexp.MustCompile(`(?m)^<h2[\s>]`)
+	h2Pattern    = regexp.MustCompile(`(?s)<h2[^>]*>(.*?)</h2>`)
+	idPattern    = regexp.MustCompile(`\sid="([^"]+)"`)
+	fragPattern  = regexp.MustCompile(`href="#([^"]+)"`)
+	tagPattern   = regexp.MustCompile(`<[^>]*>`)
+)
+
+// splitPage returns the pages to render for a page: the page itself, or
+// with split set and at least two h2 sections, one page per section and
+// one with every section. The first part keeps the page's URL, the others
+// are written next to it and name it as their canonical URL.
+// e.g., /blog/guide.html → /blog/guide.html, /blog/guide-2.html, ...,
+// and /blog/guide-all.html
+func splitPage(cfg *Config, page Page) []Page {
+	content := string(page.Content)
+	starts := splitPattern.FindAllStringIndex(content, -1)
+	if !page.split || len(starts) < 2 {
+		return []Page{page}
+	}
+
+	base := strings.TrimSuffix(page.URL, ".html")
+	canonical := page.URL
+	if cfg.BaseURL != "" {
+		canonical = absURL(cfg, page.URL)
+	}
+
+	// The introduction belongs to the first part
+	bounds := []int{0}
+	for _, start := range starts[1:] {
+		bounds = append(bounds, start[0])
+	}
+	bounds = append(bounds, len(content))
+
+	var sections []string
+	links := []PageLink{{Title: page.Title, URL: page.URL}}
+	anchors := map[string]string{}
+	for i := range len(bounds) - 1 {
+		section := content[bounds[i]:bounds[i+1]]
+		sections = append(sections, section)
+		if i > 0 {
+			title := page.Title
+			if m := h2Pattern.FindStringSubmatch(section); m != nil {
+				title = html.UnescapeString(tagPattern.ReplaceAllString(m[1], ""))
+			}
+			links = append(links, PageLink{Title: title, URL: fmt.Sprintf("%s-%d.html", base, i+1)})
+		}
+		for _, m := range idPattern.FindAllStringSubmatch(section, -1) {
+			anchors[m[1]] = links[i].URL
+		}
+	}
+
+	allURL := base + "-all.html"
+	var parts []Page
+	for i, section := range sections {
+		part := page
+		part.URL = links[i].URL
+		part.Title = links[i].Title
+		if i > 0 {
+			part.Title = page.Title + ": " + links[i].Title
+			part.Canonical = canonical
+		}
+		// Fragment links to other parts point at the part holding the id
+		part.Content = template.HTML(fragPattern.ReplaceAllStringFunc(section, func(attr string) string {
+			id := fragPattern.FindStringSubmatch(attr)[1]
+			if url, ok := anchors[id]; ok && url != part.URL {
+				return `href="` + url + `#` + id + `"`
+			}
+			return attr
+		}))
+		part.TOC = nil
+		for _, entry := range page.TOC {
+			if url := anchors[entry.ID]; url != part.URL {
+				entry.URL = url
+			}
+			part.TOC = append(part.TOC, entry)
+		}
+
+		info := &SplitInfo{Part: i + 1, Parts: links, AllURL: allURL, Anchors: anchors}
+		if i > 0 {
+			info.Prev = &links[i-1]
+		}
+		if i < len(links)-1 {
+			info.Next = &links[i+1]
+		}
+		part.Split = info
+		parts = append(parts, part)
+	}
+
+	all := page
+	all.URL = allURL
+	all.Canonical = canonical
+	all.Split = &SplitInfo{Parts: links, AllURL: allURL}
+	return append(parts, all)
+}
+
+// partsPartial renders .Split as navigation between the parts of a split
+// page. Links to an id in another part, e.g. from before the page was
+// split, are sent on to that part.
+const partsPartial = `
+{{define "parts"}}{{with .Split}}<nav class="page-parts" aria-label="Parts of this page">
+    {{if .Part}}<p>Part {{.Part}} of {{len .Parts}}</p>{{end}}
+    <ol>{{range .Parts}}
+        <li><a href="{{.URL}}"{{if eq .URL $.URL}} aria-current="page"{{end}}>{{.Title}}</a></li>{{end}}
+    </ol>
+    <p>{{with .Prev}}<a href="{{.URL}}" rel="prev">&larr; {{.Title}}</a> {{end}}{{with .Next}}<a href="{{.URL}}" rel="next">{{.Title}} &rarr;</a> {{end}}{{if .Part}}<a href="{{.AllURL}}">All on one page</a>{{end}}</p>
+</nav>{{if .Part}}
+<script>
+(function () {
+    var anchors = {{.Anchors}};
+    var id = decodeURIComponent(location.hash.slice(1));
+    if (id && !document.getElementById(id) && anchors[id]) {
+        location.replace(anchors[id] + "#" + encodeURIComponent(id));
+    }
+})();
+</script>{{end}}{{end}}{{end}}
+`
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// stagingRoot holds the output directories being built, so a failed build
+// never leaves a half-written site and serve never reads a partial page
+var stagingRoot = filepath.Join(".slate", "staging")
+
+// stagedOutputs maps the staging directories of the running build to the
+// output directories they're synced into
+var stagedOutputs = map[string]string{}
+
+// outputKey names the files slate keeps about an output directory, e.g.
+// public or dist_site
+func outputKey(dir string) string {
+	return strings.ReplaceAll(filepath.ToSlash(filepath.Clean(dir)), "/", "_")
+}
+
+// stagingDir is where dir is built, e.g. .slate/staging/public
+func stagingDir(dir string) string {
+	return filepath.Join(stagingRoot, outputKey(dir))
+}
+
+// derivedCacheDir keeps the files derived from the output, like compressed
+// copies and font subsets, since every build starts from an empty staging
+// directory
+var derivedCacheDir = filepath.Join(".slate", "cache")
+
+// usedDerived holds the cache entries the running build used, the rest are
+// pruned once it's synced
+var usedDerived = map[string]bool{}
+
+// stageOutput returns an empty staging directory to build dir in, so files
+// the build no longer produces are gone from dir once it's synced. Retrying
+// keeps what the failed build left instead, since the pages that didn't fail
+// were never synced, or starts from a copy of dir without it.
+func stageOutput(dir string, retry bool) (string, error) {
+	staging := stagingDir(dir)
+	stagedOutputs[staging] = dir
+	if _, err := os.Stat(staging); err == nil && retry {
+		return staging, nil
+	}
+	if err := os.RemoveAll(staging); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(staging, 0755); err != nil {
+		return "", err
+	}
+	if !retry {
+		return staging, nil
+	}
+	files, err := listOutput(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	for name, file := range files {
+		// Modification times are kept, caches compare them
+		if _, err := copyHashed(file, filepath.Join(staging, filepath.FromSlash(name))); err != nil {
+			return "", err
+		}
+	}
+	return staging, nil
+}
+
+// syncOutput makes dir match staging and removes staging. Each changed file
+// replaces the old one with a rename, so a server reads either; unchanged
+// files are left alone, keeping their modification times for deploy tools.
+// Files the build no longer produces are deleted if built, the manifest of
+// the last completed build, lists them as they are. Any other file is moved
+// to the trash, as dir may hold files slate didn't write, e.g. with -o.
+func syncOutput(staging, dir string, built *outputManifest, trash *trashBatch) error {
+	files, err := listOutput(staging)
+	if err != nil {
+		return err
+	}
+	current, err := listOutput(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	changed := 0
+	for name, file := range files {
+		dest := filepath.Join(dir, filepath.FromSlash(name))
+		if same, err := sameContent(file, dest); err != nil {
+			return err
+		} else if same {
+			continue
+		}
+		if err := publishFile(file, dest); err != nil {
+			return err
+		}
+		changed++
+	}
+	removed := 0
+	for _, name := range sortedKeys(current) {
+		if _, ok := files[name]; ok {
+			continue
+		}
+		file := current[name]
+		ours := false
+		if built != nil && built.Files[name] != "" {
+			hash, err := hashFile(file)
+			if err != nil {
+				return err
+			}
+			ours = hash == built.Files[name]
+		}
+		if ours {
+			err = os.Remove(file)
+		} else {
+			err = trash.remove(file)
+		}
+		if err != nil {
+			return err
+		}
+		removed++
+	}
+	if err := removeEmptyDirs(dir); err != nil {
+		return err
+	}
+	log.Debugf("Synced %d changed and %d removed file(s) into %s/", changed, removed, dir)
+
+	delete(stagedOutputs, staging)
+	return os.RemoveAll(staging)
+}
+
+// removeEmptyDirs removes the directories below dir left empty, e.g. the
+// page of a deleted tag
+func removeEmptyDirs(dir string) error {
+	var dirs []string
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && p != dir {
+			dirs = append(dirs, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	// Deepest first, so parents are empty by the time they're checked
+	for i := len(dirs) - 1; i >= 0; i-- {
+		entries, err := os.ReadDir(dirs[i])
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			if err := os.Remove(dirs[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// cachedDerived writes dest from the cache entry of kind named key, creating
+// the entry with create first when there's none. It reports whether create
+// ran.
+func cachedDerived(kind, key, dest string, create func(file string) error) (bool, error) {
+	cached := filepath.Join(derivedCacheDir, kind, key)
+	usedDerived[cached] = true
+	created := false
+	if _, err := os.Stat(cached); err != nil {
+		if err := os.MkdirAll(filepath.Dir(cached), 0755); err != nil {
+			return false, err
+		}
+		tmp := cached + ".tmp"
+		if err := create(tmp); err != nil {
+			os.Remove(tmp)
+			return false, err
+		}
+		if err := os.Rename(tmp, cached); err != nil {
+			return false, err
+		}
+		created = true
+	}
+	_, err := copyHashed(cached, dest)
+	return created, err
+}
+
+// pruneDerivedCache removes the cache entries the build didn't use, which
+// belong to files that have since changed
+func pruneDerivedCache() error {
+	defer clear(usedDerived)
+	err := filepath.WalkDir(derivedCacheDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || usedDerived[p] {
+			return err
+		}
+		return os.Remove(p)
+	})
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// publishFile moves file to dest atomically. Staging can be on another
+// filesystem than the output, e.g. with -o, so a failed rename falls back to
+// copying next to dest and renaming that.
+func publishFile(file, dest string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(file, dest); err == nil {
+		return nil
+	}
+
+	info, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+	in, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	tmp, err := os.CreateTemp(filepath.Dir(dest), ".slate-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, in); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), info.Mode().Perm()); err != nil {
+		return err
+	}
+	if err := os.Chtimes(tmp.Name(), info.ModTime(), info.ModTime()); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dest)
+}
+
+// sameContent reports whether dest exists with the content of file
+func sameContent(file, dest string) (bool, error) {
+	a, err := os.Stat(file)
+	if err != nil {
+		return false, err
+	}
+	b, err := os.Stat(dest)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if a.Size() != b.Size() {
+		return false, nil
+	}
+	x, err := os.ReadFile(file)
+	if err != nil {
+		return false, err
+	}
+	y, err := os.ReadFile(dest)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(x, y), nil
+}
+
+// publishedPath returns where a file written to a staging directory ends
+// up, for messages
+func publishedPath(file string) string {
+	for staging, dir := range stagedOutputs {
+		if rel, err := filepath.Rel(staging, file); err == nil && !strings.HasPrefix(rel, "..") {
+			return filepath.Join(dir, rel)
+		}
+	}
+	return file
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSyncOutput(t *testing.T) {
+	t.Chdir(t.TempDir())
+	write := func(name, content string, mtime time.Time) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(name, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mtime := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// The last build wrote these
+	built := map[string]string{
+		"index.html":             "home v1",
+		"same.html":              "same",
+		"tags/old/index.html":    "old tag",
+		"edited-since-build.txt": "built",
+	}
+	manifest := &outputManifest{Files: map[string]string{}}
+	for name, content := range built {
+		write(filepath.Join("public", name), content, mtime)
+		manifest.Files[name], _ = hashFile(filepath.Join("public", name))
+	}
+	write(filepath.Join("public", "edited-since-build.txt"), "by hand", mtime)
+	write(filepath.Join("public", "notes.txt"), "not slate's", mtime)
+
+	// The new build changes index.html within the same second, keeping its size
+	staging := stagingDir("public")
+	write(filepath.Join(staging, "index.html"), "home v2", mtime)
+	write(filepath.Join(staging, "same.html"), "same", mtime)
+	write(filepath.Join(staging, "blog", "new.html"), "new", mtime)
+
+	trash := newTrashBatch(&Config{}, "build")
+	if err := syncOutput(staging, "public", manifest, trash); err != nil {
+		t.Fatal(err)
+	}
+
+	trashed := map[string]bool{}
+	for _, entry := range trash.manifest.Entries {
+		trashed[filepath.ToSlash(entry.Original)] = true
+	}
+	tests := []struct {
+		name        string
+		wantContent string // empty when the file is gone
+		wantTrashed bool
+	}{
+		{"index.html", "home v2", false},
+		{"same.html", "same", false},
+		{"blog/new.html", "new", false},
+		{"tags/old/index.html", "", false},
+		{"edited-since-build.txt", "", true},
+		{"notes.txt", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join("public", filepath.FromSlash(tt.name))
+			data, err := os.ReadFile(file)
+			switch {
+			case tt.wantContent == "" && !os.IsNotExist(err):
+				t.Errorf("%s still in the output (err %v)", file, err)
+			case tt.wantContent != "" && string(data) != tt.wantContent:
+				t.Errorf("%s = %q (err %v), want %q", file, data, err, tt.wantContent)
+			}
+			if trashed[filepath.ToSlash(file)] != tt.wantTrashed {
+				t.Errorf("%s trashed: %v, want %v", file, trashed[filepath.ToSlash(file)], tt.wantTrashed)
+			}
+		})
+	}
+	if _, err := os.Stat(filepath.Join("public", "tags")); !os.IsNotExist(err) {
+		t.Errorf("empty public/tags/ left behind (err %v)", err)
+	}
+	if _, err := os.Stat(staging); !os.IsNotExist(err) {
+		t.Errorf("staging directory left behind (err %v)", err)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// reportedLinkLoops holds the symlink loops already warned about, as every
+// walk of a directory finds the same ones
+var reportedLinkLoops = map[string]bool{}
+
+// walkFollowingLinks is filepath.WalkDir, except that symlinks to
+// directories are walked as if they were directories, with paths under the
+// link. This lets content stored elsewhere, e.g. an Obsidian vault, be
+// linked into content/. A link to a directory it's inside of is skipped
+// with a warning rather than walked forever.
+func walkFollowingLinks(root string, fn fs.WalkDirFunc) error {
+	real, err := realPath(root)
+	if err != nil {
+		// Let fn decide, e.g. a missing static/ is fine
+		return filepath.WalkDir(root, fn)
+	}
+	return walkLinked(root, real, []string{real}, fn)
+}
+
+// walkLinked walks the real directory dir, reporting its files under root.
+// chain holds the real directories being walked, outermost first.
+func walkLinked(root, dir string, chain []string, fn fs.WalkDirFunc) error {
+	return filepath.WalkDir(dir, func(file string, d fs.DirEntry, err error) error {
+		rel, relErr := filepath.Rel(dir, file)
+		if relErr != nil {
+			return relErr
+		}
+		path := filepath.Join(root, rel)
+		if err != nil || d.Type()&fs.ModeSymlink == 0 {
+			return fn(path, d, err)
+		}
+
+		info, err := os.Stat(file)
+		if err != nil || !info.IsDir() {
+			// Broken links and links to files are left to fn
+			return fn(path, d, err)
+		}
+		target, err := realPath(file)
+		if err != nil {
+			return fn(path, d, err)
+		}
+		parent, err := realPath(filepath.Dir(file))
+		if err != nil {
+			return fn(path, d, err)
+		}
+		for _, walked := range append(chain, parent) {
+			if within(walked, target) {
+				if !reportedLinkLoops[path] {
+					log.Warnf("skipping %s: it links to %s, which contains it", path, target)
+					reportedLinkLoops[path] = true
+				}
+				return nil
+			}
+		}
+		return walkLinked(path, target, append(chain[:len(chain):len(chain)], target), fn)
+	})
+}
+
+// realPath returns the absolute path of a file with every symlink resolved
+func realPath(file string) (string, error) {
+	real, err := filepath.EvalSymlinks(file)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Abs(real)
+}
+
+// within reports whether path is dir or inside it
+func within(path, dir string) bool {
+	return path == dir || strings.HasPrefix(path, dir+string(filepath.Separator))
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// TagsConfig controls how tags are normalized before taxonomy pages are built
+type TagsConfig struct {
+	// Aliases maps a tag (matched case-insensitively) to its canonical name
+	// e.g., golang: Go
+	Aliases map[string]string `yaml:"aliases"`
+}
+
+// TagPage is the data passed to templates/tag.html
+type TagPage struct {
+	Name  string
+	URL   string
+	Pages []Page
+}
+
+// tagNormalizer folds case and applies aliases so near-duplicate tags
+// ("golang", "Go", "GO") end up on a single taxonomy page
+type tagNormalizer struct {
+	aliases map[string]string // folded tag → canonical name
+	names   map[string]string // folded canonical name → display name
+}
+
+func newTagNormalizer(cfg TagsConfig) *tagNormalizer {
+	n := &tagNormalizer{
+		aliases: map[string]string{},
+		names:   map[string]string{},
+	}
+	// In order, so aliases differing only in case resolve the same each build
+	for _, from := range sortedKeys(cfg.Aliases) {
+		to := cfg.Aliases[from]
+		if earlier, ok := n.aliases[foldTag(from)]; ok && earlier != to {
+			log.Warnf("tags.aliases: %s is aliased to both %q and %q, using %q", from, earlier, to, to)
+		}
+		n.aliases[foldTag(from)] = to
+		n.names[foldTag(to)] = to
+	}
+	return n
+}
+
+// normalize returns the display name for tag
+// Without an alias, the first spelling seen wins
+func (n *tagNormalizer) normalize(tag string) string {
+	key := foldTag(tag)
+	if alias, ok := n.aliases[key]; ok {
+		tag = alias
+		key = foldTag(alias)
+	}
+	if name, ok := n.names[key]; ok {
+		return name
+	}
+	name := strings.TrimSpace(tag)
+	n.names[key] = name
+	return name
+}
+
+// normalizeAll normalizes a page's tags, dropping empties and duplicates
+func (n *tagNormalizer) normalizeAll(tags []string) []string {
+	var result []string
+	seen := map[string]bool{}
+	for _, tag := range tags {
+		if strings.TrimSpace(tag) == "" {
+			continue
+		}
+		name := n.normalize(tag)
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		result = append(result, name)
+	}
+	return result
+}
+
+func foldTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// collectTagPages groups pages by tag, sorted by tag name
+func collectTagPages(pages []Page) []TagPage {
+	byTag := map[string][]Page{}
+	for _, page := range pages {
+		for _, tag := range page.Tags {
+			byTag[tag] = append(byTag[tag], page)
+		}
+	}
+
+	names := make([]string, 0, len(byTag))
+	for name := range byTag {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// Tags that slugify alike, e.g. "C++" and "C#", share one listing page,
+	// so their pages are listed together under the first name
+	var tagPages []TagPage
+	byURL := map[string]int{}
+	for _, name := range names {
+		url := tagURL(name)
+		if i, ok := byURL[url]; ok {
+			log.Warnf("tags %q and %q share the URL %s, listing them together", tagPages[i].Name, name, url)
+			for _, page := range byTag[name] {
+				if !slices.ContainsFunc(tagPages[i].Pages, func(p Page) bool { return p.Path == page.Path }) {
+					tagPages[i].Pages = append(tagPages[i].Pages, page)
+				}
+			}
+			continue
+		}
+		byURL[url] = len(tagPages)
+		tagPages = append(tagPages, TagPage{Name: name, URL: url, Pages: byTag[name]})
+	}
+	for _, tagPage := range tagPages {
+		sort.Slice(tagPage.Pages, func(i, j int) bool {
+			return listedBefore(tagPage.Pages[i], tagPage.Pages[j])
+		})
+	}
+	return tagPages
+}
+
+// tagURL returns the listing page URL for a tag
+// e.g., "Go Tooling" → "/tags/go-tooling/"
+func tagURL(tag string) string {
+	return "/tags/" + slugify(tag) + "/"
+}
+
+// slugify lowercases s and replaces runs of non-alphanumerics with hyphens
+func slugify(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
+
+// Category is a node in the hierarchical category taxonomy
+// e.g., "go/tooling" has Name "tooling" and breadcrumbs Go → Tooling
+type Category struct {
+	Name        string // last path segment
+	Path        string // full path, e.g. "go/tooling"
+	URL         string
+	Breadcrumbs []PageLink // from the top-level category down to this one
+}
+
+// CategoryPage is the data passed to templates/category.html
+type CategoryPage struct {
+	Category
+	Children []Category
+	Pages    []Page // pages in this category or any descendant
+}
+
+// parseCategory splits a frontmatter category path into a Category
+func parseCategory(path string) (Category, bool) {
+	var segments []string
+	for _, segment := range strings.Split(path, "/") {
+		if segment = strings.TrimSpace(segment); segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	if len(segments) == 0 {
+		return Category{}, false
+	}
+
+	var c Category
+	url := "/categories/"
+	for i, segment := range segments {
+		url += slugify(segment) + "/"
+		c.Breadcrumbs = append(c.Breadcrumbs, PageLink{Title: segment, URL: url})
+		if i == len(segments)-1 {
+			c.Name = segment
+		}
+	}
+	c.Path = strings.Join(segments, "/")
+	c.URL = url
+	return c, true
+}
+
+// parseCategories parses a page's category paths, dropping empties and duplicates
+func parseCategories(paths []string) []Category {
+	var categories []Category
+	seen := map[string]bool{}
+	for _, path := range paths {
+		c, ok := parseCategory(path)
+		if !ok || seen[c.URL] {
+			continue
+		}
+		seen[c.URL] = true
+		categories = append(categories, c)
+	}
+	return categories
+}
+
+// collectCategoryPages builds a listing page for every category and each of
+// its ancestors, sorted by path
+func collectCategoryPages(pages []Page) []CategoryPage {
+	byURL := map[string]*CategoryPage{}
+	children := map[string]map[string]Category{}
+
+	for _, page := range pages {
+		added := map[string]bool{}
+		for _, c := range page.Categories {
+			// Walk from the top-level category down, registering each level
+			var parent string
+			for depth := range c.Breadcrumbs {
+				node := Category{
+					Name:        c.Breadcrumbs[depth].Title,
+					URL:         c.Breadcrumbs[depth].URL,
+					Breadcrumbs: c.Breadcrumbs[:depth+1],
+				}
+				var names []string
+				for _, crumb := range node.Breadcrumbs {
+					names = append(names, crumb.Title)
+				}
+				node.Path = strings.Join(names, "/")
+
+				if byURL[node.URL] == nil {
+					byURL[node.URL] = &CategoryPage{Category: node}
+				}
+				if !added[node.URL] {
+					byURL[node.URL].Pages = append(byURL[node.URL].Pages, page)
+					added[node.URL] = true
+				}
+				if parent != "" {
+					if children[parent] == nil {
+						children[parent] = map[string]Category{}
+					}
+					children[parent][node.URL] = node
+				}
+				parent = node.URL
+			}
+		}
+	}
+
+	var categoryPages []CategoryPage
+	for url, categoryPage := range byURL {
+		for _, child := range children[url] {
+			categoryPage.Children = append(categoryPage.Children, child)
+		}
+		sort.Slice(categoryPage.Children, func(i, j int) bool {
+			return categoryPage.Children[i].Name < categoryPage.Children[j].Name
+		})
+		sort.Slice(categoryPage.Pages, func(i, j int) bool {
+			return listedBefore(categoryPage.Pages[i], categoryPage.Pages[j])
+		})
+		categoryPages = append(categoryPages, *categoryPage)
+	}
+	sort.Slice(categoryPages, func(i, j int) bool {
+		return categoryPages[i].Path < categoryPages[j].Path
+	})
+	return categoryPages
+}
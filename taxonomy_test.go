@@ -0,0 +1,129 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSlugify(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Go", "go"},
+		{"Go Tooling", "go-tooling"},
+		{"  spaced  out  ", "spaced-out"},
+		{"C++", "c"},
+		{"C#", "c"},
+		{"node.js", "node-js"},
+		{"--dashes--", "dashes"},
+		{"Café au lait", "café-au-lait"},
+		{"v1.2", "v1-2"},
+		{"!!!", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := slugify(tt.in); got != tt.want {
+				t.Errorf("slugify(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTagNormalizer(t *testing.T) {
+	tests := []struct {
+		name    string
+		aliases map[string]string
+		tags    []string
+		want    []string
+	}{
+		{"first spelling wins", nil, []string{"Go", "go", "GO"}, []string{"Go"}},
+		{"trims", nil, []string{"  tooling "}, []string{"tooling"}},
+		{"drops empties", nil, []string{"", "  ", "go"}, []string{"go"}},
+		{"alias", map[string]string{"golang": "Go"}, []string{"golang", "go"}, []string{"Go"}},
+		{"alias case-insensitive", map[string]string{"golang": "Go"}, []string{"GoLang"}, []string{"Go"}},
+		{"alias wins over earlier spelling", map[string]string{"golang": "Go"}, []string{"go", "golang"}, []string{"Go"}},
+		{"keeps order", nil, []string{"b", "a"}, []string{"b", "a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := newTagNormalizer(TagsConfig{Aliases: tt.aliases})
+			if got := n.normalizeAll(tt.tags); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("normalizeAll(%q) = %q, want %q", tt.tags, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCategory(t *testing.T) {
+	tests := []struct {
+		path   string
+		want   Category
+		wantOK bool
+	}{
+		{"", Category{}, false},
+		{" / ", Category{}, false},
+		{"Go", Category{
+			Name: "Go", Path: "Go", URL: "/categories/go/",
+			Breadcrumbs: []PageLink{{Title: "Go", URL: "/categories/go/"}},
+		}, true},
+		{"Go/ Go Tooling /", Category{
+			Name: "Go Tooling", Path: "Go/Go Tooling", URL: "/categories/go/go-tooling/",
+			Breadcrumbs: []PageLink{
+				{Title: "Go", URL: "/categories/go/"},
+				{Title: "Go Tooling", URL: "/categories/go/go-tooling/"},
+			},
+		}, true},
+		{"a//b", Category{
+			Name: "b", Path: "a/b", URL: "/categories/a/b/",
+			Breadcrumbs: []PageLink{
+				{Title: "a", URL: "/categories/a/"},
+				{Title: "b", URL: "/categories/a/b/"},
+			},
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, ok := parseCategory(tt.path)
+			if ok != tt.wantOK || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseCategory(%q) = %+v, %v, want %+v, %v", tt.path, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestCollectTagPages(t *testing.T) {
+	a := Page{Path: "a.md", Tags: []string{"C++", "Go"}, Date: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	b := Page{Path: "b.md", Tags: []string{"C#"}, Date: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	tests := []struct {
+		name  string
+		pages []Page
+		want  map[string][]string // URL → tag name and page paths
+	}{
+		{"one per tag", []Page{a}, map[string][]string{
+			"/tags/c/":  {"C++", "a.md"},
+			"/tags/go/": {"Go", "a.md"},
+		}},
+		{"same URL merged", []Page{a, b}, map[string][]string{
+			"/tags/c/":  {"C#", "a.md", "b.md"},
+			"/tags/go/": {"Go", "a.md"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := map[string][]string{}
+			for _, tagPage := range collectTagPages(tt.pages) {
+				if _, ok := got[tagPage.URL]; ok {
+					t.Errorf("two tag pages at %s", tagPage.URL)
+				}
+				got[tagPage.URL] = []string{tagPage.Name}
+				for _, page := range tagPage.Pages {
+					got[tagPage.URL] = append(got[tagPage.URL], page.Path)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("collectTagPages() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// templateFiles maps the names of the parsed templates to their files, for
+// error messages
+var templateFiles = map[string]string{}
+
+// templateErrorPattern matches the location Go's template errors start
+// with, e.g. template: post.html:12:5: executing "post.html" at <.Nope>: ...
+// or html/template:post.html:3: ... for escaping errors
+var templateErrorPattern = regexp.MustCompile(`(?s)^(?:html/)?template: ?([^:]+):(\d+):(?:(\d+):)? ?(.*)$`)
+
+// templateError wraps a template error so it names the template's file
+// instead of its name and shows the lines around the failing one
+type templateError struct {
+	msg string
+	err error
+}
+
+func (e templateError) Error() string { return e.msg }
+func (e templateError) Unwrap() error { return e.err }
+
+// withTemplateContext returns err with the template file and the lines
+// around the failing one, or err itself when it has no location
+func withTemplateContext(err error) error {
+	m := templateErrorPattern.FindStringSubmatch(err.Error())
+	if m == nil {
+		return err
+	}
+	label, source, ok := templateSource(m[1])
+	if !ok {
+		return err
+	}
+	line, _ := strconv.Atoi(m[2])
+	location := fmt.Sprintf("%s:%d", label, line)
+	if m[3] != "" {
+		location += ":" + m[3]
+	}
+	msg := location + ": " + m[4]
+	if snippet := sourceSnippet(source, line); snippet != "" {
+		msg += "\n" + snippet
+	}
+	return templateError{msg: msg, err: err}
+}
+
+// templateSource returns the file a template was parsed from, or what
+// stands in for it, and its text
+func templateSource(name string) (string, string, bool) {
+	if n, ok := strings.CutPrefix(name, "builtin-"); ok {
+		i, err := strconv.Atoi(n)
+		if err != nil || i < 0 || i >= len(builtinTemplates) {
+			return "", "", false
+		}
+		return "built-in partials", builtinTemplates[i], true
+	}
+	file, ok := templateFiles[name]
+	if !ok {
+		return "", "", false
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return file, "", true
+	}
+	return file, string(data), true
+}
+
+// sourceSnippet returns the lines around line of source, numbered, with the
+// line itself marked
+func sourceSnippet(source string, line int) string {
+	lines := strings.Split(source, "\n")
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+	first, last := max(1, line-2), min(len(lines), line+2)
+	width := len(strconv.Itoa(last))
+	var b strings.Builder
+	for n := first; n <= last; n++ {
+		marker := " "
+		if n == line {
+			marker = ">"
+		}
+		fmt.Fprintf(&b, "  %s %*d | %s\n", marker, width, n, lines[n-1])
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// builtinPartials are available to every template and can be overridden
+// by defining a template of the same name in templates/partials/
+const builtinPartials = `
+{{define "head"}}{{with canonical .}}
+<link rel="canonical" href="{{.}}">{{end}}{{with robots .}}
+<meta name="robots" content="{{.}}">{{end}}{{with site.Head.Favicon}}
+<link rel="icon" href="{{.}}">{{end}}{{with site.Head.AppleTouchIcon}}
+<link rel="apple-touch-icon" href="{{.}}">{{end}}{{with description .}}
+<meta name="description" content="{{.}}">{{end}}{{with ogImage .}}
+<meta property="og:image" content="{{.}}">{{end}}{{with jsonLD .}}
+<script type="application/ld+json">{{.}}</script>{{end}}{{with mathHead .}}
+{{.}}{{end}}{{with mermaidHead .}}
+{{.}}{{end}}{{range $name, $content := site.Head.Meta}}
+<meta name="{{$name}}" content="{{$content}}">{{end}}{{range $name, $content := verificationMeta}}
+<meta name="{{$name}}" content="{{$content}}">{{end}}{{range site.Head.Snippets}}
+{{safeHTML .}}{{end}}
+{{end}}
+`
+
+// builtinTemplates are parsed ahead of the partials in templates/partials/
+var builtinTemplates = []string{builtinPartials, offlineBannerPartial, tocPartial, partsPartial, suggestEditPartial}
+
+// templateFuncs returns the functions available to every template
+func templateFuncs(cfg *Config) template.FuncMap {
+	return template.FuncMap{
+		"site": func() *Config {
+			return cfg
+		},
+		// description returns the page description, falling back to the site default
+		"description": func(data any) string {
+			if page, ok := data.(Page); ok && page.Description != "" {
+				return page.Description
+			}
+			return cfg.Head.Description
+		},
+		// ogImage returns the page's social card URL, absolute when baseURL is set
+		"ogImage": func(data any) string {
+			if page, ok := data.(Page); ok && page.OGImage != "" {
+				return absURL(cfg, page.OGImage)
+			}
+			return ""
+		},
+		// jsonLD returns Schema.org structured data for a page, see jsonld.go
+		"jsonLD": func(data any) map[string]any {
+			return jsonLD(cfg, data)
+		},
+		// canonical returns the URL search engines should index instead of
+		// this page, e.g. the first part of a split page
+		"canonical": func(data any) string {
+			if page, ok := data.(Page); ok {
+				return page.Canonical
+			}
+			return ""
+		},
+		// robots returns the content of the page's robots meta tag, empty
+		// when search engines may index it and follow its links
+		"robots": func(data any) string {
+			page, ok := data.(Page)
+			if !ok {
+				return ""
+			}
+			var directives []string
+			if page.NoIndex {
+				directives = append(directives, "noindex")
+			}
+			if page.NoFollow {
+				directives = append(directives, "nofollow")
+			}
+			return strings.Join(directives, ", ")
+		},
+		// mathHead returns the math renderer's stylesheet and scripts for
+		// pages with math
+		"mathHead": func(data any) template.HTML {
+			if page, ok := data.(Page); ok && cfg.Math.Enabled && page.Math {
+				return mathHead(cfg.Math)
+			}
+			return ""
+		},
+		// mermaidHead returns the Mermaid script for pages with diagrams
+		// left for the browser to draw
+		"mermaidHead": func(data any) template.HTML {
+			if page, ok := data.(Page); ok && page.Mermaid {
+				return mermaidHead()
+			}
+			return ""
+		},
+		"safeHTML": func(s string) template.HTML {
+			return template.HTML(s)
+		},
+		"verificationMeta": func() map[string]string {
+			return verificationMeta(cfg)
+		},
+		// popular returns up to n of the most linked pages
+		"popular": func(n int) []Page {
+			return popularPages[:min(n, len(popularPages))]
+		},
+		// featured returns the blog posts marked featured, in list order
+		"featured": func() []Page {
+			return featuredPosts
+		},
+		// asset returns the fingerprinted URL of a file in static/
+		"asset": func(name string) (string, error) {
+			return builtAssets.url(name)
+		},
+		// cached renders a partial once per build for each set of arguments,
+		// bound to the site templates by parseTemplate
+		"cached": func(name string, args ...any) (template.HTML, error) {
+			return "", fmt.Errorf("cached %q: only available in site templates", name)
+		},
+		// readCSV and readJSON read data files in the project, see data.go
+		"readCSV":   readCSV,
+		"readJSON":  readJSON,
+		"tagURL":    tagURL,
+		"daysSince": daysSince,
+		"buildTime": func() time.Time { return buildTime },
+	}
+}
+
+// parseTemplate parses templates/<name> together with the built-in partials
+// and any partials found in templates/partials/
+func parseTemplate(cfg *Config, name string) (*template.Template, error) {
+	tmpl := template.New(name).Funcs(templateFuncs(cfg))
+	tmpl.Funcs(template.FuncMap{"cached": cachedFunc(tmpl)})
+	// Named apart from name, so errors in them aren't taken for its lines
+	for i, partial := range builtinTemplates {
+		if _, err := tmpl.New(fmt.Sprintf("builtin-%d", i)).Parse(partial); err != nil {
+			return nil, withTemplateContext(err)
+		}
+	}
+
+	partials, err := filepath.Glob(filepath.Join(cfg.templateDir(), "partials", "*.html"))
+	if err != nil {
+		return nil, err
+	}
+	file := filepath.Join(cfg.templateDir(), name)
+	for _, f := range append(partials, file) {
+		templateFiles[filepath.Base(f)] = f
+	}
+	if len(partials) > 0 {
+		if _, err := tmpl.ParseFiles(partials...); err != nil {
+			return nil, withTemplateContext(err)
+		}
+	}
+
+	if _, err := tmpl.ParseFiles(file); err != nil {
+		return nil, withTemplateContext(err)
+	}
+	return tmpl, nil
+}
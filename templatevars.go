@@ -0,0 +1,390 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"text/template/parse"
+)
+
+// templateDataTypes records the type of the data each template was
+// executed with, so the fields its templates read can be checked
+var templateDataTypes = map[string]reflect.Type{}
+
+// templateVar is a field or method a template reads
+type templateVar struct {
+	Template string `json:"template"` // page template, e.g. post.html
+	Type     string `json:"type"`     // type the field is read on, e.g. Page, or ? when unknown
+	Field    string `json:"field"`
+	Location string `json:"location"` // first read, e.g. partials/nav.html:3:12
+	OK       bool   `json:"ok"`       // the field exists, or its type is unknown
+}
+
+// themeVarsCmd reports the fields each page template of a theme reads,
+// following partials, and which of them slate's data doesn't have
+func themeVarsCmd(args []string) {
+	flags := flag.NewFlagSet("theme vars", flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "print the report as JSON")
+	static := flags.String("static", "", "static files used by the theme, defaults to static/ next to the templates")
+	flags.Parse(args)
+
+	theme := "templates"
+	if flags.NArg() > 0 {
+		theme = flags.Arg(0)
+	}
+	if *static == "" {
+		*static = filepath.Join(filepath.Dir(filepath.Clean(theme)), "static")
+	}
+
+	// The fixture build records which data each template gets, before
+	// executing it, so templates reading missing fields are still analyzed
+	site, err := renderFixtureSite(theme, *static)
+	os.RemoveAll(site)
+	if err != nil {
+		log.Warnf("theme vars: %v", err)
+	}
+	vars, err := themeVars(theme)
+	if err != nil {
+		log.Errorf("theme vars: %v", err)
+		os.Exit(1)
+	}
+
+	if porcelainOut != nil {
+		for _, v := range vars {
+			state := "ok"
+			if !v.OK {
+				state = "missing"
+			}
+			record("var", v.Template, v.Type, v.Field, state, v.Location)
+		}
+	} else if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(vars); err != nil {
+			log.Errorf("theme vars: %v", err)
+			os.Exit(1)
+		}
+	} else {
+		printTemplateVars(vars)
+	}
+	if slices.ContainsFunc(vars, func(v templateVar) bool { return !v.OK }) {
+		os.Exit(1)
+	}
+}
+
+// printTemplateVars lists the fields read per template and type
+func printTemplateVars(vars []templateVar) {
+	template, typ := "", ""
+	var fields []string
+	flush := func() {
+		if len(fields) > 0 {
+			fmt.Printf("  %s: %s\n", typ, strings.Join(fields, ", "))
+		}
+		fields = nil
+	}
+	for _, v := range vars {
+		if v.Template != template {
+			flush()
+			template, typ = v.Template, ""
+			fmt.Println(template)
+		}
+		if v.Type != typ {
+			flush()
+			typ = v.Type
+		}
+		if v.OK {
+			fields = append(fields, v.Field)
+		} else {
+			fields = append(fields, v.Field+" (missing, "+v.Location+")")
+		}
+	}
+	flush()
+}
+
+// themeVars analyzes every page template in dir against the data type it
+// was last executed with. Fields of templates that weren't executed are
+// listed on type ?.
+func themeVars(dir string) ([]templateVar, error) {
+	pages, err := filepath.Glob(filepath.Join(dir, "*.html"))
+	if err != nil {
+		return nil, err
+	}
+	partials, err := filepath.Glob(filepath.Join(dir, "partials", "*.html"))
+	if err != nil {
+		return nil, err
+	}
+
+	// Later definitions replace earlier ones, as in parseTemplate
+	shared := map[string]*parse.Tree{}
+	for i, text := range builtinTemplates {
+		if err := parseTrees(shared, fmt.Sprintf("builtin-%d", i), "built-in partials", text); err != nil {
+			return nil, err
+		}
+	}
+	for _, file := range partials {
+		if err := parseTreeFile(shared, dir, file); err != nil {
+			return nil, err
+		}
+	}
+
+	site := reflect.TypeOf(templateFuncs(&Config{})["site"]).Out(0)
+	var vars []templateVar
+	for _, file := range pages {
+		name := filepath.Base(file)
+		trees := maps.Clone(shared)
+		if err := parseTreeFile(trees, dir, file); err != nil {
+			return nil, err
+		}
+		a := &varAnalyzer{template: name, trees: trees, site: site, seen: map[string]templateVar{}, visited: map[string]bool{}}
+		a.tree(name, templateDataTypes[name])
+		for _, key := range sortedKeys(a.seen) {
+			vars = append(vars, a.seen[key])
+		}
+	}
+	return vars, nil
+}
+
+func parseTreeFile(trees map[string]*parse.Tree, dir, file string) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(dir, file)
+	if err != nil {
+		return err
+	}
+	// Templates are named after their file name, as ParseFiles does
+	return parseTrees(trees, filepath.Base(file), filepath.ToSlash(rel), string(data))
+}
+
+// parseTrees parses text into trees without checking function names, which
+// belong to slate. Locations in them start with file.
+func parseTrees(trees map[string]*parse.Tree, name, file, text string) error {
+	set := map[string]*parse.Tree{}
+	t := parse.New(name)
+	t.Mode = parse.SkipFuncCheck
+	if _, err := t.Parse(text, "", "", set); err != nil {
+		return fmt.Errorf("%s: %w", file, err)
+	}
+	for name, tree := range set {
+		tree.ParseName = file
+		trees[name] = tree
+	}
+	return nil
+}
+
+// varAnalyzer walks a page template and the templates it invokes, tracking
+// the type of dot and of variables to resolve field reads
+type varAnalyzer struct {
+	template string
+	trees    map[string]*parse.Tree
+	site     reflect.Type
+	seen     map[string]templateVar // type.field → first read
+	visited  map[string]bool        // template and dot type already walked
+}
+
+func (a *varAnalyzer) tree(name string, dot reflect.Type) {
+	tree := a.trees[name]
+	key := fmt.Sprint(name, dot)
+	if tree == nil || tree.Root == nil || a.visited[key] {
+		return
+	}
+	a.visited[key] = true
+	a.list(tree, tree.Root, dot, map[string]reflect.Type{"$": dot})
+}
+
+func (a *varAnalyzer) list(tree *parse.Tree, list *parse.ListNode, dot reflect.Type, vars map[string]reflect.Type) {
+	if list == nil {
+		return
+	}
+	for _, node := range list.Nodes {
+		switch n := node.(type) {
+		case *parse.ActionNode:
+			a.pipe(tree, n.Pipe, dot, vars)
+		case *parse.IfNode:
+			a.pipe(tree, n.Pipe, dot, vars)
+			a.list(tree, n.List, dot, maps.Clone(vars))
+			a.list(tree, n.ElseList, dot, maps.Clone(vars))
+		case *parse.WithNode:
+			inner := maps.Clone(vars)
+			typ := a.pipe(tree, n.Pipe, dot, inner)
+			a.list(tree, n.List, typ, inner)
+			a.list(tree, n.ElseList, dot, maps.Clone(vars))
+		case *parse.RangeNode:
+			inner := maps.Clone(vars)
+			typ := a.pipe(tree, n.Pipe, dot, inner)
+			key, elem := rangeTypes(typ)
+			switch len(n.Pipe.Decl) {
+			case 1:
+				inner[n.Pipe.Decl[0].Ident[0]] = elem
+			case 2:
+				inner[n.Pipe.Decl[0].Ident[0]] = key
+				inner[n.Pipe.Decl[1].Ident[0]] = elem
+			}
+			a.list(tree, n.List, elem, inner)
+			a.list(tree, n.ElseList, dot, maps.Clone(vars))
+		case *parse.TemplateNode:
+			var typ reflect.Type
+			if n.Pipe != nil {
+				typ = a.pipe(tree, n.Pipe, dot, vars)
+			}
+			a.tree(n.Name, typ)
+		}
+	}
+}
+
+// pipe records the fields a pipeline reads and returns the type of its
+// result, declaring its variables in vars
+func (a *varAnalyzer) pipe(tree *parse.Tree, pipe *parse.PipeNode, dot reflect.Type, vars map[string]reflect.Type) reflect.Type {
+	var typ reflect.Type
+	for _, cmd := range pipe.Cmds {
+		typ = a.command(tree, cmd, dot, vars)
+	}
+	for _, v := range pipe.Decl {
+		vars[v.Ident[0]] = typ
+	}
+	return typ
+}
+
+func (a *varAnalyzer) command(tree *parse.Tree, cmd *parse.CommandNode, dot reflect.Type, vars map[string]reflect.Type) reflect.Type {
+	for _, arg := range cmd.Args[1:] {
+		a.arg(tree, arg, dot, vars)
+	}
+	if ident, ok := cmd.Args[0].(*parse.IdentifierNode); ok {
+		return a.call(ident.Ident, cmd.Args[1:], tree, dot, vars)
+	}
+	return a.arg(tree, cmd.Args[0], dot, vars)
+}
+
+// call returns the result type of a function, when known
+func (a *varAnalyzer) call(name string, args []parse.Node, tree *parse.Tree, dot reflect.Type, vars map[string]reflect.Type) reflect.Type {
+	switch name {
+	case "site":
+		return a.site
+	case "index", "slice":
+		if len(args) == 0 {
+			return nil
+		}
+		typ := indirect(a.arg(tree, args[0], dot, vars))
+		if name == "slice" || typ == nil {
+			return typ
+		}
+		if k := typ.Kind(); k == reflect.Slice || k == reflect.Array || k == reflect.Map {
+			return typ.Elem()
+		}
+		return nil
+	}
+	if fn, ok := templateFuncs(&Config{})[name]; ok {
+		if out := reflect.TypeOf(fn); out.NumOut() > 0 {
+			return out.Out(0)
+		}
+	}
+	return nil
+}
+
+// arg records the fields an argument reads and returns its type
+func (a *varAnalyzer) arg(tree *parse.Tree, node parse.Node, dot reflect.Type, vars map[string]reflect.Type) reflect.Type {
+	switch n := node.(type) {
+	case *parse.DotNode:
+		return dot
+	case *parse.FieldNode:
+		return a.fields(tree, n, dot, n.Ident)
+	case *parse.VariableNode:
+		return a.fields(tree, n, vars[n.Ident[0]], n.Ident[1:])
+	case *parse.ChainNode:
+		var base reflect.Type
+		if ident, ok := n.Node.(*parse.IdentifierNode); ok {
+			base = a.call(ident.Ident, nil, tree, dot, vars)
+		} else {
+			base = a.arg(tree, n.Node, dot, vars)
+		}
+		return a.fields(tree, n, base, n.Field)
+	case *parse.PipeNode:
+		return a.pipe(tree, n, dot, vars)
+	case *parse.IdentifierNode:
+		return a.call(n.Ident, nil, tree, dot, vars)
+	}
+	return nil
+}
+
+// fields records each field of a chain such as .Series.Name read on typ
+// and returns the type of the last one, nil once it's unknown
+func (a *varAnalyzer) fields(tree *parse.Tree, node parse.Node, typ reflect.Type, names []string) reflect.Type {
+	for _, name := range names {
+		typ = indirect(typ)
+		next, ok := fieldType(typ, name)
+		label := "?"
+		if typ != nil {
+			label = typ.Name()
+			if label == "" {
+				label = typ.String()
+			}
+		}
+		if _, seen := a.seen[label+"."+name]; !seen {
+			location, _ := tree.ErrorContext(node)
+			a.seen[label+"."+name] = templateVar{Template: a.template, Type: label, Field: name, Location: location, OK: ok}
+		}
+		if !ok {
+			// The rest of the chain reads a field that doesn't exist
+			return nil
+		}
+		typ = next
+	}
+	return typ
+}
+
+// fieldType returns the type of a field, method or map entry name of typ
+// A nil typ or an interface can hold anything, so those are ok with an
+// unknown type.
+func fieldType(typ reflect.Type, name string) (reflect.Type, bool) {
+	if typ == nil || typ.Kind() == reflect.Interface {
+		return nil, true
+	}
+	if typ.Kind() == reflect.Map {
+		return typ.Elem(), true
+	}
+	for _, t := range []reflect.Type{typ, reflect.PointerTo(typ)} {
+		if m, ok := t.MethodByName(name); ok {
+			if m.Type.NumOut() == 0 {
+				return nil, true
+			}
+			return m.Type.Out(0), true
+		}
+	}
+	if typ.Kind() == reflect.Struct {
+		if f, ok := typ.FieldByName(name); ok && f.IsExported() {
+			return f.Type, true
+		}
+	}
+	return nil, false
+}
+
+// rangeTypes returns the key and element types ranging over typ yields
+func rangeTypes(typ reflect.Type) (reflect.Type, reflect.Type) {
+	typ = indirect(typ)
+	if typ == nil {
+		return nil, nil
+	}
+	switch typ.Kind() {
+	case reflect.Slice, reflect.Array:
+		return reflect.TypeFor[int](), typ.Elem()
+	case reflect.Map:
+		return typ.Key(), typ.Elem()
+	case reflect.Int:
+		return typ, typ
+	}
+	return nil, nil
+}
+
+func indirect(typ reflect.Type) reflect.Type {
+	for typ != nil && typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	return typ
+}
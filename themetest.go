@@ -0,0 +1,373 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// requiredTemplates are needed to build any site
+var requiredTemplates = []string{"home.html", "post.html", "blog_index.html"}
+
+// optionalTemplates enable more pages when present
+var optionalTemplates = []string{"tag.html", "category.html", "author.html", "series.html", "digest.html", "fallback.html"}
+
+// themeFixtures is the content a theme is rendered against, covering the
+// page kinds and the frontmatter the templates can show
+var themeFixtures = map[string]string{
+	"slate.yaml": `title: Theme Test
+baseURL: https://example.com
+head:
+  description: Fixture site for theme tests
+accessibility:
+  landmarks: true
+toc:
+  sections: [blog]
+`,
+	"data/authors.yaml": `jane:
+  name: Jane Doe
+  bio: Writes the fixture posts.
+`,
+	"content/index.md": `# Theme Test
+
+The home page, linking to the [blog](/blog/).
+`,
+	"content/blog/first-post.md": `---
+title: First Post
+date: 2025-01-10
+description: The first fixture post.
+tags: [go, themes]
+categories: [guides/basics]
+author: jane
+series: Fixture Series
+seriesPart: 1
+---
+
+Opening paragraph with **bold**, *emphasis*, ` + "`code`" + ` and a [link](second-post.md).
+
+## Setup
+
+A list:
+
+- one
+- two
+
+### Details
+
+> A blockquote.
+
+## Code
+
+` + "```go\nfunc main() {\n\tfmt.Println(\"hello\")\n}\n```" + `
+
+## Wrap-up
+
+The end.
+`,
+	"content/blog/second-post.md": `---
+title: Second Post
+date: 2025-02-01
+tags: [go]
+author: jane
+series: Fixture Series
+seriesPart: 2
+---
+
+A shorter post with an image:
+
+![A placeholder](/images/placeholder.png)
+
+| Column | Value |
+| ------ | ----- |
+| a      | 1     |
+`,
+}
+
+// templateCallPattern matches partial invocations, e.g. {{template "nav" .}}
+var templateCallPattern = regexp.MustCompile(`\{\{-?\s*(?:template|block)\s+"([^"]+)"`)
+
+func themeCmd(args []string) {
+	if len(args) > 0 && args[0] == "vars" {
+		themeVarsCmd(args[1:])
+		return
+	}
+	if len(args) == 0 || args[0] != "test" {
+		log.Errorf("Usage: slate theme test [--update] [--snapshots dir] [templates-dir]\n       slate theme vars [--json] [templates-dir]")
+		os.Exit(2)
+	}
+
+	flags := flag.NewFlagSet("theme test", flag.ExitOnError)
+	update := flags.Bool("update", false, "write the rendered pages as the new snapshots")
+	snapshots := flags.String("snapshots", "theme-snapshots", "directory holding the expected pages")
+	static := flags.String("static", "", "static files used by the theme, defaults to static/ next to the templates")
+	flags.Parse(args[1:])
+
+	theme := "templates"
+	if flags.NArg() > 0 {
+		theme = flags.Arg(0)
+	}
+	if *static == "" {
+		*static = filepath.Join(filepath.Dir(filepath.Clean(theme)), "static")
+	}
+
+	ok, err := testTheme(theme, *static, *snapshots, *update)
+	if err != nil {
+		log.Errorf("theme test: %v", err)
+		os.Exit(1)
+	}
+	if !ok {
+		os.Exit(1)
+	}
+}
+
+// testTheme validates the theme in dir, renders it against the fixtures and
+// compares the pages with the snapshots, or replaces them when update is set
+// It reports false when a check failed.
+func testTheme(dir, static, snapshots string, update bool) (bool, error) {
+	ok := true
+	for _, name := range requiredTemplates {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			log.Errorf("missing required template %s", name)
+			ok = false
+		}
+	}
+	if !ok {
+		return false, nil
+	}
+	for _, name := range optionalTemplates {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			log.Infof("No %s, those pages won't be rendered", name)
+		}
+	}
+
+	unused, err := unusedPartials(dir)
+	if err != nil {
+		return false, err
+	}
+	for _, partial := range unused {
+		log.Warnf("partials/%s is never used", partial)
+	}
+
+	site, err := renderFixtureSite(dir, static)
+	if err != nil {
+		return false, err
+	}
+	defer os.RemoveAll(site)
+
+	pages, err := listOutput(filepath.Join(site, "public"))
+	if err != nil {
+		return false, err
+	}
+	for name := range pages {
+		if filepath.Ext(name) != ".html" {
+			delete(pages, name)
+		}
+	}
+
+	if update {
+		cfg, err := loadConfig()
+		if err != nil {
+			return false, fmt.Errorf("loading %s: %w", configFile, err)
+		}
+		trash := newTrashBatch(cfg, "theme-test")
+		if err := trash.remove(snapshots); err != nil {
+			return false, err
+		}
+		if err := trash.close(); err != nil {
+			return false, err
+		}
+		for name, file := range pages {
+			if err := copyFile(file, filepath.Join(snapshots, filepath.FromSlash(name))); err != nil {
+				return false, err
+			}
+		}
+		log.Infof("Wrote %d snapshot(s) to %s/", len(pages), snapshots)
+		record("snapshots", snapshots, len(pages))
+		return ok, nil
+	}
+	if _, err := os.Stat(snapshots); os.IsNotExist(err) {
+		return false, fmt.Errorf("no snapshots in %s/, run with --update to create them", snapshots)
+	}
+	return compareSnapshots(pages, snapshots) && ok, nil
+}
+
+// renderFixtureSite builds the fixtures with the theme in a temporary
+// directory and returns it
+func renderFixtureSite(theme, static string) (string, error) {
+	site, err := os.MkdirTemp("", "slate-theme-test")
+	if err != nil {
+		return "", err
+	}
+	for name, content := range themeFixtures {
+		path := filepath.Join(site, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return site, err
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			return site, err
+		}
+	}
+	if err := copyDir(theme, filepath.Join(site, "templates")); err != nil {
+		return site, err
+	}
+	if _, err := os.Stat(static); err == nil {
+		if err := copyDir(static, filepath.Join(site, "static")); err != nil {
+			return site, err
+		}
+	} else {
+		// Themes without static files get the starter stylesheet
+		if err := os.MkdirAll(filepath.Join(site, "static"), 0755); err != nil {
+			return site, err
+		}
+		if err := os.WriteFile(filepath.Join(site, "static", "styles.css"), []byte(starterCSS), 0644); err != nil {
+			return site, err
+		}
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return site, err
+	}
+	if err := os.Chdir(site); err != nil {
+		return site, err
+	}
+	defer os.Chdir(wd)
+
+	// The fixture build's own records would describe a temporary site
+	records := porcelainOut
+	porcelainOut = nil
+	defer func() { porcelainOut = records }()
+
+	log.Infof("Rendering fixtures with %s", theme)
+	if err := build(context.Background(), buildOptions{}); err != nil {
+		return site, fmt.Errorf("building fixtures: %w", err)
+	}
+	return site, nil
+}
+
+// compareSnapshots reports every page that differs from, or is missing
+// in, the snapshots and every snapshot no longer rendered
+func compareSnapshots(pages map[string]string, snapshots string) bool {
+	expected, err := listOutput(snapshots)
+	if err != nil {
+		log.Errorf("reading snapshots: %v", err)
+		return false
+	}
+
+	ok := true
+	for _, name := range sortedKeys(pages) {
+		want, err := os.ReadFile(expected[name])
+		if expected[name] == "" || err != nil {
+			log.Errorf("%s: new page, not in the snapshots", name)
+			record("snapshot", name, "new")
+			ok = false
+			continue
+		}
+		got, err := os.ReadFile(pages[name])
+		if err != nil {
+			log.Errorf("%s: %v", name, err)
+			ok = false
+			continue
+		}
+		if line, wantLine, gotLine, differ := firstDifference(want, got); differ {
+			log.Errorf("%s:%d: snapshot differs\n  want: %s\n  got:  %s", name, line, wantLine, gotLine)
+			record("snapshot", name, "differs", line)
+			ok = false
+		}
+	}
+	for _, name := range sortedKeys(expected) {
+		if _, found := pages[name]; !found {
+			log.Errorf("%s: in the snapshots but no longer rendered", name)
+			record("snapshot", name, "removed")
+			ok = false
+		}
+	}
+	if ok {
+		log.Infof("All %d page(s) match the snapshots", len(pages))
+	}
+	return ok
+}
+
+// firstDifference returns the first line, 1-based, where a and b differ
+func firstDifference(a, b []byte) (int, string, string, bool) {
+	if bytes.Equal(a, b) {
+		return 0, "", "", false
+	}
+	linesA := strings.Split(string(a), "\n")
+	linesB := strings.Split(string(b), "\n")
+	for i := 0; i < max(len(linesA), len(linesB)); i++ {
+		var lineA, lineB string
+		if i < len(linesA) {
+			lineA = linesA[i]
+		}
+		if i < len(linesB) {
+			lineB = linesB[i]
+		}
+		if lineA != lineB {
+			return i + 1, strings.TrimSpace(lineA), strings.TrimSpace(lineB), true
+		}
+	}
+	return len(linesA), "", "", true
+}
+
+// unusedPartials returns the files in dir/partials whose templates no
+// template or partial invokes
+func unusedPartials(dir string) ([]string, error) {
+	partials, err := filepath.Glob(filepath.Join(dir, "partials", "*.html"))
+	if err != nil {
+		return nil, err
+	}
+	pages, err := filepath.Glob(filepath.Join(dir, "*.html"))
+	if err != nil {
+		return nil, err
+	}
+
+	used := map[string]bool{}
+	for _, file := range append(pages, partials...) {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range templateCallPattern.FindAllStringSubmatch(string(data), -1) {
+			used[m[1]] = true
+		}
+	}
+
+	var unused []string
+	for _, file := range partials {
+		tmpl, err := template.New(filepath.Base(file)).Funcs(templateFuncs(&Config{})).ParseFiles(file)
+		if err != nil {
+			return nil, err
+		}
+		names := []string{filepath.Base(file)}
+		for _, t := range tmpl.Templates() {
+			names = append(names, t.Name())
+		}
+		if !slices.ContainsFunc(names, func(name string) bool { return used[name] }) {
+			unused = append(unused, filepath.Base(file))
+		}
+	}
+	return unused, nil
+}
+
+// copyDir copies the files under source to dest
+func copyDir(source, dest string) error {
+	return filepath.WalkDir(source, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(source, path)
+		if err != nil {
+			return err
+		}
+		return copyFile(path, filepath.Join(dest, rel))
+	})
+}
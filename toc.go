@@ -0,0 +1,142 @@
+package main
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+// TOCConfig enables the table of contents sidebar for long pages
+type TOCConfig struct {
+	Sections    []string `yaml:"sections"`    // directories under content/, e.g. [docs]
+	MinHeadings int      `yaml:"minHeadings"` // fewer headings means no TOC, default 3
+	MaxLevel    int      `yaml:"maxLevel"`    // deepest heading level listed, default 3
+}
+
+// TOCEntry is a heading listed in a page's table of contents
+type TOCEntry struct {
+	Level int // 2 for h2, 3 for h3, ...
+	ID    string
+	Title string
+	URL   string // part of a split page holding the heading, empty for this page
+}
+
+// tocKey stores the headings of the document being converted
+var tocKey = parser.NewContextKey()
+
+// tocTransformer collects h2 and deeper headings with their generated ids
+type tocTransformer struct{}
+
+func (t *tocTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	var entries []TOCEntry
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		heading, ok := n.(*ast.Heading)
+		if !entering || !ok {
+			return ast.WalkContinue, nil
+		}
+		id, _ := heading.AttributeString("id")
+		if heading.Level >= 2 && id != nil {
+			entries = append(entries, TOCEntry{
+				Level: heading.Level,
+				ID:    string(id.([]byte)),
+				Title: nodeText(heading, reader.Source()),
+			})
+		}
+		return ast.WalkSkipChildren, nil
+	})
+	pc.Set(tocKey, entries)
+}
+
+// nodeText returns the plain text of n's inline content
+func nodeText(n ast.Node, source []byte) string {
+	var b strings.Builder
+	ast.Walk(n, func(child ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch c := child.(type) {
+		case *ast.Text:
+			b.Write(c.Segment.Value(source))
+			if c.SoftLineBreak() {
+				b.WriteByte(' ')
+			}
+		case *ast.String:
+			b.Write(c.Value)
+		}
+		return ast.WalkContinue, nil
+	})
+	return b.String()
+}
+
+// tocEntries returns the headings collected while converting a document
+func tocEntries(pc parser.Context) []TOCEntry {
+	entries, _ := pc.Get(tocKey).([]TOCEntry)
+	return entries
+}
+
+// pageTOC returns the table of contents for a page in section, nil unless
+// the section has TOCs enabled and the page has enough headings
+func pageTOC(cfg TOCConfig, section string, entries []TOCEntry) []TOCEntry {
+	if !slices.Contains(cfg.Sections, section) {
+		return nil
+	}
+	maxLevel := cfg.MaxLevel
+	if maxLevel == 0 {
+		maxLevel = 3
+	}
+	minHeadings := cfg.MinHeadings
+	if minHeadings == 0 {
+		minHeadings = 3
+	}
+
+	var toc []TOCEntry
+	for _, entry := range entries {
+		if entry.Level <= maxLevel {
+			toc = append(toc, entry)
+		}
+	}
+	if len(toc) < minHeadings {
+		return nil
+	}
+	return toc
+}
+
+// tocPartial renders .TOC as a sidebar whose links are marked with
+// aria-current while their section is on screen
+const tocPartial = `
+{{define "toc"}}{{with .TOC}}<nav class="toc" aria-label="Table of contents" data-scrollspy>
+    <ul>{{range .}}
+        <li class="toc-h{{.Level}}"><a href="{{.URL}}#{{.ID}}" data-scrollspy-target="{{.ID}}">{{.Title}}</a></li>{{end}}
+    </ul>
+</nav>
+<script>
+(function () {
+    var nav = document.currentScript.previousElementSibling;
+    var links = nav.querySelectorAll("[data-scrollspy-target]");
+    if (!("IntersectionObserver" in window) || !links.length) return;
+    var visible = {};
+    function update() {
+        var current = null;
+        links.forEach(function (link) {
+            if (!current && visible[link.dataset.scrollspyTarget]) current = link;
+        });
+        if (!current) return;
+        links.forEach(function (link) {
+            if (link === current) link.setAttribute("aria-current", "true");
+            else link.removeAttribute("aria-current");
+        });
+    }
+    var observer = new IntersectionObserver(function (entries) {
+        entries.forEach(function (entry) { visible[entry.target.id] = entry.isIntersecting; });
+        update();
+    }, { rootMargin: "0px 0px -60% 0px" });
+    links.forEach(function (link) {
+        var target = document.getElementById(link.dataset.scrollspyTarget);
+        if (target) observer.observe(target);
+    });
+})();
+</script>{{end}}{{end}}
+`
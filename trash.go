@@ -0,0 +1,313 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// trashDir holds what destructive commands removed, one batch per run
+var trashDir = filepath.Join(".slate", "trash")
+
+// TrashConfig controls whether destructive commands keep what they remove
+type TrashConfig struct {
+	Disabled bool `yaml:"disabled"` // delete outright
+	Keep     int  `yaml:"keep"`     // batches kept, default 20
+}
+
+// trashEntry is one removed file or directory
+type trashEntry struct {
+	Stored   string `json:"stored"`             // path inside the batch
+	Original string `json:"original,omitempty"` // local path it was removed from
+	Target   string `json:"target,omitempty"`   // or deploy target and key it was deleted from
+	Key      string `json:"key,omitempty"`
+}
+
+// trashManifest describes a batch, stored as its manifest.json
+type trashManifest struct {
+	Command string       `json:"command"`
+	Time    time.Time    `json:"time"`
+	Entries []trashEntry `json:"entries"`
+}
+
+// trashBatch collects what one command removes, nil when the trash is
+// disabled so files are deleted outright
+type trashBatch struct {
+	dir  string
+	keep int
+
+	mu       sync.Mutex
+	manifest trashManifest
+}
+
+func newTrashBatch(cfg *Config, command string) *trashBatch {
+	if cfg.Trash.Disabled {
+		return nil
+	}
+	now := time.Now()
+	keep := cfg.Trash.Keep
+	if keep <= 0 {
+		keep = 20
+	}
+	return &trashBatch{
+		dir:      filepath.Join(trashDir, now.Format("20060102-150405.000")+"-"+command),
+		keep:     keep,
+		manifest: trashManifest{Command: command, Time: now},
+	}
+}
+
+// remove moves a local file or directory into the batch
+func (b *trashBatch) remove(path string) error {
+	if b == nil {
+		return os.RemoveAll(path)
+	}
+	if _, err := os.Lstat(path); os.IsNotExist(err) {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	stored, err := trashedPath(path)
+	if err != nil {
+		return err
+	}
+	stored = filepath.Join("files", stored)
+	dest := filepath.Join(b.dir, stored)
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(path, dest); err != nil {
+		// Across file systems, copy then delete
+		if err := copyDir(path, dest); err != nil {
+			return err
+		}
+		if err := os.RemoveAll(path); err != nil {
+			return err
+		}
+	}
+	b.manifest.Entries = append(b.manifest.Entries, trashEntry{Stored: filepath.ToSlash(stored), Original: path})
+	return nil
+}
+
+// trashedPath returns where a local path is kept inside a batch: relative
+// to the project, or under outside/ with its absolute path, never with ..
+func trashedPath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	if rel, err := filepath.Rel(wd, abs); err == nil && filepath.IsLocal(rel) {
+		return rel, nil
+	}
+	return filepath.Join("outside", filepath.FromSlash(batchRelative(filepath.ToSlash(strings.TrimPrefix(abs, filepath.VolumeName(abs)))))), nil
+}
+
+// batchRelative makes a slash-separated path relative, dropping leading
+// slashes and .. elements, so it can't point out of a batch
+func batchRelative(p string) string {
+	return strings.TrimPrefix(path.Clean("/"+p), "/")
+}
+
+// keepRemote stores a copy of a file about to be deleted from a deploy target
+func (b *trashBatch) keepRemote(target, key string, data []byte) error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	stored := filepath.Join("remote", slugify(target), filepath.FromSlash(batchRelative(key)))
+	dest := filepath.Join(b.dir, stored)
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(dest, data, 0644); err != nil {
+		return err
+	}
+	b.manifest.Entries = append(b.manifest.Entries, trashEntry{Stored: filepath.ToSlash(stored), Target: target, Key: key})
+	return nil
+}
+
+// close writes the batch's manifest, if anything was removed, and prunes
+// the oldest batches
+func (b *trashBatch) close() error {
+	if b == nil || len(b.manifest.Entries) == 0 {
+		return nil
+	}
+	data, err := json.MarshalIndent(b.manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(b.dir, "manifest.json"), append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	log.Infof("Moved %d item(s) to %s, `slate restore` brings them back", len(b.manifest.Entries), b.dir)
+	record("trashed", b.dir, len(b.manifest.Entries))
+
+	batches, err := trashBatches()
+	if err != nil {
+		return err
+	}
+	for len(batches) > b.keep {
+		if err := os.RemoveAll(filepath.Join(trashDir, batches[0])); err != nil {
+			return err
+		}
+		batches = batches[1:]
+	}
+	return nil
+}
+
+// trashBatches returns the batch IDs, oldest first
+func trashBatches() ([]string, error) {
+	entries, err := os.ReadDir(trashDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, entry := range entries {
+		if _, err := os.Stat(filepath.Join(trashDir, entry.Name(), "manifest.json")); err == nil {
+			ids = append(ids, entry.Name())
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+func readTrashManifest(id string) (trashManifest, error) {
+	var m trashManifest
+	data, err := os.ReadFile(filepath.Join(trashDir, id, "manifest.json"))
+	if err != nil {
+		return m, err
+	}
+	return m, json.Unmarshal(data, &m)
+}
+
+func restoreCmd(args []string) {
+	flags := flag.NewFlagSet("restore", flag.ExitOnError)
+	list := flags.Bool("list", false, "list the batches in the trash")
+	flags.Parse(args)
+
+	ids, err := trashBatches()
+	if err != nil {
+		log.Errorf("reading %s: %v", trashDir, err)
+		os.Exit(1)
+	}
+	if len(ids) == 0 {
+		log.Infof("The trash is empty")
+		return
+	}
+
+	if *list {
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "BATCH\tCOMMAND\tITEMS")
+		for _, id := range slices.Backward(ids) {
+			m, err := readTrashManifest(id)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "%s\t%s\t%d\n", id, m.Command, len(m.Entries))
+		}
+		w.Flush()
+		return
+	}
+
+	// The most recent batch unless one is named
+	id := ids[len(ids)-1]
+	if flags.NArg() > 0 {
+		id = flags.Arg(0)
+		if !slices.Contains(ids, id) {
+			log.Errorf("no batch %s in the trash, see `slate restore --list`", id)
+			os.Exit(1)
+		}
+	}
+	if err := restoreBatch(id); err != nil {
+		log.Errorf("restoring %s: %v", id, err)
+		os.Exit(1)
+	}
+}
+
+// restoreBatch puts back everything in a batch and removes it from the
+// trash. Local paths that exist again are left alone and the batch is kept.
+func restoreBatch(id string) error {
+	m, err := readTrashManifest(id)
+	if err != nil {
+		return err
+	}
+
+	var cfg *Config
+	targets := map[string]restorer{}
+	skipped := 0
+	for _, entry := range m.Entries {
+		stored := filepath.Join(trashDir, id, filepath.FromSlash(entry.Stored))
+		if entry.Target == "" {
+			if _, err := os.Lstat(entry.Original); err == nil {
+				log.Warnf("%s exists, not restoring it", entry.Original)
+				skipped++
+				continue
+			}
+			if err := os.MkdirAll(filepath.Dir(entry.Original), 0755); err != nil {
+				return err
+			}
+			if err := os.Rename(stored, entry.Original); err != nil {
+				return err
+			}
+			log.Infof("Restored: %s", entry.Original)
+			record("restored", entry.Original)
+			continue
+		}
+
+		// Remote files are uploaded again
+		d, ok := targets[entry.Target]
+		if !ok {
+			if cfg == nil {
+				if cfg, err = loadConfig(); err != nil {
+					return err
+				}
+			}
+			target, err := cfg.Deploy.target(entry.Target)
+			if err != nil {
+				return err
+			}
+			newDeployer, ok := deployers[target.Type]
+			if !ok {
+				return fmt.Errorf("target %s: unknown type %q", target.Name, target.Type)
+			}
+			dd, err := newDeployer(target)
+			if err != nil {
+				return fmt.Errorf("target %s: %w", target.Name, err)
+			}
+			if d, ok = dd.(restorer); !ok {
+				return fmt.Errorf("target %s: restoring to %s targets isn't supported", target.Name, target.Type)
+			}
+			targets[entry.Target] = d
+		}
+		opts := deployOptions{Types: mimeTypes(cfg), Cache: cfg.Deploy.CacheControl}
+		if err := d.restore(context.Background(), entry.Key, stored, opts); err != nil {
+			return fmt.Errorf("restoring %s to %s: %w", entry.Key, entry.Target, err)
+		}
+		log.Infof("Restored: %s on %s", entry.Key, entry.Target)
+		record("restored", entry.Key, entry.Target)
+	}
+
+	if skipped > 0 {
+		return errors.New("some items were not restored, the batch stays in the trash")
+	}
+	return os.RemoveAll(filepath.Join(trashDir, id))
+}
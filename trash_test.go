@@ -0,0 +1,59 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBatchRelative(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"blog/a.html", "blog/a.html"},
+		{"/blog/a.html", "blog/a.html"},
+		{"../../etc/passwd", "etc/passwd"},
+		{"blog/../../a.html", "a.html"},
+		{"./a//b.html", "a/b.html"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := batchRelative(tt.in); got != tt.want {
+				t.Errorf("batchRelative(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrashedPath(t *testing.T) {
+	t.Chdir(t.TempDir())
+	// The working directory as the OS reports it, with symlinks resolved
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	outside := filepath.Join(filepath.Dir(dir), "other", "a.html")
+	tests := []struct {
+		path, want string
+	}{
+		{"public/a.html", filepath.Join("public", "a.html")},
+		{filepath.Join(dir, "public", "a.html"), filepath.Join("public", "a.html")},
+		{outside, filepath.Join("outside", filepath.FromSlash(batchRelative(filepath.ToSlash(outside))))},
+		{filepath.Join("..", "a.html"), filepath.Join("outside", filepath.FromSlash(batchRelative(filepath.ToSlash(filepath.Join(filepath.Dir(dir), "a.html")))))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := trashedPath(tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("trashedPath(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+			if !filepath.IsLocal(got) {
+				t.Errorf("trashedPath(%q) = %q, not inside the batch", tt.path, got)
+			}
+		})
+	}
+}
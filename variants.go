@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Variant builds the same content into another output with its own
+// settings, e.g. a branded partner mirror of the docs
+type Variant struct {
+	Name      string   `yaml:"name"`
+	Output    string   `yaml:"output"`    // defaults to public-<name>
+	Config    string   `yaml:"config"`    // slate.yaml overrides, e.g. slate.partner.yaml
+	BaseURL   string   `yaml:"baseURL"`   // overrides baseURL
+	Title     string   `yaml:"title"`     // overrides title
+	Templates string   `yaml:"templates"` // template directory, defaults to templates
+	Exclude   []string `yaml:"exclude"`   // sections left out, e.g. [internal]
+}
+
+// variantConfig returns the configuration for building v
+// The variant's config file is applied on top of the site configuration,
+// then its baseURL and title
+func (c *Config) variantConfig(v Variant) (*Config, error) {
+	if v.Name == "" {
+		return nil, fmt.Errorf("variant without a name")
+	}
+
+	// Start from a fresh copy so overrides don't leak between variants
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	if v.Config != "" {
+		data, err := os.ReadFile(v.Config)
+		if err != nil {
+			return nil, err
+		}
+		if err := yaml.Unmarshal(data, cfg); err != nil {
+			return nil, fmt.Errorf("%s: %w", v.Config, err)
+		}
+	}
+	if v.BaseURL != "" {
+		cfg.BaseURL = v.BaseURL
+	}
+	if v.Title != "" {
+		cfg.Title = v.Title
+	}
+	if v.Output == "" {
+		v.Output = c.outputDir() + "-" + slugify(v.Name)
+	}
+	if v.Output == c.outputDir() {
+		return nil, fmt.Errorf("variant %s: output %s is the main site's output", v.Name, v.Output)
+	}
+	cfg.Variants = nil
+	cfg.variant = &v
+	return cfg, nil
+}
+
+// outputDir is where the site is generated
+func (c *Config) outputDir() string {
+	if c.variant != nil {
+		return c.variant.Output
+	}
+	return dirOrDefault(c.Dirs.Output, "public")
+}
+
+// buildDir is where a build writes the output, its staging directory
+func (c *Config) buildDir() string {
+	if c.staging != "" {
+		return c.staging
+	}
+	return c.outputDir()
+}
+
+// templateDir is where the site's templates are read from
+func (c *Config) templateDir() string {
+	if c.variant != nil && c.variant.Templates != "" {
+		return c.variant.Templates
+	}
+	return dirOrDefault(c.Dirs.Templates, "templates")
+}
+
+// contentDir is where the site's markdown is read from
+func (c *Config) contentDir() string {
+	return dirOrDefault(c.Dirs.Content, "content")
+}
+
+// staticDir is where the site's static files are read from
+func (c *Config) staticDir() string {
+	return dirOrDefault(c.Dirs.Static, "static")
+}
+
+func dirOrDefault(dir, def string) string {
+	if dir == "" {
+		return def
+	}
+	return filepath.Clean(dir)
+}
+
+// excluded reports whether a content file is left out of this build
+func (c *Config) excluded(file string) bool {
+	return c.variant != nil && slices.Contains(c.variant.Exclude, c.contentSection(file))
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// VerificationConfig proves site ownership to search engines, either with
+// meta tags in the head partial or with the files they look for at the root
+type VerificationConfig struct {
+	Method string `yaml:"method"` // meta (default) or file
+	Google string `yaml:"google"`
+	Bing   string `yaml:"bing"`
+	Yandex string `yaml:"yandex"`
+}
+
+func (v VerificationConfig) useFiles() bool {
+	return v.Method == "file"
+}
+
+// verificationMeta returns the verification meta tags, keyed by name
+func verificationMeta(cfg *Config) map[string]string {
+	v := cfg.Verification
+	meta := map[string]string{}
+	if v.useFiles() {
+		return meta
+	}
+	if v.Google != "" {
+		meta["google-site-verification"] = v.Google
+	}
+	if v.Bing != "" {
+		meta["msvalidate.01"] = v.Bing
+	}
+	if v.Yandex != "" {
+		meta["yandex-verification"] = v.Yandex
+	}
+	return meta
+}
+
+// writeVerificationFiles writes the root files each search engine checks for
+// when verification uses the file method
+func writeVerificationFiles(cfg *Config, publicDir string) error {
+	v := cfg.Verification
+	if !v.useFiles() {
+		return nil
+	}
+
+	files := map[string]string{}
+	if v.Google != "" {
+		// Accept the code with or without the google prefix and .html suffix
+		code := strings.TrimSuffix(strings.TrimPrefix(v.Google, "google"), ".html")
+		name := "google" + code + ".html"
+		files[name] = "google-site-verification: " + name + "\n"
+	}
+	if v.Bing != "" {
+		files["BingSiteAuth.xml"] = fmt.Sprintf("<?xml version=\"1.0\"?>\n<users>\n\t<user>%s</user>\n</users>\n", v.Bing)
+	}
+	if v.Yandex != "" {
+		code := strings.TrimSuffix(strings.TrimPrefix(v.Yandex, "yandex_"), ".html")
+		files["yandex_"+code+".html"] = fmt.Sprintf("<html>\n\t<head>\n\t\t<meta http-equiv=\"Content-Type\" content=\"text/html; charset=UTF-8\">\n\t</head>\n\t<body>Verification: %s</body>\n</html>\n", code)
+	}
+
+	for _, name := range sortedKeys(files) {
+		outputPath := filepath.Join(publicDir, name)
+		if err := os.WriteFile(outputPath, []byte(files[name]), 0644); err != nil {
+			return err
+		}
+		logGenerated(outputPath)
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+// wikiIndex resolves [[wikilink]] targets to page URLs
+// Targets match a page's title or file name, ignoring case and punctuation
+// e.g., "Hello World" and "hello-world" → "/blog/hello-world.html"
+type wikiIndex map[string]string
+
+// buildWikiIndex indexes every content file by title and file name
+// Titles take precedence over file names when they collide
+func buildWikiIndex(cfg *Config) (wikiIndex, error) {
+	files, err := findContentFiles(cfg)
+	if err != nil {
+		return nil, err
+	}
+	files = slices.DeleteFunc(files, cfg.excluded)
+
+	index := wikiIndex{}
+	var names [][2]string
+	for _, file := range files {
+		content, err := readContent(file)
+		if err != nil {
+			continue
+		}
+		fm, _, _ := parseFrontmatter(content)
+		title := fm.Title
+		if title == "" {
+			title = extractTitle(file)
+		}
+		url := cfg.pathToURL(file)
+		if other, ok := index[slugify(title)]; ok && other != url {
+			log.Debugf("%s: title %q is also used by %s, wikilinks resolve to the first", file, title, other)
+			continue
+		}
+		index[slugify(title)] = url
+		names = append(names, [2]string{slugify(strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))), url})
+	}
+	for _, name := range names {
+		if _, ok := index[name[0]]; !ok {
+			index[name[0]] = name[1]
+		}
+	}
+	return index, nil
+}
+
+// resolve returns the URL for a wikilink target, keeping any #fragment
+func (w wikiIndex) resolve(target string) (string, bool) {
+	target, fragment, _ := strings.Cut(target, "#")
+	url, ok := w[slugify(target)]
+	if !ok {
+		return "", false
+	}
+	if fragment != "" {
+		url += "#" + fragment
+	}
+	return url, true
+}
+
+// attachmentIndex resolves ![[embed]] targets to the URLs of static files
+// and files next to content, by file name or the end of their path, ignoring
+// case, e.g. "Diagram.png" and "attachments/diagram.png" →
+// "/notes/attachments/diagram.png"
+type attachmentIndex map[string]string
+
+// buildAttachmentIndex indexes every asset by each tail of its output path
+// Assets sorted first win when names collide.
+func buildAttachmentIndex(cfg *Config) (attachmentIndex, error) {
+	assets, err := findAssets(cfg)
+	if err != nil {
+		return nil, err
+	}
+	index := attachmentIndex{}
+	for _, a := range assets {
+		segments := strings.Split(strings.ToLower(strings.TrimPrefix(a.Output, "/")), "/")
+		for i := range segments {
+			key := strings.Join(segments[i:], "/")
+			if _, ok := index[key]; !ok {
+				index[key] = a.Output
+			}
+		}
+	}
+	return index, nil
+}
+
+// embedImageExts are the attachments embedded as images, others are linked
+var embedImageExts = []string{".png", ".jpg", ".jpeg", ".gif", ".svg", ".webp", ".avif", ".bmp"}
+
+// embedSizePattern matches an Obsidian image size, e.g. 300 or 300x200
+var embedSizePattern = regexp.MustCompile(`^(\d+)(?:x(\d+))?$`)
+
+// wikiLinkParser parses [[Target]] and [[Target|label]] into links, and
+// ![[file.png]] and ![[file.png|300]] into images
+type wikiLinkParser struct {
+	index wikiIndex
+	files attachmentIndex
+}
+
+func (p *wikiLinkParser) Trigger() []byte {
+	return []byte{'[', '!'}
+}
+
+func (p *wikiLinkParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, _ := block.PeekLine()
+	embed := bytes.HasPrefix(line, []byte("!"))
+	if embed {
+		line = line[1:]
+	}
+	if !bytes.HasPrefix(line, []byte("[[")) {
+		return nil
+	}
+	end := bytes.Index(line[2:], []byte("]]"))
+	if end <= 0 {
+		return nil
+	}
+	inner := string(line[2 : 2+end])
+	if strings.ContainsAny(inner, "[]\n") {
+		return nil
+	}
+	block.Advance(end + 4)
+
+	target, label, hasLabel := strings.Cut(inner, "|")
+	target = strings.TrimSpace(target)
+	if !hasLabel {
+		label, _, _ = strings.Cut(target, "#")
+	}
+	label = strings.TrimSpace(label)
+
+	if embed {
+		block.Advance(1)
+		if node := p.embed(target, label, hasLabel); node != nil {
+			return node
+		}
+		// Embedded notes can't be inlined, so they're linked
+	}
+
+	url, ok := p.index.resolve(target)
+	if !ok {
+		source, _ := pc.Get(sourcePathKey).(string)
+		if embed {
+			log.Warnf("%s: embed ![[%s]] matches no file or page", source, target)
+		} else {
+			log.Warnf("%s: wikilink [[%s]] matches no page", source, target)
+		}
+		return ast.NewString([]byte(label))
+	}
+
+	link := ast.NewLink()
+	link.Destination = []byte(url)
+	link.AppendChild(link, ast.NewString([]byte(label)))
+	return link
+}
+
+// embed returns an image for an embedded image file, a link for any other
+// file, or nil if target isn't a file, e.g. an embedded note
+// The label is the image's alt text, or its size in pixels.
+func (p *wikiLinkParser) embed(target, label string, hasLabel bool) ast.Node {
+	url, ok := p.files[strings.ToLower(strings.TrimPrefix(target, "/"))]
+	if !ok {
+		return nil
+	}
+	name := path.Base(target)
+	if !slices.Contains(embedImageExts, strings.ToLower(path.Ext(name))) {
+		link := ast.NewLink()
+		link.Destination = []byte(url)
+		link.AppendChild(link, ast.NewString([]byte(label)))
+		return link
+	}
+
+	alt := strings.TrimSuffix(name, path.Ext(name))
+	img := ast.NewImage(ast.NewLink())
+	img.Destination = []byte(url)
+	if m := embedSizePattern.FindStringSubmatch(label); hasLabel && m != nil {
+		img.SetAttributeString("width", []byte(m[1]))
+		if m[2] != "" {
+			img.SetAttributeString("height", []byte(m[2]))
+		}
+	} else if hasLabel {
+		alt = label
+	}
+	img.AppendChild(img, ast.NewString([]byte(alt)))
+	return img
+}